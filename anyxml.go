@@ -56,21 +56,39 @@ An extreme example is available in examples/goofy_map.go.
 */
 // Alternative values for DefaultRootTag and DefaultElementTag can be set as:
 // AnyXml( v, myRootTag, myElementTag).
+//
+// To use settings other than the package defaults for a single call, see EncoderOptions.
 func AnyXml(v interface{}, tags ...string) ([]byte, error) {
-	var rt, et string
+	return anyXmlOptions(tags).AnyXml(v)
+}
+
+// anyXmlOptions returns the package default EncoderOptions with the root and
+// element 'tags' applied as passed to AnyXml().
+func anyXmlOptions(tags []string) *EncoderOptions {
+	o := NewEncoderOptions()
 	if len(tags) == 1 || len(tags) == 2 {
-		rt = tags[0]
-	} else {
-		rt = DefaultRootTag
+		o.RootTag = tags[0]
 	}
 	if len(tags) == 2 {
-		et = tags[1]
-	} else {
+		o.ElementTag = tags[1]
+	}
+	return o
+}
+
+// AnyXml encodes an arbitrary value as XML using the settings in 'o'.
+// See the AnyXml function.
+func (o *EncoderOptions) AnyXml(v interface{}) ([]byte, error) {
+	o = o.call()
+	rt, et := o.RootTag, o.ElementTag
+	if rt == "" {
+		rt = DefaultRootTag
+	}
+	if et == "" {
 		et = DefaultElementTag
 	}
 
 	if v == nil {
-		if useGoXmlEmptyElemSyntax {
+		if o.GoEmptyElemSyntax {
 			return []byte("<" + rt + "></" + rt + ">"), nil
 		}
 		return []byte("<" + rt + "/>"), nil
@@ -95,13 +113,13 @@ func AnyXml(v interface{}, tags ...string) ([]byte, error) {
 				m := vv.(map[string]interface{})
 				if len(m) == 1 {
 					for tag, val := range m {
-						err = marshalMapToXmlIndent(false, s, tag, val, p)
+						err = o.marshalMapToXmlIndent(false, s, tag, val, p)
 					}
 				} else {
-					err = marshalMapToXmlIndent(false, s, et, vv, p)
+					err = o.marshalMapToXmlIndent(false, s, et, vv, p)
 				}
 			default:
-				err = marshalMapToXmlIndent(false, s, et, vv, p)
+				err = o.marshalMapToXmlIndent(false, s, et, vv, p)
 			}
 			if err != nil {
 				break
@@ -112,10 +130,11 @@ func AnyXml(v interface{}, tags ...string) ([]byte, error) {
 		}
 		b = s.Bytes()
 	case map[string]interface{}:
-		m := Map(v.(map[string]interface{}))
-		b, err = m.Xml(rt)
+		oc := *o
+		oc.RootTag = rt
+		b, err = oc.Xml(Map(v.(map[string]interface{})))
 	default:
-		err = marshalMapToXmlIndent(false, s, rt, v, p)
+		err = o.marshalMapToXmlIndent(false, s, rt, v, p)
 		b = s.Bytes()
 	}
 
@@ -126,20 +145,23 @@ func AnyXml(v interface{}, tags ...string) ([]byte, error) {
 // Alternative values for DefaultRootTag and DefaultElementTag can be set as:
 // AnyXmlIndent( v, "", "  ", myRootTag, myElementTag).
 func AnyXmlIndent(v interface{}, prefix, indent string, tags ...string) ([]byte, error) {
-	var rt, et string
-	if len(tags) == 1 || len(tags) == 2 {
-		rt = tags[0]
-	} else {
+	return anyXmlOptions(tags).AnyXmlIndent(v, prefix, indent)
+}
+
+// AnyXmlIndent encodes an arbitrary value as a pretty XML string using the
+// settings in 'o'.  See the AnyXmlIndent function.
+func (o *EncoderOptions) AnyXmlIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	o = o.call()
+	rt, et := o.RootTag, o.ElementTag
+	if rt == "" {
 		rt = DefaultRootTag
 	}
-	if len(tags) == 2 {
-		et = tags[1]
-	} else {
+	if et == "" {
 		et = DefaultElementTag
 	}
 
 	if v == nil {
-		if useGoXmlEmptyElemSyntax {
+		if o.GoEmptyElemSyntax {
 			return []byte(prefix + "<" + rt + "></" + rt + ">"), nil
 		}
 		return []byte(prefix + "<" + rt + "/>"), nil
@@ -167,11 +189,11 @@ func AnyXmlIndent(v interface{}, prefix, indent string, tags ...string) ([]byte,
 				m := vv.(map[string]interface{})
 				if len(m) == 1 {
 					for tag, val := range m {
-						err = marshalMapToXmlIndent(true, s, tag, val, p)
+						err = o.marshalMapToXmlIndent(true, s, tag, val, p)
 					}
 				} else {
 					p.start = 1 // we 1 tag in
-					err = marshalMapToXmlIndent(true, s, et, vv, p)
+					err = o.marshalMapToXmlIndent(true, s, et, vv, p)
 					// *s += "\n"
 					if _, err = s.WriteString("\n"); err != nil {
 						return nil, err
@@ -179,7 +201,7 @@ func AnyXmlIndent(v interface{}, prefix, indent string, tags ...string) ([]byte,
 				}
 			default:
 				p.start = 0 // in case trailing p.start = 1
-				err = marshalMapToXmlIndent(true, s, et, vv, p)
+				err = o.marshalMapToXmlIndent(true, s, et, vv, p)
 			}
 			if err != nil {
 				break
@@ -190,10 +212,11 @@ func AnyXmlIndent(v interface{}, prefix, indent string, tags ...string) ([]byte,
 		}
 		b = s.Bytes()
 	case map[string]interface{}:
-		m := Map(v.(map[string]interface{}))
-		b, err = m.XmlIndent(prefix, indent, rt)
+		oc := *o
+		oc.RootTag = rt
+		b, err = oc.XmlIndent(Map(v.(map[string]interface{})), prefix, indent)
 	default:
-		err = marshalMapToXmlIndent(true, s, rt, v, p)
+		err = o.marshalMapToXmlIndent(true, s, rt, v, p)
 		b = s.Bytes()
 	}
 
@@ -0,0 +1,465 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// c14n.go - Canonical XML 1.0 and Exclusive XML Canonicalization of XML
+// documents and MapSeq values.
+
+package mxj
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// C14NMethod is a canonicalization algorithm, identified by its URI as in XML Signature.
+type C14NMethod string
+
+const (
+	C14N10                C14NMethod = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	C14N10WithComments    C14NMethod = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315#WithComments"
+	ExcC14N10             C14NMethod = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	ExcC14N10WithComments C14NMethod = "http://www.w3.org/2001/10/xml-exc-c14n#WithComments"
+)
+
+// CanonicalXml writes the canonical form of the XML document read from 'r' on 'w'.
+// The document is processed as a stream of tokens, so it needn't fit in memory.
+//
+//	'method' is C14N10, C14N10WithComments, ExcC14N10 or ExcC14N10WithComments.
+//	'inclusivePrefixes' (optional) is the Exclusive C14N InclusiveNamespaces PrefixList - the
+//	       prefixes whose declarations are handled as for Canonical XML 1.0; "#default" is
+//	       the default name space.  Ignored for C14N10.
+//
+//	The output is UTF-8, with no XML declaration or DTD; empty elements have an end
+//	tag; attributes are in double quotes and ordered by name space URI and local name;
+//	only the name space declarations that aren't superfluous - or, for Exclusive C14N,
+//	that are used - are kept.  Whitespace in element content is kept as is.
+//	See XmlCharsetReader for documents that aren't UTF-8.
+//	NOTE: there's no DTD processing - default attributes aren't added and entities other
+//	      than the predefined ones are errors.
+func CanonicalXml(r io.Reader, w io.Writer, method C14NMethod, inclusivePrefixes ...string) error {
+	c, err := newCanonicalizer(w, method, inclusivePrefixes)
+	if err != nil {
+		return err
+	}
+	rec := newRawRecorder(r)
+	dec := xml.NewDecoder(rec)
+	dec.CharsetReader = XmlCharsetReader
+	for {
+		start := dec.InputOffset()
+		t, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if se, ok := t.(xml.StartElement); ok {
+			normalizeAttrs(se.Attr, rec.raw(start, dec.InputOffset()))
+		}
+		rec.discard(dec.InputOffset())
+		if err = c.token(t); err != nil {
+			return err
+		}
+	}
+	return c.end()
+}
+
+// C14N returns the canonical form of the MapSeq as for CanonicalXml() - the elements,
+// text, comments and processing instructions in "#seq" order.
+//
+//	NOTE: a MapSeq element has one "#text" value, which is written before the sub-elements;
+//	      so the output only matches that of CanonicalXml() for the document if there's no
+//	      mixed content - e.g., whitespace for formatting between elements.
+func (msv MapSeq) C14N(method C14NMethod, inclusivePrefixes ...string) ([]byte, error) {
+	var b bytes.Buffer
+	c, err := newCanonicalizer(&b, method, inclusivePrefixes)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range seqSorted(map[string]interface{}(msv)) {
+		if err = c.mapSeqTokens(kv.k, kv.v); err != nil {
+			return nil, err
+		}
+	}
+	if err = c.end(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// c14nElem is an element that's been started - its name, the name spaces in scope
+// and the name space declarations that are in effect in the output.
+type c14nElem struct {
+	name     string
+	scope    map[string]string
+	rendered map[string]string
+}
+
+type canonicalizer struct {
+	w         *bufio.Writer
+	exclusive bool
+	comments  bool
+	inclusive map[string]bool
+	stack     []*c14nElem
+	root      bool // the document element has been started
+}
+
+func newCanonicalizer(w io.Writer, method C14NMethod, inclusivePrefixes []string) (*canonicalizer, error) {
+	c := &canonicalizer{w: bufio.NewWriter(w)}
+	switch method {
+	case C14N10:
+	case C14N10WithComments:
+		c.comments = true
+	case ExcC14N10:
+		c.exclusive = true
+	case ExcC14N10WithComments:
+		c.exclusive, c.comments = true, true
+	default:
+		return nil, fmt.Errorf("unknown canonicalization method: %s", method)
+	}
+	if c.exclusive {
+		c.inclusive = make(map[string]bool, len(inclusivePrefixes))
+		for _, p := range inclusivePrefixes {
+			if p == "#default" {
+				p = ""
+			}
+			c.inclusive[p] = true
+		}
+	}
+	c.stack = []*c14nElem{{
+		scope:    map[string]string{xmlPrefix: xmlURL},
+		rendered: map[string]string{},
+	}}
+	return c, nil
+}
+
+func (c *canonicalizer) end() error {
+	if len(c.stack) > 1 {
+		return fmt.Errorf("no end tag for: %s", c.stack[len(c.stack)-1].name)
+	}
+	return c.w.Flush()
+}
+
+// token writes the canonical form of a RawToken() token.
+func (c *canonicalizer) token(t xml.Token) error {
+	top := len(c.stack) == 1
+	switch tt := t.(type) {
+	case xml.StartElement:
+		c.root = true
+		return c.start(tt)
+	case xml.EndElement:
+		if top {
+			return fmt.Errorf("unexpected end tag: %s", qname(tt.Name))
+		}
+		e := c.stack[len(c.stack)-1]
+		if name := qname(tt.Name); name != e.name {
+			return fmt.Errorf("end tag %s doesn't match start tag %s", name, e.name)
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+		c.w.WriteString("</" + e.name + ">")
+	case xml.CharData:
+		if !top {
+			c.w.WriteString(c14nText.Replace(string(tt)))
+		}
+	case xml.Comment:
+		if c.comments {
+			c.outside(top, "<!--"+string(tt)+"-->")
+		}
+	case xml.ProcInst:
+		if tt.Target == "xml" {
+			break
+		}
+		s := "<?" + tt.Target
+		if inst := strings.TrimLeft(string(tt.Inst), " \t\r\n"); inst != "" {
+			s += " " + inst
+		}
+		c.outside(top, s+"?>")
+	}
+	return nil
+}
+
+// outside writes a comment or processing instruction - with a line break
+// before or after it, if it's outside the document element.
+func (c *canonicalizer) outside(top bool, s string) {
+	switch {
+	case !top:
+		c.w.WriteString(s)
+	case c.root:
+		c.w.WriteString("\n" + s)
+	default:
+		c.w.WriteString(s + "\n")
+	}
+}
+
+func (c *canonicalizer) start(se xml.StartElement) error {
+	parent := c.stack[len(c.stack)-1]
+	e := &c14nElem{name: qname(se.Name), scope: make(map[string]string, len(parent.scope))}
+	for p, u := range parent.scope {
+		e.scope[p] = u
+	}
+	var attrs []xml.Attr
+	for _, a := range se.Attr {
+		switch {
+		case a.Name.Space == xmlnsPrefix:
+			e.scope[a.Name.Local] = a.Value
+		case a.Name.Space == "" && a.Name.Local == xmlnsPrefix:
+			e.scope[""] = a.Value
+		default:
+			attrs = append(attrs, a)
+		}
+	}
+
+	// the name space declarations to write
+	var prefixes []string
+	if c.exclusive {
+		used := map[string]bool{se.Name.Space: true}
+		for _, a := range attrs {
+			if a.Name.Space != "" {
+				used[a.Name.Space] = true
+			}
+		}
+		for p := range c.inclusive {
+			if _, ok := e.scope[p]; ok {
+				used[p] = true
+			}
+		}
+		for p := range used {
+			if p == xmlPrefix {
+				continue
+			}
+			if _, ok := e.scope[p]; !ok && p != "" {
+				return fmt.Errorf("undeclared name space prefix: %s", p)
+			}
+			prefixes = append(prefixes, p)
+		}
+	} else {
+		if _, ok := e.scope[se.Name.Space]; !ok && se.Name.Space != "" {
+			return fmt.Errorf("undeclared name space prefix: %s", se.Name.Space)
+		}
+		for p := range e.scope {
+			if p != xmlPrefix {
+				prefixes = append(prefixes, p)
+			}
+		}
+	}
+	e.rendered = parent.rendered
+	var decls []string
+	sort.Strings(prefixes)
+	for _, p := range prefixes {
+		if e.scope[p] == parent.rendered[p] {
+			continue // superfluous - "" if not declared
+		}
+		if len(decls) == 0 {
+			e.rendered = make(map[string]string, len(parent.rendered)+1)
+			for k, v := range parent.rendered {
+				e.rendered[k] = v
+			}
+		}
+		e.rendered[p] = e.scope[p]
+		if p == "" {
+			decls = append(decls, ` xmlns="`+c14nAttr.Replace(e.scope[p])+`"`)
+		} else {
+			decls = append(decls, ` xmlns:`+p+`="`+c14nAttr.Replace(e.scope[p])+`"`)
+		}
+	}
+
+	// the attributes, by name space URI and local name
+	type c14nAttrName struct{ uri, local string }
+	names := make([]c14nAttrName, len(attrs))
+	for i, a := range attrs {
+		if a.Name.Space != "" {
+			uri, ok := e.scope[a.Name.Space]
+			if !ok {
+				return fmt.Errorf("undeclared name space prefix: %s", a.Name.Space)
+			}
+			names[i].uri = uri
+		}
+		names[i].local = a.Name.Local
+	}
+	idx := make([]int, len(attrs))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		ni, nj := names[idx[i]], names[idx[j]]
+		if ni.uri != nj.uri {
+			return ni.uri < nj.uri
+		}
+		return ni.local < nj.local
+	})
+
+	c.w.WriteString("<" + e.name)
+	for _, d := range decls {
+		c.w.WriteString(d)
+	}
+	for _, i := range idx {
+		c.w.WriteString(" " + qname(attrs[i].Name) + `="` + c14nAttr.Replace(attrs[i].Value) + `"`)
+	}
+	c.w.WriteString(">")
+	c.stack = append(c.stack, e)
+	return nil
+}
+
+func qname(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+var (
+	c14nText = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	c14nAttr = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+// normalizeAttrs applies attribute-value normalization to the values of 'attrs'
+// that have whitespace characters - xml.Decoder decodes "&#x9;" and a literal tab
+// alike, but only the tab is normalized to a space.  'raw' is the start tag as read.
+func normalizeAttrs(attrs []xml.Attr, raw []byte) {
+	var vals [][]byte
+	for i := range attrs {
+		if !strings.ContainsAny(attrs[i].Value, "\t\n\r") {
+			continue
+		}
+		if vals == nil {
+			vals = rawAttrValues(raw)
+			if len(vals) != len(attrs) {
+				return
+			}
+		}
+		v := bytes.Replace(vals[i], []byte("\r\n"), []byte(" "), -1)
+		for _, ws := range []string{"\t", "\n", "\r"} {
+			v = bytes.Replace(v, []byte(ws), []byte(" "), -1)
+		}
+		// decode the character references that are left
+		dec := xml.NewDecoder(bytes.NewReader(append(append([]byte(`<a v="`), bytes.Replace(v, []byte(`"`), []byte("&quot;"), -1)...), `"/>`...)))
+		if t, err := dec.Token(); err == nil {
+			if se, ok := t.(xml.StartElement); ok && len(se.Attr) == 1 {
+				attrs[i].Value = se.Attr[0].Value
+			}
+		}
+	}
+}
+
+// rawAttrValues returns the values of the attributes in a start tag, as written.
+func rawAttrValues(tag []byte) [][]byte {
+	var vals [][]byte
+	for {
+		i := bytes.IndexByte(tag, '=')
+		if i < 0 {
+			return vals
+		}
+		tag = bytes.TrimLeft(tag[i+1:], " \t\r\n")
+		if len(tag) == 0 || tag[0] != '"' && tag[0] != '\'' {
+			return nil
+		}
+		j := bytes.IndexByte(tag[1:], tag[0])
+		if j < 0 {
+			return nil
+		}
+		vals = append(vals, tag[1:j+1])
+		tag = tag[j+2:]
+	}
+}
+
+// mapSeqTokens writes the canonical form of the MapSeq value for 'key'.
+func (c *canonicalizer) mapSeqTokens(key string, v interface{}) error {
+	if a, ok := v.([]interface{}); ok {
+		for _, vv := range a {
+			if err := c.mapSeqTokens(key, vv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	m, _ := v.(map[string]interface{})
+	switch key {
+	case commentK:
+		s, _ := castString(textValue(v))
+		return c.token(xml.Comment(s))
+	case procinstK:
+		target, _ := m[targetK].(string)
+		inst, _ := m[instK].(string)
+		return c.token(xml.ProcInst{Target: target, Inst: []byte(inst)})
+	case directiveK, seqK, attrK, cdataK:
+		return nil
+	case textK:
+		s, _ := castString(textValue(v))
+		return c.token(xml.CharData(s))
+	}
+
+	se := xml.StartElement{Name: splitQName(key)}
+	if attrs, ok := m[attrK].(map[string]interface{}); ok {
+		for _, kv := range seqSorted(attrs) {
+			s, ok := castString(textValue(kv.v))
+			if !ok {
+				s = fmt.Sprintf("%v", textValue(kv.v))
+			}
+			se.Attr = append(se.Attr, xml.Attr{Name: splitQName(kv.k), Value: s})
+		}
+	}
+	if err := c.token(se); err != nil {
+		return err
+	}
+	switch {
+	case m != nil:
+		for _, kv := range seqSorted(m) {
+			if err := c.mapSeqTokens(kv.k, kv.v); err != nil {
+				return err
+			}
+		}
+	case v != nil:
+		s, ok := castString(v)
+		if !ok {
+			s = fmt.Sprintf("%v", v)
+		}
+		if err := c.token(xml.CharData(s)); err != nil {
+			return err
+		}
+	}
+	return c.token(xml.EndElement{Name: se.Name})
+}
+
+func splitQName(s string) xml.Name {
+	if i := strings.Index(s, ":"); i > 0 {
+		return xml.Name{Space: s[:i], Local: s[i+1:]}
+	}
+	return xml.Name{Local: s}
+}
+
+// seqSorted returns the entries of a MapSeq map - list members separately - in
+// "#seq" order; the "#seq" and "#attr" entries are left out.
+func seqSorted(m map[string]interface{}) []keyval {
+	var kv []keyval
+	for _, k := range sortedKeys(m) {
+		if k == seqK || k == attrK || k == cdataK {
+			continue
+		}
+		if a, ok := m[k].([]interface{}); ok {
+			for _, v := range a {
+				kv = append(kv, keyval{k, v})
+			}
+			continue
+		}
+		kv = append(kv, keyval{k, m[k]})
+	}
+	seq := func(v interface{}) int {
+		if mm, ok := v.(map[string]interface{}); ok {
+			switch s := mm[seqK].(type) {
+			case int:
+				return s
+			case float64:
+				return int(s)
+			}
+		}
+		return -1
+	}
+	sort.SliceStable(kv, func(i, j int) bool { return seq(kv[i].v) < seq(kv[j].v) })
+	return kv
+}
@@ -0,0 +1,177 @@
+package mxj
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// from the Canonical XML 1.0 spec, section 3 - w/o the DTD defaults and entities
+var c14nTests = []struct {
+	name, doc string
+	method    C14NMethod
+	want      string
+}{
+	{"PIs, comments, outside of doc", c14nDoc31, C14N10, `<?xml-stylesheet href="doc.xsl"
+   type="text/xsl"   ?>
+<doc>Hello, world!</doc>
+<?pi-without-data?>`},
+	{"PIs, comments, outside of doc - with comments", c14nDoc31, C14N10WithComments, `<?xml-stylesheet href="doc.xsl"
+   type="text/xsl"   ?>
+<doc>Hello, world!<!-- Comment 1 --></doc>
+<?pi-without-data?>
+<!-- Comment 2 -->
+<!-- Comment 3 -->`},
+	{"start and end tags", c14nDoc33, C14N10, `<doc>
+   <e1></e1>
+   <e2></e2>
+   <e3 id="elem3" name="elem3"></e3>
+   <e4 id="elem4" name="elem4"></e4>
+   <e5 xmlns="http://example.org" xmlns:a="http://www.w3.org" xmlns:b="http://www.ietf.org" attr="I'm" attr2="all" b:attr="sorted" a:attr="out"></e5>
+   <e6 xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="">
+            <e9 xmlns:a="http://www.ietf.org"></e9>
+         </e8>
+      </e7>
+   </e6>
+</doc>`},
+	{"character modifications", c14nDoc34, C14N10, `<doc>
+   <text>First line&#xD;
+Second line</text>
+   <value>2</value>
+   <compute>value&gt;"0" &amp;&amp; value&lt;"10" ?"valid":"error"</compute>
+   <compute expr="value>&quot;0&quot; &amp;&amp; value&lt;&quot;10&quot; ?&quot;valid&quot;:&quot;error&quot;">valid</compute>
+   <norm attr=" '    &#xD;&#xA;&#x9;   ' "></norm>
+   <lines attr="a b  c"></lines>
+</doc>`},
+	{"exclusive", c14nDocExc, ExcC14N10,
+		`<n0:local xmlns:n0="foo:bar"><n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"></n3:stuff><n4:stuff xmlns:n4="http://foo.example" n1:a="1" n4:b="2"></n4:stuff><e xmlns="urn:e"><f xmlns=""></f></e></n1:elem2></n0:local>`},
+	{"inclusive", c14nDocExc, C14N10,
+		`<n0:local xmlns:n0="foo:bar" xmlns:n3="ftp://example.org"><n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff></n3:stuff><n4:stuff xmlns:n4="http://foo.example" n1:a="1" n4:b="2"></n4:stuff><e xmlns="urn:e"><f xmlns=""></f></e></n1:elem2></n0:local>`},
+}
+
+const c14nDoc31 = `<?xml version="1.0"?>
+
+<?xml-stylesheet   href="doc.xsl"
+   type="text/xsl"   ?>
+
+<!DOCTYPE doc SYSTEM "doc.dtd">
+
+<doc>Hello, world!<!-- Comment 1 --></doc>
+
+<?pi-without-data     ?>
+
+<!-- Comment 2 -->
+
+<!-- Comment 3 -->`
+
+const c14nDoc33 = `<doc>
+   <e1   />
+   <e2   ></e2>
+   <e3   name = "elem3"   id="elem3"   />
+   <e4   name="elem4"   id="elem4"   ></e4>
+   <e5 a:attr="out" b:attr="sorted" attr2="all" attr="I'm"
+      xmlns:b="http://www.ietf.org"
+      xmlns:a="http://www.w3.org"
+      xmlns="http://example.org"/>
+   <e6 xmlns="" xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="" xmlns:a="http://www.w3.org">
+            <e9 xmlns="" xmlns:a="http://www.ietf.org"/>
+         </e8>
+      </e7>
+   </e6>
+</doc>`
+
+const c14nDoc34 = "<doc>\n" +
+	"   <text>First line&#x0d;&#10;Second line</text>\n" +
+	"   <value>&#x32;</value>\n" +
+	`   <compute><![CDATA[value>"0" && value<"10" ?"valid":"error"]]></compute>` + "\n" +
+	`   <compute expr='value>"0" &amp;&amp; value&lt;"10" ?"valid":"error"'>valid</compute>` + "\n" +
+	`   <norm attr=' &apos;   &#x20;&#13;&#xa;&#9;   &apos; '/>` + "\n" +
+	"   <lines attr=\"a\r\nb\t c\"/>\n" +
+	"</doc>"
+
+const c14nDocExc = `<n0:local xmlns:n0="foo:bar" xmlns:n3="ftp://example.org"><n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"/><n4:stuff n4:b="2" n1:a="1" xmlns:n4="http://foo.example"/><e xmlns="urn:e"><f xmlns=""/></e></n1:elem2></n0:local>`
+
+func TestCanonicalXml(t *testing.T) {
+	fmt.Println("\n================== TestCanonicalXml")
+	for _, tt := range c14nTests {
+		var b bytes.Buffer
+		if err := CanonicalXml(strings.NewReader(tt.doc), &b, tt.method); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tt.name, b.String(), tt.want)
+		}
+		// canonical XML is canonical
+		var bb bytes.Buffer
+		if err := CanonicalXml(bytes.NewReader(b.Bytes()), &bb, tt.method); err != nil || bb.String() != b.String() {
+			t.Errorf("%s: not idempotent: %v\n%s", tt.name, err, bb.String())
+		}
+	}
+
+	// InclusiveNamespaces PrefixList
+	var b bytes.Buffer
+	if err := CanonicalXml(strings.NewReader(c14nDocExc), &b, ExcC14N10, "n3", "#default"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), `<n0:local xmlns:n0="foo:bar" xmlns:n3="ftp://example.org"><n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff></n3:stuff>`) {
+		t.Errorf("prefix list: %s", b.String())
+	}
+
+	for _, doc := range []string{`<a><b></a>`, `<a><p:b/></a>`, `<a>`} {
+		if err := CanonicalXml(strings.NewReader(doc), &b, C14N10); err == nil {
+			t.Errorf("%s: no error", doc)
+		}
+	}
+	if err := CanonicalXml(strings.NewReader(`<a/>`), &b, "c14n"); err == nil {
+		t.Error("no error for unknown method")
+	}
+}
+
+func TestMapSeqC14N(t *testing.T) {
+	fmt.Println("\n================== TestMapSeqC14N")
+	for _, tt := range c14nTests {
+		if strings.Contains(tt.doc, "\n") {
+			continue // MapSeq values don't have the formatting
+		}
+		msv, err := NewMapXmlSeq([]byte(tt.doc))
+		if err != nil {
+			t.Fatal(tt.name, err)
+		}
+		b, err := msv.C14N(tt.method)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(b) != tt.want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tt.name, b, tt.want)
+		}
+	}
+
+	// the same doc, formatted differently
+	docs := []string{
+		`<doc xmlns:x="urn:x"><!-- c --><x:a y="2" x:z="1" w='3'>one<b/></x:a><?pi  data?></doc>`,
+		`<doc xmlns:x="urn:x" xmlns:u="urn:unused"><!-- c --><x:a w="3" x:z="1" y="2">one<b></b></x:a><?pi data?></doc>`,
+	}
+	var out []string
+	for _, d := range docs {
+		msv, err := NewMapXmlSeq([]byte(d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := msv.C14N(ExcC14N10WithComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, string(b))
+	}
+	want := `<doc><!-- c --><x:a xmlns:x="urn:x" w="3" y="2" x:z="1">one<b></b></x:a><?pi data?></doc>`
+	if out[0] != want || out[1] != want {
+		t.Errorf("got:\n%s\n%s\nwant:\n%s", out[0], out[1], want)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// castschema.go - cast decoded XML values to the type set for their path.
+
+package mxj
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CastType is the type a CastSchema path value is converted to.
+type CastType string
+
+const (
+	CastString  CastType = "string"      // keep the value as a string, even if 'cast' is 'true'
+	CastInt64   CastType = "int64"       // strconv.ParseInt(value, 10, 64)
+	CastUint64  CastType = "uint64"      // strconv.ParseUint(value, 10, 64)
+	CastFloat64 CastType = "float64"     // strconv.ParseFloat(value, 64)
+	CastBool    CastType = "bool"        // strconv.ParseBool(value)
+	CastNumber  CastType = "json.Number" // a JSON number, kept as written
+	CastTime    CastType = "time"        // time.Time in time.RFC3339 format; see CastTimeLayout()
+)
+
+// CastTimeLayout returns the CastType for time.Time values in the 'layout'
+// format - see time.Parse - e.g., CastTimeLayout("2006-01-02").
+func CastTimeLayout(layout string) CastType {
+	return CastType(string(CastTime) + ":" + layout)
+}
+
+// CastSchema maps the paths of decoded XML values to the type they are converted to.
+//
+//	The paths are dot-separated keys starting at the root, as for ValuesForPath(),
+//	and "*" matches any key at that level:
+//	   schema := mxj.CastSchema{
+//	      "invoice.id":          mxj.CastString, // stays a string, even though it looks numeric
+//	      "invoice.total":       mxj.CastFloat64,
+//	      "invoice.-currency":   mxj.CastString, // attribute keys have the attribute prefix
+//	      "invoice.*.qty":       mxj.CastInt64,
+//	      "invoice.date":        mxj.CastTimeLayout("2006-01-02"),
+//	   }
+//	The value of an element with attributes is at the element's path; a "#text" key at
+//	the end of a path is optional.  Attributes are addressed with the AttrPrefix key,
+//	"-" by default, for both NewMapXml() and NewMapXmlSeq() values.
+//	If more than one path matches a value, the one with the fewest wildcards is used.
+//	Values that are not matched are handled as set by the 'cast' argument of NewMapXml(), etc.
+type CastSchema map[string]CastType
+
+// CastError is returned when a value can't be converted to the type set for
+// its path in a CastSchema.  The Map is returned with it, and HandleXmlReader,
+// etc., pass the Map to mapHandler after errHandler has the error - if it
+// returns 'true'.
+type CastError struct {
+	Path  string   // the path of the value - e.g., "invoice.total" or "invoice.-currency"
+	Value string   // the value as decoded
+	Type  CastType // the type set in the CastSchema
+	Err   error    // the conversion error
+}
+
+func (e *CastError) Error() string {
+	return fmt.Sprintf("cast %s value %q to %s: %s", e.Path, e.Value, e.Type, e.Err)
+}
+
+// Unwrap returns the conversion error - e.g., a *strconv.NumError or *time.ParseError.
+func (e *CastError) Unwrap() error {
+	return e.Err
+}
+
+// isCastError reports whether 'err' is, or wraps, a *CastError.
+func isCastError(err error) bool {
+	var cerr *CastError
+	return errors.As(err, &cerr)
+}
+
+// castRule is a CastSchema entry with the path split into keys.
+type castRule struct {
+	keys []string
+	wild int
+	typ  CastType
+}
+
+// compileCastSchema returns the CastSchema rules in the order they are matched:
+// fewest wildcards first, then by path so that the order is consistent.
+func compileCastSchema(schema CastSchema, textKey string) []castRule {
+	rules := make([]castRule, 0, len(schema))
+	for path, typ := range schema {
+		keys := strings.Split(path, ".")
+		if len(keys) > 1 && keys[len(keys)-1] == textKey {
+			keys = keys[:len(keys)-1]
+		}
+		var wild int
+		for _, k := range keys {
+			if k == "*" {
+				wild++
+			}
+		}
+		rules = append(rules, castRule{keys, wild, typ})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].wild != rules[j].wild {
+			return rules[i].wild < rules[j].wild
+		}
+		return strings.Join(rules[i].keys, ".") < strings.Join(rules[j].keys, ".")
+	})
+	return rules
+}
+
+// match reports whether the rule matches the element 'path' or, if 'attr' isn't
+// "", the attribute 'attr' of the element.
+func (r *castRule) match(path []string, attr string) bool {
+	n := len(path)
+	if attr != "" {
+		n++
+	}
+	if len(r.keys) != n {
+		return false
+	}
+	for i, k := range path {
+		if r.keys[i] != "*" && r.keys[i] != k {
+			return false
+		}
+	}
+	return attr == "" || r.keys[n-1] == "*" || r.keys[n-1] == attr
+}
+
+// castPath casts 's', the value of the current element or, if 'attr' isn't "",
+// of its attribute 'attr'.  A conversion error is saved in o.castErr - the first
+// one is returned when the doc has been decoded - and 's' is returned.  The
+// 'key' argument is passed to o.cast() if no CastSchema path matches.
+func (o *DecoderOptions) castPath(s, key, attr string) interface{} {
+	for i := range o.castRules {
+		r := &o.castRules[i]
+		if !r.match(o.path, attr) {
+			continue
+		}
+		v, err := castType(s, r.typ)
+		if err != nil {
+			if o.castErr == nil {
+				path := strings.Join(o.path, ".")
+				if attr != "" {
+					path += "." + attr
+				}
+				o.castErr = &CastError{Path: path, Value: s, Type: r.typ, Err: err}
+			}
+			return s
+		}
+		return v
+	}
+	return o.cast(s, key)
+}
+
+// castResult returns the CastSchema conversion error, if any, when parsing
+// hasn't failed otherwise; the Map value is returned, too, with the value
+// that couldn't be converted as a string.
+func (o *DecoderOptions) castResult(m map[string]interface{}, err error) (map[string]interface{}, error) {
+	if err == nil && o.castErr != nil {
+		err = o.castErr
+	}
+	return m, err
+}
+
+// pushPath and popPath track the path of the element being parsed when
+// there's a CastSchema.
+func (o *DecoderOptions) pushPath(key string) {
+	if len(o.castRules) > 0 {
+		o.path = append(o.path, key)
+	}
+}
+
+func (o *DecoderOptions) popPath() {
+	if len(o.path) > 0 {
+		o.path = o.path[:len(o.path)-1]
+	}
+}
+
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// castType converts 's' to type 't'.
+func castType(s string, t CastType) (interface{}, error) {
+	switch t {
+	case CastString:
+		return s, nil
+	case CastInt64:
+		return strconv.ParseInt(s, 10, 64)
+	case CastUint64:
+		return strconv.ParseUint(s, 10, 64)
+	case CastFloat64:
+		return strconv.ParseFloat(s, 64)
+	case CastBool:
+		return strconv.ParseBool(s)
+	case CastNumber:
+		if !jsonNumber.MatchString(s) {
+			return nil, fmt.Errorf("not a JSON number")
+		}
+		return json.Number(s), nil
+	case CastTime:
+		return time.Parse(time.RFC3339, s)
+	}
+	if strings.HasPrefix(string(t), string(CastTime)+":") {
+		return time.Parse(string(t[len(CastTime)+1:]), s)
+	}
+	return nil, fmt.Errorf("unknown CastType")
+}
+
+// castString returns the XML text of a value decoded by NewMapXml(), etc. -
+// including the CastSchema types - or 'false' if 'v' isn't one of them.
+func castString(v interface{}) (string, bool) {
+	switch vv := v.(type) {
+	case string:
+		return vv, true
+	case float64, bool, int, int32, int64, uint64, float32, json.Number:
+		return fmt.Sprint(vv), true
+	case time.Time:
+		return vv.Format(time.RFC3339Nano), true
+	}
+	return "", false
+}
@@ -0,0 +1,262 @@
+package mxj
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var castSchemaData = []byte(`<invoice currency="EUR" lines="2">
+	<id>00123</id>
+	<date>2026-10-16</date>
+	<total>123.45</total>
+	<ref>18446744073709551615</ref>
+	<tax rate="0.2">20.58</tax>
+	<line><sku>A-1</sku><qty>3</qty><price>10.5</price></line>
+	<line><sku>B-2</sku><qty>7</qty><price>12</price></line>
+</invoice>`)
+
+func castSchemaOptions() *DecoderOptions {
+	o := NewDecoderOptions()
+	o.Cast = true
+	o.CastSchema = CastSchema{
+		"invoice.id":                         CastString,
+		"invoice.date":                       CastTimeLayout("2006-01-02"),
+		"invoice.total":                      CastNumber,
+		"invoice.ref":                        CastUint64,
+		"invoice.tax." + textK:               CastFloat64,
+		"invoice.tax." + attrPrefix + "rate": CastString,
+		"invoice." + attrPrefix + "lines":    CastInt64,
+		"invoice.*.qty":                      CastInt64,
+		"invoice.*.*":                        CastString,
+	}
+	return o
+}
+
+func TestCastSchema(t *testing.T) {
+	fmt.Println("\n================== TestCastSchema")
+	m, err := castSchemaOptions().NewMapXml(castSchemaData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("%v\n", m)
+
+	checks := map[string]interface{}{
+		"invoice.id":                         "00123",
+		"invoice.date":                       time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		"invoice.total":                      json.Number("123.45"),
+		"invoice.ref":                        uint64(18446744073709551615),
+		"invoice.tax." + textK:               float64(20.58),
+		"invoice.tax." + attrPrefix + "rate": "0.2",
+		"invoice." + attrPrefix + "lines":    int64(2),
+		"invoice." + attrPrefix + "currency": "EUR",
+		"invoice.line[0].qty":                int64(3),
+		"invoice.line[1].qty":                int64(7),
+		"invoice.line[0].price":              "10.5", // "invoice.*.*"
+	}
+	for path, want := range checks {
+		v, err := m.ValueForPath(path)
+		if err != nil {
+			t.Fatal(path, err)
+		}
+		if v != want {
+			t.Errorf("%s: got %#v, want %#v", path, v, want)
+		}
+	}
+}
+
+func TestCastSchemaNoCast(t *testing.T) {
+	fmt.Println("\n================== TestCastSchemaNoCast")
+	o := NewDecoderOptions()
+	o.CastSchema = CastSchema{"invoice.total": CastFloat64}
+	m, err := o.NewMapXml(castSchemaData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.ValueForPath("invoice.total"); v != float64(123.45) {
+		t.Errorf("invoice.total: %#v", v)
+	}
+	// not in the schema and 'Cast' isn't set
+	if v, _ := m.ValueForPath("invoice.line[0].qty"); v != "3" {
+		t.Errorf("invoice.line[0].qty: %#v", v)
+	}
+}
+
+func TestCastSchemaError(t *testing.T) {
+	fmt.Println("\n================== TestCastSchemaError")
+	o := NewDecoderOptions()
+	o.CastSchema = CastSchema{"invoice.line.sku": CastInt64, "invoice.total": CastFloat64}
+	m, err := o.NewMapXml(castSchemaData)
+	fmt.Println("err:", err)
+	var cerr *CastError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("not a *CastError: %#v", err)
+	}
+	if cerr.Path != "invoice.line.sku" || cerr.Value != "A-1" || cerr.Type != CastInt64 {
+		t.Errorf("%#v", cerr)
+	}
+	if _, ok := cerr.Unwrap().(interface{ Error() string }); !ok {
+		t.Errorf("no conversion error")
+	}
+	// the doc is decoded with the value left as a string
+	if v, _ := m.ValueForPath("invoice.line[1].sku"); v != "B-2" {
+		t.Errorf("invoice.line[1].sku: %#v", v)
+	}
+	if v, _ := m.ValueForPath("invoice.total"); v != float64(123.45) {
+		t.Errorf("invoice.total: %#v", v)
+	}
+
+	// and with the raw XML
+	m, raw, err := o.NewMapXmlReaderRaw(bytes.NewReader(castSchemaData))
+	if !errors.As(err, &cerr) {
+		t.Fatalf("NewMapXmlReaderRaw - not a *CastError: %#v", err)
+	}
+	if v, _ := m.ValueForPath("invoice.total"); v != float64(123.45) || len(raw) == 0 {
+		t.Errorf("NewMapXmlReaderRaw - invoice.total: %#v, raw: %s", v, raw)
+	}
+
+	o.CastSchema = CastSchema{"invoice.total": "decimal"}
+	if _, err = o.NewMapXml(castSchemaData); err == nil || !strings.Contains(err.Error(), "unknown CastType") {
+		t.Errorf("unknown type: %v", err)
+	}
+}
+
+func TestCastSchemaSeq(t *testing.T) {
+	fmt.Println("\n================== TestCastSchemaSeq")
+	msv, err := castSchemaOptions().NewMapXmlSeq(castSchemaData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Map(msv)
+	checks := map[string]interface{}{
+		"invoice.id." + textK:                     "00123",
+		"invoice.ref." + textK:                    uint64(18446744073709551615),
+		"invoice.tax." + textK:                    float64(20.58),
+		"invoice." + attrK + ".lines." + textK:    int64(2),
+		"invoice.tax." + attrK + ".rate." + textK: "0.2",
+		"invoice.line[1].qty." + textK:            int64(7),
+	}
+	for path, want := range checks {
+		v, err := m.ValueForPath(path)
+		if err != nil {
+			t.Fatal(path, err)
+		}
+		if v != want {
+			t.Errorf("%s: got %#v, want %#v", path, v, want)
+		}
+	}
+
+	// the CastSchema types are encoded
+	b, err := msv.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(b))
+	for _, s := range []string{`lines="2"`, `<date>2026-10-16T00:00:00Z</date>`, `<ref>18446744073709551615</ref>`, `<total>123.45</total>`} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("no %s", s)
+		}
+	}
+}
+
+func TestCastSchemaReader(t *testing.T) {
+	fmt.Println("\n================== TestCastSchemaReader")
+	data := []byte(`<doc><n>1</n></doc><doc><n>x</n></doc><doc><n>3</n></doc>`)
+	o := NewDecoderOptions()
+	o.CastSchema = CastSchema{"doc.n": CastInt64}
+	var got []interface{}
+	var errs []string
+	err := o.HandleXmlReader(bytes.NewReader(data),
+		func(m Map) bool {
+			v, _ := m.ValueForPath("doc.n")
+			got = append(got, v)
+			return true
+		},
+		func(err error) bool {
+			errs = append(errs, err.Error())
+			return true
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(got, errs)
+	// the doc with the error is handled, too, with the value as a string
+	if len(got) != 3 || got[0] != int64(1) || got[1] != "x" || got[2] != int64(3) {
+		t.Errorf("got: %v", got)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0], "[xmlReader: 2] cast doc.n value \"x\" to int64") {
+		t.Errorf("errs: %v", errs)
+	}
+
+	// the path scanner goes on after a CastSchema error
+	o.CastSchema = CastSchema{"doc.e.n": CastInt64}
+	s := o.NewXmlPathScanner(bytes.NewReader([]byte(`<doc><e><n>1</n></e><e><n>x</n></e><e><n>3</n></e></doc>`)), "doc.e")
+	got, errs = nil, nil
+	for {
+		m, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		v, _ := m.ValueForPath("e.n")
+		got = append(got, v)
+	}
+	fmt.Println(got, errs)
+	if len(got) != 2 || got[1] != int64(3) || len(errs) != 1 || !strings.Contains(errs[0], "doc.e.n") {
+		t.Errorf("got: %v, errs: %v", got, errs)
+	}
+}
+
+func TestCastSchemaHandlers(t *testing.T) {
+	fmt.Println("\n================== TestCastSchemaHandlers")
+	o := NewDecoderOptions()
+	o.CastSchema = CastSchema{"a.n": CastInt64}
+	data := `<a><n>x</n></a><a><n>2</n></a>`
+
+	var maps, errs int
+	mapHandler := func(m Map) bool {
+		maps++
+		return true
+	}
+	errHandler := func(err error) bool {
+		var cerr *CastError
+		if !errors.As(err, &cerr) || cerr.Path != "a.n" {
+			t.Errorf("not a *CastError: %v", err)
+		}
+		errs++
+		return true
+	}
+	rawMapHandler := func(m Map, _ []byte) bool { return mapHandler(m) }
+	rawErrHandler := func(err error, _ []byte) bool { return errHandler(err) }
+	for name, handle := range map[string]func() error{
+		"HandleXmlReader": func() error {
+			return o.HandleXmlReader(strings.NewReader(data), mapHandler, errHandler)
+		},
+		"HandleXmlReaderRaw": func() error {
+			return o.HandleXmlReaderRaw(strings.NewReader(data), rawMapHandler, rawErrHandler)
+		},
+		"HandleXmlReaderContext": func() error {
+			return o.HandleXmlReaderContext(context.Background(), strings.NewReader(data), mapHandler, errHandler)
+		},
+		"HandleXmlReaderConcurrent": func() error {
+			return o.HandleXmlReaderConcurrent(strings.NewReader(data), 2, true, mapHandler, errHandler)
+		},
+	} {
+		maps, errs = 0, 0
+		if err := handle(); err != nil {
+			t.Fatal(name, err)
+		}
+		if maps != 2 || errs != 1 {
+			t.Errorf("%s: %d maps, %d errors", name, maps, errs)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// cdata.go - preserve "<![CDATA[...]]>" sections.
+// The xml.Decoder returns CDATA sections as plain xml.CharData, so NewMapXmlSeq()
+// records the bytes the decoder reads and checks the raw text of each xml.CharData
+// token.  Elements whose value was a CDATA section get a "#cdata":true entry that
+// msv.Xml() uses to re-encode the value as CDATA.
+
+package mxj
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+const (
+	cdataStart = "<![CDATA["
+	cdataEnd   = "]]>"
+)
+
+// rawRecorder keeps the bytes that an xml.Decoder has read but not yet
+// tokenized, so the raw text of the last token can be inspected.
+type rawRecorder struct {
+	r    io.ByteReader
+	buf  []byte
+	base int64 // input offset of buf[0]
+}
+
+func newRawRecorder(r io.Reader) *rawRecorder {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = myByteReader(r).(io.ByteReader)
+	}
+	return &rawRecorder{r: br}
+}
+
+// Read is needed for io.Reader; the xml.Decoder only uses ReadByte.
+func (r *rawRecorder) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	c, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	p[0] = c
+	return 1, nil
+}
+
+func (r *rawRecorder) ReadByte() (byte, error) {
+	c, err := r.r.ReadByte()
+	if err == nil {
+		r.buf = append(r.buf, c)
+	}
+	return c, err
+}
+
+// isCDATA reports whether the token read from input offset 'start' to 'end'
+// is a CDATA section.
+func (r *rawRecorder) isCDATA(start, end int64) bool {
+	if start < r.base || end-r.base > int64(len(r.buf)) {
+		return false
+	}
+	return bytes.HasPrefix(r.buf[start-r.base:end-r.base], []byte(cdataStart))
+}
+
+// discard drops the recorded bytes before input offset 'off'.
+func (r *rawRecorder) discard(off int64) {
+	if n := off - r.base; n > 0 && n <= int64(len(r.buf)) {
+		r.buf = r.buf[:copy(r.buf, r.buf[n:])]
+		r.base = off
+	}
+}
+
+// cdata wraps 's' in a CDATA section; "]]>" in 's' is split across two sections.
+func cdata(s string) string {
+	return cdataStart + strings.Replace(s, cdataEnd, "]]"+cdataEnd+cdataStart+">", -1) + cdataEnd
+}
+
+// raw returns the bytes read from input offset 'start' to 'end', if they're kept.
+func (r *rawRecorder) raw(start, end int64) []byte {
+	if start < r.base || end-r.base > int64(len(r.buf)) {
+		return nil
+	}
+	return r.buf[start-r.base : end-r.base]
+}
@@ -0,0 +1,142 @@
+package mxj
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const cdataDoc = `<doc><script><![CDATA[if (a < b && c > d) { x(); }]]></script><html lang="en"><![CDATA[<p>hello</p>]]></html><plain>a &amp; b</plain></doc>`
+
+func TestCDATASeq(t *testing.T) {
+	fmt.Println("\n================== TestCDATASeq")
+	m, err := NewMapXmlSeq([]byte(cdataDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(m)
+	doc := m["doc"].(map[string]interface{})
+	if v, _ := doc["script"].(map[string]interface{})[cdataK].(bool); !v {
+		t.Fatal("script: no", cdataK, doc["script"])
+	}
+	if v, _ := doc["html"].(map[string]interface{})[cdataK].(bool); !v {
+		t.Fatal("html: no", cdataK, doc["html"])
+	}
+	if _, ok := doc["plain"].(map[string]interface{})[cdataK]; ok {
+		t.Fatal("plain: unexpected", cdataK, doc["plain"])
+	}
+
+	x, err := m.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(x))
+	for _, s := range []string{
+		`<script><![CDATA[if (a < b && c > d) { x(); }]]></script>`,
+		`<html lang="en"><![CDATA[<p>hello</p>]]></html>`,
+	} {
+		if !strings.Contains(string(x), s) {
+			t.Fatal("missing:", s)
+		}
+	}
+}
+
+func TestCDATABeautifyXml(t *testing.T) {
+	fmt.Println("\n================== TestCDATABeautifyXml")
+	x, err := BeautifyXml([]byte(cdataDoc), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(x))
+	if !strings.Contains(string(x), `<![CDATA[<p>hello</p>]]>`) {
+		t.Fatal("CDATA section lost")
+	}
+}
+
+func TestCDATASeqReader(t *testing.T) {
+	fmt.Println("\n================== TestCDATASeqReader")
+	// two docs - the reader must not consume the second one
+	data := `<a><![CDATA[1 < 2]]></a><b>2 &lt; 3</b>`
+	r := bytes.NewBufferString(data)
+	m, err := NewMapXmlSeqReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m["a"].(map[string]interface{})[cdataK].(bool); !v {
+		t.Fatal("a: no", cdataK, m)
+	}
+	m, err = NewMapXmlSeqReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["b"].(map[string]interface{})[cdataK]; ok {
+		t.Fatal("b: unexpected", cdataK, m)
+	}
+}
+
+func TestCDATAMixed(t *testing.T) {
+	fmt.Println("\n================== TestCDATAMixed")
+	m, err := NewMapXmlSeq([]byte(`<a>x <![CDATA[<y>]]> z</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := m["a"].(map[string]interface{})
+	if a[textK] != "x<y>z" {
+		t.Fatal("text:", a[textK])
+	}
+}
+
+func TestCDATAMixedCast(t *testing.T) {
+	fmt.Println("\n================== TestCDATAMixedCast")
+	m, err := NewMapXmlSeq([]byte(`<a><b>12<![CDATA[<x>]]></b><c>1<![CDATA[2]]>3</c><d>45</d></a>`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := m["a"].(map[string]interface{})
+	b := a["b"].(map[string]interface{})
+	if b[textK] != "12<x>" || b[seqK] != 0 || b[cdataK] != true {
+		t.Fatal("b:", b)
+	}
+	c := a["c"].(map[string]interface{})
+	if c[textK] != "123" || c[seqK] != 1 || c[cdataK] != true {
+		t.Fatal("c:", c)
+	}
+	d := a["d"].(map[string]interface{})
+	if d[textK] != float64(45) || d[seqK] != 2 {
+		t.Fatal("d:", d)
+	}
+}
+
+func TestCDATAEncoderOption(t *testing.T) {
+	fmt.Println("\n================== TestCDATAEncoderOption")
+	opts := NewEncoderOptions()
+	opts.CDATA = true
+	m := Map{"doc": map[string]interface{}{
+		"code":  "a < b",
+		"end":   "x]]>y",
+		"empty": "",
+		"attr":  map[string]interface{}{attrPrefix + "k": "v", textK: "t&t"},
+	}}
+	x, err := opts.Xml(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(x))
+	for _, s := range []string{
+		`<code><![CDATA[a < b]]></code>`,
+		`<end><![CDATA[x]]]]><![CDATA[>y]]></end>`,
+		`<attr k="v"><![CDATA[t&t]]></attr>`,
+	} {
+		if !strings.Contains(string(x), s) {
+			t.Fatal("missing:", s)
+		}
+	}
+	mm, err := NewMapXml(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := mm.ValueForPath("doc.end"); v != "x]]>y" {
+		t.Fatal("doc.end:", v)
+	}
+}
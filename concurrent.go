@@ -0,0 +1,229 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// concurrent.go - HandleXmlReader and HandleJsonReader with a pool of workers.
+// The stream is read serially - it's just split into docs - and the docs are
+// decoded and handled by the workers.
+
+package mxj
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// HandleXmlReaderConcurrent bulk processes XML using 'workers' goroutines to
+// decode the docs and run the handlers.
+//
+//	'xmlReader' is an io.Reader for the XML (stream).
+//	'workers' is the number of worker goroutines; if <= 0, runtime.NumCPU() is used.
+//	'ordered', if 'true', calls mapHandler() and errHandler() one at a time in stream
+//	   order - only decoding is concurrent.  Otherwise, the handlers are called
+//	   concurrently by the workers and must be safe for concurrent use.
+//	'mapHandler' is the Map processing handler. Return of 'false' stops io.Reader processing.
+//	'errHandler' is the error processor. Return of 'false' stops io.Reader processing and returns the error.
+//	Note: at most 2*workers docs are read ahead of the handlers, so a slow handler
+//	      slows reading rather than buffering the stream.  Once a handler returns 'false'
+//	      no more docs are read; the docs in progress are finished, but in ordered mode
+//	      the docs that follow the stopping doc are not passed to the handlers.
+//	      HandleXmlReaderConcurrent returns after all workers have exited.
+func HandleXmlReaderConcurrent(xmlReader io.Reader, workers int, ordered bool, mapHandler func(Map) bool, errHandler func(error) bool) error {
+	return NewDecoderOptions().HandleXmlReaderConcurrent(xmlReader, workers, ordered, mapHandler, errHandler)
+}
+
+// HandleXmlReaderConcurrent bulk processes XML using the settings in 'o' and
+// 'workers' goroutines.  See the HandleXmlReaderConcurrent function.
+func (o *DecoderOptions) HandleXmlReaderConcurrent(xmlReader io.Reader, workers int, ordered bool, mapHandler func(Map) bool, errHandler func(error) bool) error {
+	oc := o.call()
+	dr := &xmlDocReader{o: oc, r: xmlReader}
+	return handleConcurrent("xmlReader", workers, ordered, dr.next, oc.NewMapXml, mapHandler, errHandler)
+}
+
+// HandleJsonReaderConcurrent bulk processes JSON using 'workers' goroutines to
+// decode the JSON objects and run the handlers.  The arguments are as for
+// HandleXmlReaderConcurrent(); see HandleJsonReader() on the bytes read past
+// the last value.
+func HandleJsonReaderConcurrent(jsonReader io.Reader, workers int, ordered bool, mapHandler func(Map) bool, errHandler func(error) bool) error {
+	jr := NewJsonReader(jsonReader)
+	return handleConcurrent("jsonReader", workers, ordered, jr.next, NewMapJson, mapHandler, errHandler)
+}
+
+// xmlDocReader splits an XML stream into docs.
+type xmlDocReader struct {
+	o   *DecoderOptions
+	r   io.Reader
+	buf bytes.Buffer
+}
+
+// next returns the raw XML of the next doc in the stream.  Only the tokens are
+// scanned; NewMapXml() does the decoding.
+func (x *xmlDocReader) next() ([]byte, error) {
+	x.buf.Reset()
+	// myTeeReader reads one byte at a time, so the xml.Decoder doesn't read
+	// beyond the end of the doc.
+	p := x.o.newDecoder(myTeeReader(x.r, &x.buf))
+	var depth int
+	for {
+		t, err := p.RawToken()
+		if err != nil {
+			if err == io.EOF && len(bytes.TrimSpace(x.buf.Bytes())) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch t.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				b := make([]byte, x.buf.Len())
+				copy(b, x.buf.Bytes())
+				return b, nil
+			}
+		}
+	}
+}
+
+// handleJob is a doc from the stream and, once decoded, its Map value.
+type handleJob struct {
+	n   int // position in the stream - 1, 2, ...
+	raw []byte
+	m   Map
+	err error
+}
+
+// handleConcurrent reads docs with 'next' and has a pool of workers 'decode' and
+// handle them.
+func handleConcurrent(label string, workers int, ordered bool,
+	next func() ([]byte, error), decode func([]byte) (Map, error),
+	mapHandler func(Map) bool, errHandler func(error) bool) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		wg      sync.WaitGroup
+		once    sync.Once
+		stopErr error
+	)
+	done := make(chan struct{})
+	stop := func(err error) {
+		once.Do(func() {
+			stopErr = err
+			close(done)
+		})
+	}
+	stopped := func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
+
+	// handle calls the handlers for a decoded doc; a 'false' return stops processing.
+	handle := func(j *handleJob) {
+		if j.err != nil {
+			err := fmt.Errorf("[%s: %d] %w", label, j.n, j.err)
+			if ok := errHandler(err); !ok {
+				stop(err)
+				return
+			}
+			if !isCastError(err) {
+				return
+			}
+		}
+		if len(j.m) != 0 {
+			if ok := mapHandler(j.m); !ok {
+				stop(nil)
+			}
+		}
+	}
+
+	// 'slots' limits the number of docs in progress - back-pressure on reading
+	slots := make(chan struct{}, 2*workers)
+	jobs := make(chan *handleJob, workers)
+	var results chan *handleJob
+	var delivered chan struct{}
+	if ordered {
+		results = make(chan *handleJob, workers)
+		delivered = make(chan struct{})
+		go func() {
+			defer close(delivered)
+			pending := make(map[int]*handleJob)
+			want := 1
+			for j := range results {
+				pending[j.n] = j
+				for {
+					jj, ok := pending[want]
+					if !ok {
+						break
+					}
+					delete(pending, want)
+					want++
+					if !stopped() {
+						handle(jj)
+					}
+					<-slots
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if j.err == nil && !stopped() {
+					j.m, j.err = decode(j.raw)
+				}
+				if ordered {
+					results <- j
+					continue
+				}
+				if !stopped() {
+					handle(j)
+				}
+				<-slots
+			}
+		}()
+	}
+
+	// read the stream in this goroutine
+	var n int
+read:
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-done:
+			break read
+		}
+		raw, err := next()
+		if err == io.EOF {
+			<-slots
+			break
+		}
+		n++
+		select {
+		case jobs <- &handleJob{n: n, raw: raw, err: err}:
+		case <-done:
+			<-slots
+			break read
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if ordered {
+		close(results)
+		<-delivered
+	}
+	return stopErr
+}
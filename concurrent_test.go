@@ -0,0 +1,142 @@
+package mxj
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func concurrentXmlData(n int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?>`)
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "<doc id=\"%d\"><v>%d</v></doc>\n", i, i)
+	}
+	return b.String()
+}
+
+func TestHandleXmlReaderConcurrentOrdered(t *testing.T) {
+	fmt.Println("\n================== TestHandleXmlReaderConcurrentOrdered")
+	var got []int
+	err := HandleXmlReaderConcurrent(strings.NewReader(concurrentXmlData(100)), 4, true,
+		func(m Map) bool {
+			v, _ := m.ValueForPath("doc.v")
+			var i int
+			fmt.Sscan(v.(string), &i)
+			got = append(got, i) // ordered - no concurrent calls
+			return true
+		},
+		func(err error) bool {
+			t.Fatal(err)
+			return false
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 100 {
+		t.Fatal("got", len(got), "docs")
+	}
+	for i, v := range got {
+		if v != i+1 {
+			t.Fatal("out of order at", i, ":", v)
+		}
+	}
+}
+
+func TestHandleXmlReaderConcurrentUnordered(t *testing.T) {
+	fmt.Println("\n================== TestHandleXmlReaderConcurrentUnordered")
+	var sum, cnt int64
+	opts := NewDecoderOptions()
+	opts.Cast = true
+	err := opts.HandleXmlReaderConcurrent(strings.NewReader(concurrentXmlData(100)), 0, false,
+		func(m Map) bool {
+			v, _ := m.ValueForPath("doc.v")
+			atomic.AddInt64(&sum, int64(v.(float64)))
+			atomic.AddInt64(&cnt, 1)
+			return true
+		},
+		func(err error) bool {
+			t.Error(err)
+			return false
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 100 || sum != 5050 {
+		t.Fatal("cnt:", cnt, "sum:", sum)
+	}
+}
+
+func TestHandleXmlReaderConcurrentStop(t *testing.T) {
+	fmt.Println("\n================== TestHandleXmlReaderConcurrentStop")
+	var mu sync.Mutex
+	var cnt int
+	err := HandleXmlReaderConcurrent(strings.NewReader(concurrentXmlData(1000)), 4, true,
+		func(m Map) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			cnt++
+			return cnt < 10
+		},
+		func(err error) bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 10 {
+		t.Fatal("handled after stop:", cnt)
+	}
+}
+
+func TestHandleXmlReaderConcurrentErr(t *testing.T) {
+	fmt.Println("\n================== TestHandleXmlReaderConcurrentErr")
+	data := `<doc><v>1</v></doc><doc><v>2</x></doc><doc><v>3</v></doc>`
+	var errs int
+	var docs int
+	err := HandleXmlReaderConcurrent(strings.NewReader(data), 2, true,
+		func(m Map) bool {
+			docs++
+			return true
+		},
+		func(err error) bool {
+			fmt.Println("err:", err)
+			errs++
+			return false
+		})
+	if err == nil || !strings.HasPrefix(err.Error(), "[xmlReader: 2]") {
+		t.Fatal("err:", err)
+	}
+	if errs != 1 || docs != 1 {
+		t.Fatal("errs:", errs, "docs:", docs)
+	}
+}
+
+func TestHandleJsonReaderConcurrent(t *testing.T) {
+	fmt.Println("\n================== TestHandleJsonReaderConcurrent")
+	var b bytes.Buffer
+	for i := 1; i <= 50; i++ {
+		fmt.Fprintf(&b, "{\"id\":%d}\n", i)
+	}
+	b.WriteString(`{"id":`)
+	var want error = errors.New("stop")
+	var got []float64
+	err := HandleJsonReaderConcurrent(&b, 3, true,
+		func(m Map) bool {
+			got = append(got, m["id"].(float64))
+			return true
+		},
+		func(err error) bool {
+			fmt.Println("err:", err)
+			want = err
+			return false
+		})
+	if err != want {
+		t.Fatal("err:", err)
+	}
+	if len(got) != 50 || got[49] != 50 {
+		t.Fatal("got:", got)
+	}
+}
@@ -0,0 +1,341 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// decoderoptions.go - per-call settings for the NewMapXml and NewMapXmlSeq decoders.
+// The package-level toggles - CoerceKeysToLower(), SetAttrPrefix(), etc. - only
+// set the defaults that NewDecoderOptions() copies.
+
+package mxj
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DecoderOptions holds the XML decoding settings for a single call. Since each
+// decoding call works on its own copy, a DecoderOptions value can be used by
+// concurrent goroutines and changing the package-level toggles while a document
+// is being parsed does not affect the result.
+//
+// Use NewDecoderOptions() to get a value initialized with the current package
+// settings; the zero value is not the package default - e.g., CastToFloat is 'false'.
+//
+//	opts := mxj.NewDecoderOptions()
+//	opts.AttrPrefix = "@"
+//	opts.Cast = true
+//	m, err := opts.NewMapXml(xmlVal)
+type DecoderOptions struct {
+	// Cast values to bool or float64 if possible - the 'cast' argument to NewMapXml().
+	Cast bool
+	// CastToInt, CastToFloat, CastToBool and CastNanInf refine 'Cast' handling.
+	// See CastValuesToInt(), CastValuesToFloat(), CastValuesToBool() and CastNanInf().
+	CastToInt   bool
+	CastToFloat bool
+	CastToBool  bool
+	CastNanInf  bool
+	// CastSchema sets the type of the values at its paths - see CastSchema.
+	// It applies whether or not 'Cast' is set.
+	CastSchema CastSchema
+	// CheckTagToSkip - see SetCheckTagToSkipFunc().
+	CheckTagToSkip func(string) bool
+	// AttrPrefix is prepended to attribute keys - see SetAttrPrefix().
+	// (Not applicable for NewMapXmlSeq(), etc.)
+	AttrPrefix string
+	// KeysToLower - see CoerceKeysToLower(). (Not applicable for NewMapXmlSeq(), etc.)
+	KeysToLower bool
+	// KeysToSnakeCase - see CoerceKeysToSnakeCase().
+	KeysToSnakeCase bool
+	// DisableTrimWhiteSpace - see DisableTrimWhiteSpace().
+	DisableTrimWhiteSpace bool
+	// DecodeSimpleValuesAsMap - see DecodeSimpleValuesAsMap().
+	DecodeSimpleValuesAsMap bool
+	// IncludeTagSeqNum - see IncludeTagSeqNum().
+	IncludeTagSeqNum bool
+	// HandleXMPPStreamTag - see HandleXMPPStreamTag().
+	HandleXMPPStreamTag bool
+	// EscapeChars escapes XML characters in decoded values - see XMLEscapeCharsDecoder().
+	EscapeChars bool
+	// TextKey is the key for the value of a simple element with attributes, "#text" by default.
+	// See SetGlobalKeyMapPrefix().
+	TextKey string
+	// Namespaces selects how element and attribute name spaces are represented in
+	// the keys of NewMapXml(), etc.; NamespaceLocal by default. See NamespaceMode.
+	Namespaces NamespaceMode
+	// CustomDecoder and CharsetReader - see the CustomDecoder and XmlCharsetReader variables.
+	CustomDecoder *xml.Decoder
+	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+
+	// the remaining MapSeq keys - "#seq", "#attr", etc. - as of NewDecoderOptions()
+	keys mapKeys
+	// derived from DisableTrimWhiteSpace when a call is started
+	trimRunes string
+	// name space declarations in scope while parsing - see namespace.go
+	ns *nsScope
+	// the raw input for detecting CDATA sections - see cdata.go
+	raw *rawRecorder
+	// CastSchema handling - see castschema.go
+	castRules []castRule
+	path      []string
+	castErr   error
+}
+
+// mapKeys holds the special key labels used in Map and MapSeq values.
+type mapKeys struct {
+	text, seq, comment, attr, directive, procinst, target, inst, cdata string
+}
+
+// defaultMapKeys returns the current package key labels - see SetGlobalKeyMapPrefix().
+func defaultMapKeys() mapKeys {
+	return mapKeys{
+		text:      textK,
+		seq:       seqK,
+		comment:   commentK,
+		attr:      attrK,
+		directive: directiveK,
+		procinst:  procinstK,
+		target:    targetK,
+		inst:      instK,
+		cdata:     cdataK,
+	}
+}
+
+// NewDecoderOptions returns a DecoderOptions value initialized with the current
+// package settings.
+func NewDecoderOptions() *DecoderOptions {
+	return &DecoderOptions{
+		CastToInt:               castToInt,
+		CastToFloat:             castToFloat,
+		CastToBool:              castToBool,
+		CastNanInf:              castNanInf,
+		CheckTagToSkip:          checkTagToSkip,
+		AttrPrefix:              attrPrefix,
+		KeysToLower:             lowerCase,
+		KeysToSnakeCase:         snakeCaseKeys,
+		DisableTrimWhiteSpace:   disableTrimWhiteSpace,
+		DecodeSimpleValuesAsMap: decodeSimpleValuesAsMap,
+		IncludeTagSeqNum:        includeTagSeqNum,
+		HandleXMPPStreamTag:     handleXMPPStreamTag,
+		EscapeChars:             xmlEscapeCharsDecoder,
+		TextKey:                 textK,
+		CustomDecoder:           CustomDecoder,
+		CharsetReader:           XmlCharsetReader,
+		keys:                    defaultMapKeys(),
+	}
+}
+
+// decoderOptions returns the package default DecoderOptions with 'cast' applied
+// as passed to NewMapXml(), etc.
+func decoderOptions(cast []bool) *DecoderOptions {
+	o := NewDecoderOptions()
+	if len(cast) == 1 {
+		o.Cast = cast[0]
+	}
+	return o
+}
+
+// call returns the copy of 'o' that a single decoding call works with.
+// A nil 'o' is the package default.
+func (o *DecoderOptions) call() *DecoderOptions {
+	if o == nil {
+		o = NewDecoderOptions()
+	}
+	oc := *o
+	oc.ns = nil
+	oc.raw = nil
+	if oc.keys == (mapKeys{}) {
+		oc.keys = defaultMapKeys()
+	}
+	if oc.TextKey != "" {
+		oc.keys.text = oc.TextKey
+	}
+	oc.castRules = nil
+	oc.path = nil
+	oc.castErr = nil
+	if len(oc.CastSchema) > 0 {
+		oc.castRules = compileCastSchema(oc.CastSchema, oc.keys.text)
+	}
+	if oc.DisableTrimWhiteSpace {
+		oc.trimRunes = "\t\r\b\n"
+	} else {
+		oc.trimRunes = "\t\r\b\n "
+	}
+	return &oc
+}
+
+// newDecoder returns an xml.Decoder for 'rdr' with the CustomDecoder or
+// CharsetReader settings applied.
+func (o *DecoderOptions) newDecoder(rdr io.Reader) *xml.Decoder {
+	p := xml.NewDecoder(rdr)
+	if o.CustomDecoder != nil {
+		useCustomDecoder(p, o.CustomDecoder)
+	} else {
+		p.CharsetReader = o.CharsetReader
+	}
+	return p
+}
+
+// ------------------- Map decoding using DecoderOptions -------------------------
+
+// NewMapXml converts a XML doc into a Map using the settings in 'o'.
+// See the NewMapXml function.
+func (o *DecoderOptions) NewMapXml(xmlVal []byte) (Map, error) {
+	return o.call().xmlToMap(xmlVal)
+}
+
+// NewMapXmlReader gets the next XML doc from an io.Reader as a Map value using
+// the settings in 'o'.  See the NewMapXmlReader function.
+func (o *DecoderOptions) NewMapXmlReader(xmlReader io.Reader) (Map, error) {
+	// We need to put an *os.File reader in a ByteReader or the xml.NewDecoder
+	// will wrap it in a bufio.Reader and seek on the file beyond where the
+	// xml.Decoder parses!
+	if _, ok := xmlReader.(io.ByteReader); !ok {
+		xmlReader = myByteReader(xmlReader) // see code at EOF
+	}
+
+	// build the map
+	return o.call().xmlReaderToMap(xmlReader)
+}
+
+// NewMapXmlReaderRaw gets the next XML doc from an io.Reader as a Map value and
+// the raw XML using the settings in 'o'.  See the NewMapXmlReaderRaw function.
+func (o *DecoderOptions) NewMapXmlReaderRaw(xmlReader io.Reader) (Map, []byte, error) {
+	// create TeeReader so we can retrieve raw XML
+	buf := make([]byte, 0)
+	wb := bytes.NewBuffer(buf)
+	trdr := myTeeReader(xmlReader, wb) // see code at EOF
+
+	m, err := o.call().xmlReaderToMap(trdr)
+
+	// retrieve the raw XML that was decoded
+	b := wb.Bytes()
+
+	// a *CastError is returned with the Map, as for NewMapXmlReader
+	if _, ok := err.(*CastError); err != nil && !ok {
+		return nil, b, err
+	}
+
+	return m, b, err
+}
+
+// ------------------- MapSeq decoding using DecoderOptions -------------------------
+
+// NewMapXmlSeq converts a XML doc into a MapSeq value using the settings in 'o'.
+// See the NewMapXmlSeq function.
+func (o *DecoderOptions) NewMapXmlSeq(xmlVal []byte) (MapSeq, error) {
+	return o.call().xmlSeqToMap(xmlVal)
+}
+
+// NewMapFormattedXmlSeq converts a whitespace formatted XML doc into a MapSeq
+// value using the settings in 'o'.  See the NewMapFormattedXmlSeq function.
+func (o *DecoderOptions) NewMapFormattedXmlSeq(xmlVal []byte) (MapSeq, error) {
+	return o.call().xmlSeqToMap(cleanFormattedXml(xmlVal))
+}
+
+// NewMapXmlSeqReader returns next XML doc from an io.Reader as a MapSeq value
+// using the settings in 'o'.  See the NewMapXmlSeqReader function.
+func (o *DecoderOptions) NewMapXmlSeqReader(xmlReader io.Reader) (MapSeq, error) {
+	// We need to put an *os.File reader in a ByteReader or the xml.NewDecoder
+	// will wrap it in a bufio.Reader and seek on the file beyond where the
+	// xml.Decoder parses!
+	if _, ok := xmlReader.(io.ByteReader); !ok {
+		xmlReader = myByteReader(xmlReader) // see code at EOF
+	}
+
+	// build the map
+	return o.call().xmlSeqReaderToMap(xmlReader)
+}
+
+// NewMapXmlSeqReaderRaw returns the next XML doc from an io.Reader as a MapSeq
+// value and the raw XML using the settings in 'o'.  See the NewMapXmlSeqReaderRaw function.
+func (o *DecoderOptions) NewMapXmlSeqReaderRaw(xmlReader io.Reader) (MapSeq, []byte, error) {
+	// create TeeReader so we can retrieve raw XML
+	buf := make([]byte, 0)
+	wb := bytes.NewBuffer(buf)
+	trdr := myTeeReader(xmlReader, wb)
+
+	m, err := o.call().xmlSeqReaderToMap(trdr)
+
+	// retrieve the raw XML that was decoded
+	b := wb.Bytes()
+
+	// err may be NoRoot
+	return m, b, err
+}
+
+// --------------  Handle XML stream using DecoderOptions --------------------
+
+// HandleXmlReader bulk processes XML using the settings in 'o'.
+// See the HandleXmlReader function.
+func (o *DecoderOptions) HandleXmlReader(xmlReader io.Reader, mapHandler func(Map) bool, errHandler func(error) bool) error {
+	var n int
+	for {
+		m, merr := o.NewMapXmlReader(xmlReader)
+		n++
+
+		// handle error condition with errhandler
+		if merr != nil && merr != io.EOF {
+			merr = fmt.Errorf("[xmlReader: %d] %w", n, merr)
+			if ok := errHandler(merr); !ok {
+				// caused reader termination
+				return merr
+			}
+			if !isCastError(merr) {
+				continue
+			}
+		}
+
+		// pass to maphandler
+		if len(m) != 0 {
+			if ok := mapHandler(m); !ok {
+				break
+			}
+		} else if merr != io.EOF {
+			time.Sleep(xhandlerPollInterval)
+		}
+
+		if merr == io.EOF {
+			break
+		}
+	}
+	return nil
+}
+
+// HandleXmlReaderRaw bulk processes XML using the settings in 'o' and passes
+// the raw XML to the handlers.  See the HandleXmlReaderRaw function.
+func (o *DecoderOptions) HandleXmlReaderRaw(xmlReader io.Reader, mapHandler func(Map, []byte) bool, errHandler func(error, []byte) bool) error {
+	var n int
+	for {
+		m, raw, merr := o.NewMapXmlReaderRaw(xmlReader)
+		n++
+
+		// handle error condition with errhandler
+		if merr != nil && merr != io.EOF {
+			merr = fmt.Errorf("[xmlReader: %d] %w", n, merr)
+			if ok := errHandler(merr, raw); !ok {
+				// caused reader termination
+				return merr
+			}
+			if !isCastError(merr) {
+				continue
+			}
+		}
+
+		// pass to maphandler
+		if len(m) != 0 {
+			if ok := mapHandler(m, raw); !ok {
+				break
+			}
+		} else if merr != io.EOF {
+			time.Sleep(xhandlerPollInterval)
+		}
+
+		if merr == io.EOF {
+			break
+		}
+	}
+	return nil
+}
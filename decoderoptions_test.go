@@ -0,0 +1,121 @@
+package mxj
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+var decoderOptionsData = []byte(`<doc><Item id="007" Code="A-1">3.5</Item><flag>true</flag></doc>`)
+
+func TestDecoderOptions(t *testing.T) {
+	fmt.Println("------------ decoderoptions_test.go")
+	opts := NewDecoderOptions()
+	opts.AttrPrefix = "@"
+	opts.Cast = true
+	opts.KeysToLower = true
+
+	m, err := opts.NewMapXml(decoderOptionsData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.ValueForPath("doc.item.@code"); v != "A-1" {
+		t.Fatal("doc.item.@code:", v)
+	}
+	if v, _ := m.ValueForPath("doc.item.@id"); v != float64(7) {
+		t.Fatalf("doc.item.@id: %#v", v)
+	}
+	if v, _ := m.ValueForPath("doc.flag"); v != true {
+		t.Fatalf("doc.flag: %#v", v)
+	}
+
+	// package defaults are unchanged
+	m, err = NewMapXml(decoderOptionsData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.ValueForPath("doc.Item." + attrPrefix + "id"); v != "007" {
+		t.Fatalf("doc.Item.%sid: %#v", attrPrefix, v)
+	}
+}
+
+func TestDecoderOptionsConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			opts := NewDecoderOptions()
+			opts.AttrPrefix = fmt.Sprintf("a%d_", i)
+			opts.CheckTagToSkip = func(tag string) bool { return tag == "flag" }
+			opts.Cast = i%2 == 0
+			m, err := opts.NewMapXml(decoderOptionsData)
+			if err != nil {
+				errs <- err
+				return
+			}
+			v, err := m.ValueForPath("doc.Item." + opts.AttrPrefix + "id")
+			if err != nil {
+				errs <- fmt.Errorf("%d: %s", i, err)
+				return
+			}
+			if opts.Cast && v != float64(7) || !opts.Cast && v != "007" {
+				errs <- fmt.Errorf("%d: got %#v", i, v)
+			}
+			if v, _ := m.ValueForPath("doc.flag"); v != "true" {
+				errs <- fmt.Errorf("%d: flag got %#v", i, v)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDecoderOptionsSeqAndReader(t *testing.T) {
+	opts := NewDecoderOptions()
+	ms, err := opts.NewMapXmlSeq(decoderOptionsData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := ms.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(x) != string(decoderOptionsData) {
+		t.Fatal("got:", string(x))
+	}
+
+	opts.TextKey = "#value"
+	ms, err = opts.NewMapXmlSeq(decoderOptionsData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := Map(ms).ValueForPath("doc.flag.#value"); v != "true" {
+		t.Fatalf("doc.flag.#value: %#v", v)
+	}
+
+	var n int
+	opts.AttrPrefix = "_"
+	rdr := bytes.NewReader(append(decoderOptionsData, decoderOptionsData...))
+	err = opts.HandleXmlReader(rdr, func(m Map) bool {
+		if _, err := m.ValueForPath("doc.Item._Code"); err != nil {
+			t.Error(err)
+		}
+		n++
+		return true
+	}, func(err error) bool {
+		t.Error(err)
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatal("handled:", n)
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// diff.go - the structural differences between two Map values.
+
+package mxj
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeOp is the kind of a Change.
+type ChangeOp string
+
+const (
+	ChangeAdded       ChangeOp = "added"        // a value in the new Map only
+	ChangeRemoved     ChangeOp = "removed"      // a value in the old Map only
+	ChangeChanged     ChangeOp = "changed"      // a value of the same type that's different
+	ChangeTypeChanged ChangeOp = "type-changed" // a value of another type - e.g., a map for a string
+)
+
+// Change is a difference between two Map values found by mv.Diff().
+type Change struct {
+	Op   ChangeOp
+	Path string      // as for ValuesForPath() - e.g., "doc.items.item[2].price"
+	Old  interface{} // nil if Op is ChangeAdded
+	New  interface{} // nil if Op is ChangeRemoved
+}
+
+// Changes is the list of Change values returned by mv.Diff().
+type Changes []Change
+
+// DiffOptions are the settings for mv.Diff().  The zero value compares all
+// values as they are and list members by position.
+type DiffOptions struct {
+	// NormalizeNumbers compares numbers by value - float64(1), int64(1) and json.Number("1")
+	// are the same.
+	NormalizeNumbers bool
+	// IgnoreAttrs skips the attribute keys - those with the attribute prefix, see
+	// SetAttrPrefix(); an element with attributes is compared by its "#text" value.
+	IgnoreAttrs bool
+	// ListKeys matches the members of the lists at the paths by the value of a key,
+	// rather than by position.  As for MergeOptions.Paths the paths are dot-separated
+	// keys - without indexes - and "*" matches any key at that level:
+	//    opts.ListKeys = map[string]string{"feed.items.item": "-sku"}
+	// The Path of a Change in a matched member has a predicate - "feed.items.item[-sku='a1'].price".
+	ListKeys map[string]string
+}
+
+// Diff returns the changes that make the Map into 'other' - values that are in
+// 'other' only are ChangeAdded, values in the Map only are ChangeRemoved.  The
+// changes are in the order of the values, with the keys of maps sorted.
+//
+//	A value that isn't a list and a list are compared as a list of one and the list -
+//	as the XML decoder has it when a tag is repeated in one doc only.
+//	See Changes.String() for a "unified diff" rendering.
+func (mv Map) Diff(other Map, opts ...*DiffOptions) Changes {
+	o := &DiffOptions{}
+	if len(opts) == 1 && opts[0] != nil {
+		o = opts[0]
+	}
+	d := &differ{opts: o}
+	paths := make([]string, 0, len(o.ListKeys))
+	for path := range o.ListKeys {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		wi, wj := strings.Count(paths[i], "*"), strings.Count(paths[j], "*")
+		if wi != wj {
+			return wi < wj
+		}
+		return paths[i] < paths[j]
+	})
+	for _, path := range paths {
+		d.listKeys = append(d.listKeys, diffListKey{strings.Split(path, "."), o.ListKeys[path]})
+	}
+
+	d.diffMaps(map[string]interface{}(mv), map[string]interface{}(other), nil, "")
+	return d.changes
+}
+
+type diffListKey struct {
+	keys []string
+	key  string
+}
+
+type differ struct {
+	opts     *DiffOptions
+	listKeys []diffListKey
+	changes  Changes
+}
+
+func (d *differ) add(op ChangeOp, path string, oldVal, newVal interface{}) {
+	d.changes = append(d.changes, Change{op, path, oldVal, newVal})
+}
+
+// diffMaps compares the maps at 'path'; 'keys' is the path without indexes.
+func (d *differ) diffMaps(a, b map[string]interface{}, keys []string, path string) {
+	names := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		names[k] = true
+	}
+	for k := range b {
+		names[k] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for k := range names {
+		if d.opts.IgnoreAttrs && attrPrefix != "" && strings.HasPrefix(k, attrPrefix) {
+			continue
+		}
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, k := range sorted {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		kk := make([]string, len(keys)+1)
+		copy(kk, keys)
+		kk[len(keys)] = k
+		av, aok := a[k]
+		bv, bok := b[k]
+		switch {
+		case !bok:
+			d.add(ChangeRemoved, p, av, nil)
+		case !aok:
+			d.add(ChangeAdded, p, nil, bv)
+		default:
+			d.diffValues(av, bv, kk, p)
+		}
+	}
+}
+
+// diffValues compares the values at 'path'.
+func (d *differ) diffValues(a, b interface{}, keys []string, path string) {
+	if d.opts.IgnoreAttrs {
+		a, b = d.noAttrs(a), d.noAttrs(b)
+	}
+	al, aList := a.([]interface{})
+	bl, bList := b.([]interface{})
+	if aList || bList {
+		if !aList {
+			al = []interface{}{a}
+		}
+		if !bList {
+			bl = []interface{}{b}
+		}
+		d.diffLists(al, bl, keys, path)
+		return
+	}
+
+	am, aMap := a.(map[string]interface{})
+	bm, bMap := b.(map[string]interface{})
+	switch {
+	case aMap && bMap:
+		d.diffMaps(am, bm, keys, path)
+	case aMap || bMap:
+		d.add(ChangeTypeChanged, path, a, b)
+	default:
+		d.diffScalars(a, b, path)
+	}
+}
+
+func (d *differ) diffScalars(a, b interface{}, path string) {
+	if d.opts.NormalizeNumbers {
+		ar, aok := diffNumber(a)
+		br, bok := diffNumber(b)
+		if aok && bok {
+			if ar.Cmp(br) != 0 {
+				d.add(ChangeChanged, path, a, b)
+			}
+			return
+		}
+	}
+	switch {
+	case reflect.TypeOf(a) != reflect.TypeOf(b):
+		d.add(ChangeTypeChanged, path, a, b)
+	case !reflect.DeepEqual(a, b):
+		d.add(ChangeChanged, path, a, b)
+	}
+}
+
+// diffLists compares the lists at 'path' by position or, if there's a
+// ListKeys entry for the path, by key value.
+func (d *differ) diffLists(a, b []interface{}, keys []string, path string) {
+	var key string
+	for _, lk := range d.listKeys {
+		if matchPath(lk.keys, keys) {
+			key = lk.key
+			break
+		}
+	}
+	if key == "" {
+		for i := 0; i < len(a) || i < len(b); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(b):
+				d.add(ChangeRemoved, p, a[i], nil)
+			case i >= len(a):
+				d.add(ChangeAdded, p, nil, b[i])
+			default:
+				d.diffValues(a[i], b[i], keys, p)
+			}
+		}
+		return
+	}
+
+	// the members by key value; members without a key - or with a key value
+	// that's been seen - are compared by position
+	bIdx := make(map[string]int)
+	for i, v := range b {
+		if kv, ok := diffKeyValue(v, key); ok {
+			if _, ok := bIdx[kv]; !ok {
+				bIdx[kv] = i
+			}
+		}
+	}
+	matched := make(map[int]bool)
+	seen := make(map[string]bool)
+	for i, v := range a {
+		kv, ok := diffKeyValue(v, key)
+		if !ok || seen[kv] {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			if i < len(b) && !matched[i] {
+				if _, ok := diffKeyValue(b[i], key); !ok {
+					matched[i] = true
+					d.diffValues(v, b[i], keys, p)
+					continue
+				}
+			}
+			d.add(ChangeRemoved, p, v, nil)
+			continue
+		}
+		seen[kv] = true
+		p := path + "[" + diffPredicate(key, textValue(v.(map[string]interface{})[key])) + "]"
+		j, ok := bIdx[kv]
+		if !ok {
+			d.add(ChangeRemoved, p, v, nil)
+			continue
+		}
+		matched[j] = true
+		d.diffValues(v, b[j], keys, p)
+	}
+	for j, v := range b {
+		if matched[j] {
+			continue
+		}
+		p := fmt.Sprintf("%s[%d]", path, j)
+		if kv, ok := diffKeyValue(v, key); ok && bIdx[kv] == j {
+			p = path + "[" + diffPredicate(key, textValue(v.(map[string]interface{})[key])) + "]"
+		}
+		d.add(ChangeAdded, p, nil, v)
+	}
+}
+
+// noAttrs returns the value without attribute keys - an element with only a
+// "#text" value left is its "#text" value, and one with nothing left is "",
+// as an empty element is decoded.
+func (d *differ) noAttrs(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	var attrs int
+	for k := range m {
+		if attrPrefix != "" && strings.HasPrefix(k, attrPrefix) {
+			attrs++
+		}
+	}
+	if attrs > 0 && len(m) == attrs {
+		return ""
+	}
+	if t, ok := m[textK]; ok && len(m) == attrs+1 {
+		return t
+	}
+	return v
+}
+
+// diffKeyValue returns the ListKeys 'key' value of a list member as a string.
+func diffKeyValue(v interface{}, key string) (string, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	kv, ok := m[key]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(textValue(kv)), true
+}
+
+// diffPredicate returns the path predicate for a list member with the 'key' value 'v'.
+func diffPredicate(key string, v interface{}) string {
+	if _, ok := diffNumber(v); ok {
+		return fmt.Sprintf("%s=%v", key, v)
+	}
+	s := fmt.Sprint(v)
+	if strings.Contains(s, "'") {
+		return key + `="` + s + `"`
+	}
+	return key + "='" + s + "'"
+}
+
+// diffNumber returns a numeric value as a big.Rat.
+func diffNumber(v interface{}) (*big.Rat, bool) {
+	r := new(big.Rat)
+	switch vv := v.(type) {
+	case float64:
+		if r.SetFloat64(vv) == nil {
+			return nil, false
+		}
+		return r, true
+	case float32:
+		if r.SetFloat64(float64(vv)) == nil {
+			return nil, false
+		}
+		return r, true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		if _, ok := r.SetString(fmt.Sprint(vv)); ok {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// String renders the changes as a "unified diff" - a "-" line with the old value
+// and a "+" line with the new value for each path:
+//
+//	-doc.items.item[1].price: "12"
+//	+doc.items.item[1].price: "15"
+//	+doc.items.item[2]: {"name":"nut","price":"3"}
+func (c Changes) String() string {
+	var b strings.Builder
+	for _, ch := range c {
+		if ch.Op != ChangeAdded {
+			fmt.Fprintf(&b, "-%s: %s\n", ch.Path, diffValue(ch.Old))
+		}
+		if ch.Op != ChangeRemoved {
+			fmt.Fprintf(&b, "+%s: %s\n", ch.Path, diffValue(ch.New))
+		}
+	}
+	return b.String()
+}
+
+// diffValue returns the value as JSON, if it can be encoded, for Changes.String().
+func diffValue(v interface{}) string {
+	if j, err := json.Marshal(v); err == nil {
+		return string(j)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,138 @@
+package mxj
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var diffOld = []byte(`<feed version="1">
+	<items>
+		<item sku="a1"><name>bolt</name><price currency="USD">12</price></item>
+		<item sku="a2"><name>nut</name><price currency="USD">3</price></item>
+		<item sku="a3"><name>washer</name><price currency="USD">1</price></item>
+	</items>
+	<vendor>Acme</vendor>
+	<note>old</note>
+</feed>`)
+
+var diffNew = []byte(`<feed version="2">
+	<items>
+		<item sku="a2"><name>nut</name><price currency="EUR">3</price></item>
+		<item sku="a1"><name>bolt</name><price currency="USD">15</price></item>
+		<item sku="a4"><name>screw</name><price currency="USD">2</price></item>
+	</items>
+	<vendor><name>Acme</name></vendor>
+</feed>`)
+
+func TestDiff(t *testing.T) {
+	fmt.Println("\n================== TestDiff")
+	ap := attrPrefix
+	a, err := NewMapXml(diffOld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewMapXml(diffNew)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// by position
+	c := a.Diff(b)
+	fmt.Print(c)
+	want := []string{
+		"changed feed." + ap + "version",
+		"changed feed.items.item[0]." + ap + "sku",
+		"changed feed.items.item[0].name",
+		"changed feed.items.item[0].price." + textK,
+		"changed feed.items.item[0].price." + ap + "currency",
+		"changed feed.items.item[1]." + ap + "sku",
+		"changed feed.items.item[1].name",
+		"changed feed.items.item[1].price." + textK,
+		"changed feed.items.item[2]." + ap + "sku",
+		"changed feed.items.item[2].name",
+		"changed feed.items.item[2].price." + textK,
+		"removed feed.note",
+		"type-changed feed.vendor",
+	}
+	if got := changeList(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("by position:\n%s", strings.Join(got, "\n"))
+	}
+
+	// by key, w/o attributes
+	c = a.Diff(b, &DiffOptions{ListKeys: map[string]string{"feed.*.item": ap + "sku"}, IgnoreAttrs: true})
+	fmt.Print(c)
+	want = []string{
+		"changed feed.items.item[" + ap + "sku='a1'].price",
+		"removed feed.items.item[" + ap + "sku='a3']",
+		"added feed.items.item[" + ap + "sku='a4']",
+		"removed feed.note",
+		"type-changed feed.vendor",
+	}
+	if got := changeList(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("by key:\n%s", strings.Join(got, "\n"))
+	}
+	// the paths are ValuesForPath paths
+	for _, ch := range c {
+		m := a
+		if ch.Op == ChangeAdded {
+			m = b
+		}
+		v, err := m.ValuesForPath(ch.Path)
+		if err != nil || len(v) != 1 {
+			t.Errorf("%s: %v, %v", ch.Path, v, err)
+		}
+	}
+	if c[0].Old != "12" || c[0].New != "15" {
+		t.Errorf("values: %#v", c[0])
+	}
+
+	s := c.String()
+	if !strings.Contains(s, "-feed.items.item["+ap+"sku='a1'].price: \"12\"\n+feed.items.item["+ap+"sku='a1'].price: \"15\"\n") ||
+		!strings.Contains(s, "+feed.vendor: {\"name\":\"Acme\"}\n") {
+		t.Errorf("unified:\n%s", s)
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	fmt.Println("\n================== TestDiffValues")
+	a := Map{"doc": map[string]interface{}{"n": float64(1), "i": int64(2), "j": json.Number("3.50"), "s": "x", "l": "one"}}
+	b := Map{"doc": map[string]interface{}{"n": int64(1), "i": json.Number("2"), "j": 3.5, "s": "x", "l": []interface{}{"one", "two"}}}
+
+	if got := changeList(a.Diff(b)); !reflect.DeepEqual(got, []string{
+		"type-changed doc.i", "type-changed doc.j", "added doc.l[1]", "type-changed doc.n",
+	}) {
+		t.Errorf("as is: %v", got)
+	}
+	if got := changeList(a.Diff(b, &DiffOptions{NormalizeNumbers: true})); !reflect.DeepEqual(got, []string{"added doc.l[1]"}) {
+		t.Errorf("normalized: %v", got)
+	}
+	b["doc"].(map[string]interface{})["n"] = 1.5
+	if got := changeList(a.Diff(b, &DiffOptions{NormalizeNumbers: true})); !reflect.DeepEqual(got, []string{"added doc.l[1]", "changed doc.n"}) {
+		t.Errorf("normalized: %v", got)
+	}
+	if c := a.Diff(a); len(c) != 0 {
+		t.Errorf("same: %v", c)
+	}
+}
+
+func TestDiffIgnoreAttrs(t *testing.T) {
+	fmt.Println("\n================== TestDiffIgnoreAttrs")
+	a, _ := NewMapXml([]byte(`<doc><e x="1"/><f y="2">t</f><g z="3"/></doc>`))
+	b, _ := NewMapXml([]byte(`<doc><e/><f>t</f><g>u</g></doc>`))
+	c := a.Diff(b, &DiffOptions{IgnoreAttrs: true})
+	fmt.Print(c)
+	if got := changeList(c); !reflect.DeepEqual(got, []string{"changed doc.g"}) {
+		t.Errorf("got: %v", got)
+	}
+}
+
+func changeList(c Changes) []string {
+	s := make([]string, len(c))
+	for i, ch := range c {
+		s[i] = string(ch.Op) + " " + ch.Path
+	}
+	return s
+}
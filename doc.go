@@ -14,6 +14,32 @@ Related Packages:
 	checkxml: github.com/clbanning/checkxml provides functions for validating XML data.
 
 Notes:
+	2026.10.16: fix MapSeq.Xml() with XmlGoEmptyElemSyntax() set encoding an empty element as "<b</b>" rather than "<b></b>".
+	2026.10.16: add CanonicalXml() and msv.C14N() for Canonical XML 1.0 and Exclusive XML Canonicalization, with and without comments.
+	2026.10.16: add mv.MergePatch() and CreateMergePatch() for JSON Merge Patch (RFC 7396), with an option for "" XML empty values as null.
+	2026.10.16: add mv.ApplyPatch() and mv.CreatePatch() for JSON Patch (RFC 6902) documents; see ParsePatch() and PatchError.
+	2026.10.16: add mv.Diff() for the changes between Maps, with DiffOptions for numbers, attributes and keyed lists; see Changes.String().
+	2026.10.16: add mv.Merge() for deep merges with per-path MergeOptions strategies - override, keep, append and union (by key).
+	2026.10.16: add mv.MoveValue() and mv.CopyValue() to relocate values, with list inserts and an 'overwrite' option; see PathExistsError.
+	2026.10.16: add mv.RenameKeys() for keys in lists and wildcard/indexed paths, merging on collision and returning a count.
+	2026.10.16: add mv.RemoveValuesForPath() with indexes, wildcards and subkeys, returning a count; see RemoveValuesForPathCollapse().
+	2026.10.16: add mv.SetValueForPathCreate() to build paths as needed - "doc.books.book[1].author"; add SetPathError.
+	2026.10.16: UpdateValuesForPath() takes indexed paths, etc., sets "#text" for elements with attributes; add msv.UpdateValuesForPath().
+	2026.10.16: add negative indexes, slices and "..key" recursive descent to paths - "entry[-1]", "entry[2:5]", "doc..id".
+	2026.10.16: add path predicates to ValuesForPath(), etc. - "books.book[author='Gaddis'].title", "items.item[price>10]".
+	2026.10.16: add mv.XPath() and msv.XPath() for XPath 1.0 expressions; MapSeq positions follow the XML doc.
+	2026.10.16: add mv.Query() for JSONPath (RFC 9535) queries returning values and normalized paths.
+	2026.10.16: add typed accessors - mv.IntForPath(), FloatForPath(), BoolForPath(), TimeForPath(), etc.
+	2026.10.16: add DecoderOptions.CastSchema to cast values by path - e.g., "invoice.total" float64, "invoice.id" string.
+	2026.10.16: add NewMapJsonSeq and msv.Json()/JsonIndent() to keep JSON member order and XML document order.
+	2026.10.16: rebuild the JSON reader; add JsonReader for streaming top-level arrays, NDJSON and concatenated values; without one, arrays of objects are still read a member at a time.
+	2026.10.16: add HandleXmlReaderContext, HandleJsonReaderContext, etc., that stop when a context.Context is done.
+	2026.10.16: add HandleXmlReaderConcurrent and HandleJsonReaderConcurrent to decode and handle with a pool of workers.
+	2026.10.16: add NewXmlPathScanner to stream the elements at a path, e.g. "feed.entry", from large XML docs.
+	2026.10.16: preserve CDATA sections in MapSeq values; add EncoderOptions.CDATA to encode text as CDATA.
+	2026.10.16: add DecoderOptions.Namespaces to decode "prefix:local" or "{uri}local" keys that mv.Xml() re-encodes.
+	2026.10.16: add EncoderOptions for per-call encoder settings - opts.Xml(mv), opts.XmlSeq(msv), opts.AnyXml(v), etc.
+	2026.10.16: add DecoderOptions for per-call decoder settings - opts.NewMapXml(), opts.HandleXmlReader(), etc.
 	2022.11.28: v2.7 - add SetGlobalKeyMapPrefix to change default prefix, '#', for default keys
 	2022.11.20: v2.6 - add NewMapForattedXmlSeq for XML docs formatted with whitespace character
 	2021.02.02: v2.5 - add XmlCheckIsValid toggle to force checking that the encoded XML is valid
@@ -119,6 +145,7 @@ XML PARSING CONVENTIONS
    - Comments, directives, and process instructions are unmarshalled into the Map using the
      keys "#comment", "#directive", and "#procinst", respectively. (See documentation for more
      specifics.)
+   - CDATA sections have a "#cdata":true key next to "#text" and are re-encoded as CDATA.
    - Name space syntax is preserved: 
       - <ns:key>something</ns.key> parses to map["ns:key"]interface{}{"something"}
       - xmlns:ns="http://myns.com/ns" parses to map["xmlns:ns"]interface{}{"http://myns.com/ns"}
@@ -0,0 +1,175 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// encoderoptions.go - per-call settings for the mv.Xml, msv.Xml and AnyXml encoders.
+// The package-level toggles - SetAttrPrefix(), XmlGoEmptyElemSyntax(), etc. - only
+// set the defaults that NewEncoderOptions() copies.
+
+package mxj
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// EncoderOptions holds the XML encoding settings for a single call.  Since each
+// encoding call works on its own copy, an EncoderOptions value can be used by
+// concurrent goroutines - e.g., HTTP handlers serving different XML dialects.
+//
+// Use NewEncoderOptions() to get a value initialized with the current package
+// settings.
+//
+//	opts := mxj.NewEncoderOptions()
+//	opts.RootTag = "msg"
+//	opts.AttrPrefix = "@"
+//	x, err := opts.Xml(mv)
+type EncoderOptions struct {
+	// RootTag is the root tag - the 'rootTag' argument to mv.Xml(). If "", the
+	// single Map key or DefaultRootTag is used.
+	RootTag string
+	// ElementTag is the tag for list members in AnyXml(); if "", DefaultElementTag.
+	ElementTag string
+	// AttrPrefix identifies attribute keys - see SetAttrPrefix().
+	// (Not applicable for msv.Xml(), etc.)
+	AttrPrefix string
+	// TextKey is the key for the value of a simple element with attributes, "#text" by default.
+	// See SetGlobalKeyMapPrefix().
+	TextKey string
+	// GoEmptyElemSyntax encodes empty elements as <tag></tag> - see XmlGoEmptyElemSyntax().
+	GoEmptyElemSyntax bool
+	// EscapeChars escapes invalid characters in values - see XMLEscapeChars().
+	EscapeChars bool
+	// CheckIsValid checks that the encoded XML is valid - see XmlCheckIsValid().
+	CheckIsValid bool
+	// CDATA writes element text values as "<![CDATA[...]]>" sections rather than
+	// escaping them; attribute values are not affected.
+	CDATA bool
+
+	// the remaining MapSeq keys - "#seq", "#attr", etc. - as of NewEncoderOptions()
+	keys mapKeys
+}
+
+// NewEncoderOptions returns an EncoderOptions value initialized with the current
+// package settings.
+func NewEncoderOptions() *EncoderOptions {
+	return &EncoderOptions{
+		AttrPrefix:        attrPrefix,
+		TextKey:           textK,
+		GoEmptyElemSyntax: useGoXmlEmptyElemSyntax,
+		EscapeChars:       xmlEscapeChars,
+		CheckIsValid:      xmlCheckIsValid,
+		keys:              defaultMapKeys(),
+	}
+}
+
+// encoderOptions returns the package default EncoderOptions with 'rootTag' applied
+// as passed to mv.Xml(), etc.
+func encoderOptions(rootTag []string) *EncoderOptions {
+	o := NewEncoderOptions()
+	if len(rootTag) == 1 {
+		o.RootTag = rootTag[0]
+	}
+	return o
+}
+
+// call returns the copy of 'o' that a single encoding call works with.
+// A nil 'o' is the package default.
+func (o *EncoderOptions) call() *EncoderOptions {
+	if o == nil {
+		o = NewEncoderOptions()
+	}
+	oc := *o
+	if oc.keys == (mapKeys{}) {
+		oc.keys = defaultMapKeys()
+	}
+	if oc.TextKey != "" {
+		oc.keys.text = oc.TextKey
+	}
+	return &oc
+}
+
+// isAttr reports whether the Map key 'k' is an attribute key.
+func (o *EncoderOptions) isAttr(k string) bool {
+	return len(o.AttrPrefix) > 0 && len(o.AttrPrefix) < len(k) && k[:len(o.AttrPrefix)] == o.AttrPrefix
+}
+
+// escape applies EscapeChars handling to 's'.
+func (o *EncoderOptions) escape(s string) string {
+	if o.EscapeChars {
+		return escapeChars(s)
+	}
+	return s
+}
+
+// text applies CDATA or EscapeChars handling to the element text 's'.
+func (o *EncoderOptions) text(s string) string {
+	if o.CDATA && s != "" {
+		return cdata(s)
+	}
+	return o.escape(s)
+}
+
+// checkIsValidXml decodes 'b' to see if it is valid XML - per issue #88.
+func checkIsValidXml(b []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// ------------------- writers using EncoderOptions -------------------------
+
+// XmlWriter writes the Map as XML on the Writer using the settings in 'o'.
+// See mv.Xml() for encoding rules.
+func (o *EncoderOptions) XmlWriter(mv Map, xmlWriter io.Writer) error {
+	x, err := o.Xml(mv)
+	if err != nil {
+		return err
+	}
+
+	_, err = xmlWriter.Write(x)
+	return err
+}
+
+// XmlIndentWriter writes the Map as pretty XML on the Writer using the settings in 'o'.
+// See mv.Xml() for encoding rules.
+func (o *EncoderOptions) XmlIndentWriter(mv Map, xmlWriter io.Writer, prefix, indent string) error {
+	x, err := o.XmlIndent(mv, prefix, indent)
+	if err != nil {
+		return err
+	}
+
+	_, err = xmlWriter.Write(x)
+	return err
+}
+
+// XmlSeqWriter writes the MapSeq value as XML on the Writer using the settings in 'o'.
+// See msv.Xml() for encoding rules.
+func (o *EncoderOptions) XmlSeqWriter(msv MapSeq, xmlWriter io.Writer) error {
+	x, err := o.XmlSeq(msv)
+	if err != nil {
+		return err
+	}
+
+	_, err = xmlWriter.Write(x)
+	return err
+}
+
+// XmlSeqIndentWriter writes the MapSeq value as pretty XML on the Writer using the
+// settings in 'o'.  See msv.Xml() for encoding rules.
+func (o *EncoderOptions) XmlSeqIndentWriter(msv MapSeq, xmlWriter io.Writer, prefix, indent string) error {
+	x, err := o.XmlSeqIndent(msv, prefix, indent)
+	if err != nil {
+		return err
+	}
+
+	_, err = xmlWriter.Write(x)
+	return err
+}
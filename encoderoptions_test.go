@@ -0,0 +1,118 @@
+package mxj
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestEncoderOptions(t *testing.T) {
+	fmt.Println("------------ encoderoptions_test.go")
+	m := Map{
+		"item": map[string]interface{}{
+			"@id":    "1",
+			"#value": "a<b",
+		},
+		"empty": nil,
+	}
+	opts := NewEncoderOptions()
+	opts.RootTag = "msg"
+	opts.AttrPrefix = "@"
+	opts.TextKey = "#value"
+	opts.GoEmptyElemSyntax = true
+	opts.EscapeChars = true
+
+	x, err := opts.Xml(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(x) != `<msg><empty></empty><item id="1">a&lt;b</item></msg>` {
+		t.Fatal("got:", string(x))
+	}
+
+	var buf bytes.Buffer
+	if err := opts.XmlIndentWriter(m, &buf, "", " "); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "<msg>\n <empty></empty>\n <item id=\"1\">a&lt;b</item>\n</msg>" {
+		t.Fatal("got:", buf.String())
+	}
+
+	// package defaults are unchanged
+	if useGoXmlEmptyElemSyntax || xmlEscapeChars {
+		t.Fatal("package settings changed")
+	}
+	x, err = m.Xml("msg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(x, []byte(`id="1"`)) {
+		t.Fatal("got:", string(x))
+	}
+}
+
+func TestEncoderOptionsCheckIsValid(t *testing.T) {
+	opts := NewEncoderOptions()
+	opts.CheckIsValid = true
+	if _, err := opts.Xml(Map{"a": "<b>"}); err == nil {
+		t.Fatal("expected error for invalid XML")
+	}
+	opts.EscapeChars = true
+	if _, err := opts.Xml(Map{"a": "<b>"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncoderOptionsSeqAndAny(t *testing.T) {
+	ms, err := NewMapXmlSeq([]byte(`<doc><b x="1">two</b><a/></doc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := NewEncoderOptions()
+	opts.RootTag = "root"
+	opts.GoEmptyElemSyntax = true
+	x, err := opts.XmlSeq(MapSeq(ms["doc"].(map[string]interface{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(x) != `<root><b x="1">two</b><a></a></root>` {
+		t.Fatal("got:", string(x))
+	}
+
+	opts.ElementTag = "v"
+	x, err = opts.AnyXml([]interface{}{"one", 2.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(x) != `<root><v>one</v><v>2</v></root>` {
+		t.Fatal("got:", string(x))
+	}
+}
+
+func TestEncoderOptionsConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			opts := NewEncoderOptions()
+			opts.AttrPrefix = fmt.Sprintf("a%d_", i)
+			m := Map{"e": map[string]interface{}{opts.AttrPrefix + "n": i}}
+			x, err := opts.Xml(m)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if want := fmt.Sprintf(`<e n="%d"/>`, i); string(x) != want {
+				errs <- fmt.Errorf("want %s got %s", want, x)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// handlecontext.go - HandleXmlReader, HandleJsonReader, etc., that stop when a
+// context.Context is canceled.
+
+package mxj
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// HandleXmlReaderContext is HandleXmlReader that stops when 'ctx' is done.
+//
+//	If 'ctx' is canceled or its deadline passes, HandleXmlReaderContext returns ctx.Err()
+//	without waiting for a blocked Read on 'xmlReader' - e.g., on a socket or os.Stdin.
+//	Since an io.Reader can't be interrupted, the goroutine doing the Read exits when the
+//	Read returns; close the reader, if possible, to release it.  Docs are still read one
+//	at a time, so no more of the stream is consumed than with HandleXmlReader.
+func HandleXmlReaderContext(ctx context.Context, xmlReader io.Reader, mapHandler func(Map) bool, errHandler func(error) bool) error {
+	return NewDecoderOptions().HandleXmlReaderContext(ctx, xmlReader, mapHandler, errHandler)
+}
+
+// HandleXmlReaderRawContext is HandleXmlReaderRaw that stops when 'ctx' is done.
+// See HandleXmlReaderContext().
+func HandleXmlReaderRawContext(ctx context.Context, xmlReader io.Reader, mapHandler func(Map, []byte) bool, errHandler func(error, []byte) bool) error {
+	return NewDecoderOptions().HandleXmlReaderRawContext(ctx, xmlReader, mapHandler, errHandler)
+}
+
+// HandleXmlReaderContext bulk processes XML using the settings in 'o' until 'ctx'
+// is done.  See the HandleXmlReaderContext function.
+func (o *DecoderOptions) HandleXmlReaderContext(ctx context.Context, xmlReader io.Reader, mapHandler func(Map) bool, errHandler func(error) bool) error {
+	read := func() (Map, []byte, error) {
+		m, err := o.NewMapXmlReader(xmlReader)
+		return m, nil, err
+	}
+	return handleReaderContext(ctx, "xmlReader", read,
+		func(m Map, _ []byte) bool { return mapHandler(m) },
+		func(err error, _ []byte) bool { return errHandler(err) })
+}
+
+// HandleXmlReaderRawContext bulk processes XML using the settings in 'o' until
+// 'ctx' is done.  See the HandleXmlReaderContext function.
+func (o *DecoderOptions) HandleXmlReaderRawContext(ctx context.Context, xmlReader io.Reader, mapHandler func(Map, []byte) bool, errHandler func(error, []byte) bool) error {
+	read := func() (Map, []byte, error) {
+		return o.NewMapXmlReaderRaw(xmlReader)
+	}
+	return handleReaderContext(ctx, "xmlReader", read, mapHandler, errHandler)
+}
+
+// HandleJsonReaderContext is HandleJsonReader that stops when 'ctx' is done.
+// See HandleXmlReaderContext() - and HandleJsonReader() on the bytes read past the last value.
+func HandleJsonReaderContext(ctx context.Context, jsonReader io.Reader, mapHandler func(Map) bool, errHandler func(error) bool) error {
+	jsonReader = NewJsonReader(jsonReader)
+	read := func() (Map, []byte, error) {
+		m, err := NewMapJsonReader(jsonReader)
+		return m, nil, err
+	}
+	return handleReaderContext(ctx, "jsonReader", read,
+		func(m Map, _ []byte) bool { return mapHandler(m) },
+		func(err error, _ []byte) bool { return errHandler(err) })
+}
+
+// HandleJsonReaderRawContext is HandleJsonReaderRaw that stops when 'ctx' is done.
+// See HandleXmlReaderContext() - and HandleJsonReader() on the bytes read past the last value.
+func HandleJsonReaderRawContext(ctx context.Context, jsonReader io.Reader, mapHandler func(Map, []byte) bool, errHandler func(error, []byte) bool) error {
+	jsonReader = NewJsonReader(jsonReader)
+	read := func() (Map, []byte, error) {
+		return NewMapJsonReaderRaw(jsonReader)
+	}
+	return handleReaderContext(ctx, "jsonReader", read, mapHandler, errHandler)
+}
+
+// readResult is the value returned by a Handle*Context 'read' function.
+type readResult struct {
+	m   Map
+	raw []byte
+	err error
+}
+
+// handleReaderContext is the HandleXmlReader loop with the reads done in a goroutine,
+// so that waiting on the reader can be abandoned when 'ctx' is done.
+func handleReaderContext(ctx context.Context, label string,
+	read func() (Map, []byte, error),
+	mapHandler func(Map, []byte) bool, errHandler func(error, []byte) bool) error {
+	req := make(chan struct{})
+	res := make(chan readResult, 1) // so the reader can exit if we've returned
+	defer close(req)
+	go func() {
+		for range req {
+			m, raw, err := read()
+			res <- readResult{m, raw, err}
+		}
+	}()
+
+	var n int
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// request the next doc - the reader is idle, so this doesn't block
+		req <- struct{}{}
+		var r readResult
+		select {
+		case r = <-res:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		n++
+
+		// handle error condition with errhandler
+		if r.err != nil && r.err != io.EOF {
+			err := fmt.Errorf("[%s: %d] %w", label, n, r.err)
+			if ok := errHandler(err, r.raw); !ok {
+				// caused reader termination
+				return err
+			}
+			if !isCastError(err) {
+				continue
+			}
+		}
+
+		// pass to maphandler - if nothing was read, the next read is requested
+		// right away, since the reading goroutine, not this loop, waits on the reader
+		if len(r.m) != 0 {
+			if ok := mapHandler(r.m, r.raw); !ok {
+				break
+			}
+		}
+
+		if r.err == io.EOF {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package mxj
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleXmlReaderContext(t *testing.T) {
+	fmt.Println("\n================== TestHandleXmlReaderContext")
+	var cnt int
+	err := HandleXmlReaderContext(context.Background(), strings.NewReader(`<a>1</a><a>2</a><a>3</a>`),
+		func(m Map) bool {
+			cnt++
+			return true
+		},
+		func(err error) bool {
+			t.Fatal(err)
+			return false
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 3 {
+		t.Fatal("cnt:", cnt)
+	}
+}
+
+func TestHandleXmlReaderContextCancel(t *testing.T) {
+	fmt.Println("\n================== TestHandleXmlReaderContextCancel")
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	got := make(chan Map, 1)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- HandleXmlReaderRawContext(ctx, pr,
+			func(m Map, raw []byte) bool {
+				got <- m
+				return true
+			},
+			func(err error, raw []byte) bool {
+				return false
+			})
+	}()
+
+	go pw.Write([]byte(`<doc>1</doc>`))
+	select {
+	case <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no doc")
+	}
+	// the reader is now blocked on the pipe
+	cancel()
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Fatal("err:", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("not canceled")
+	}
+}
+
+func TestHandleJsonReaderContextDeadline(t *testing.T) {
+	fmt.Println("\n================== TestHandleJsonReaderContextDeadline")
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	go pw.Write([]byte(`{"a":1}`))
+	var cnt int
+	err := HandleJsonReaderContext(ctx, pr,
+		func(m Map) bool {
+			cnt++
+			return true
+		},
+		func(err error) bool {
+			return false
+		})
+	if err != context.DeadlineExceeded {
+		t.Fatal("err:", err)
+	}
+	if cnt != 1 {
+		t.Fatal("cnt:", cnt)
+	}
+}
+
+func TestHandleJsonReaderRawContext(t *testing.T) {
+	fmt.Println("\n================== TestHandleJsonReaderRawContext")
+	var raws []string
+	err := HandleJsonReaderRawContext(context.Background(), strings.NewReader(`{"a":1} {"b":2}`),
+		func(m Map, raw []byte) bool {
+			raws = append(raws, string(raw))
+			return len(raws) < 1
+		},
+		func(err error, raw []byte) bool {
+			t.Fatal(err)
+			return false
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raws) != 1 || raws[0] != `{"a":1}` {
+		t.Fatal("raws:", raws)
+	}
+}
+
+func TestHandleJsonReaderContextEmpty(t *testing.T) {
+	fmt.Println("\n================== TestHandleJsonReaderContextEmpty")
+	// empty docs are skipped without waiting on a poll interval
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	var cnt int
+	err := HandleJsonReaderContext(ctx, strings.NewReader(strings.Repeat("{} ", 2000)+`{"a":1}`),
+		func(m Map) bool {
+			cnt++
+			return true
+		},
+		func(err error) bool {
+			t.Fatal(err)
+			return false
+		})
+	if err != nil || cnt != 1 {
+		t.Fatal(cnt, err)
+	}
+}
@@ -124,6 +124,7 @@ var JsonUseNumber bool
 //		}
 // NOTE: as a special case, passing a list, e.g., [{"some-null-value":"", "a-non-null-value":"bar"}],
 // will be interpreted as having the root key 'object' prepended - {"object":[ ... ]} - to unmarshal to a Map.
+// Scalar values - e.g., NDJSON lines such as 42 or "text" - are handled the same way: {"object":42}.
 // See mxj/j2x/j2x_test.go.
 func NewMapJson(jsonVal []byte) (Map, error) {
 	// empty or nil begets empty
@@ -131,8 +132,8 @@ func NewMapJson(jsonVal []byte) (Map, error) {
 		m := make(map[string]interface{}, 0)
 		return m, nil
 	}
-	// handle a goofy case ...
-	if jsonVal[0] == '[' {
+	// handle a goofy case ... and scalar values
+	if b := bytes.TrimLeft(jsonVal, " \t\r\n"); len(b) > 0 && b[0] != '{' {
 		jsonVal = []byte(`{"object":` + string(jsonVal) + `}`)
 	}
 	m := make(map[string]interface{})
@@ -147,92 +148,37 @@ func NewMapJson(jsonVal []byte) (Map, error) {
 }
 
 // Retrieve a Map value from an io.Reader.
-//  NOTE: The raw JSON off the reader is buffered to []byte using a ByteReader. If the io.Reader is an
-//        os.File, there may be significant performance impact. If the io.Reader is wrapping a []byte
-//        value in-memory, however, such as http.Request.Body you CAN use it to efficiently unmarshal
-//        a JSON object.
+//  The reader can hold a series of JSON values - objects, NDJSON lines, etc.; see NewMapJson()
+//  for how arrays and scalar values are represented.
+//  The members of a top-level array of objects, "[{...}, {...}]", are returned one at a time;
+//  other arrays are returned as a single value unless the io.Reader is a JsonReader.
+//  NOTE: To not read beyond the value, the io.Reader is read a byte at a time unless it is an
+//        io.ByteReader - e.g., a bufio.Reader or bytes.Reader. If it isn't an io.ByteScanner, as
+//        well, the byte following a number or literal value - e.g., "42{...}" - is lost. For a
+//        stream of values use a JsonReader, which reads in blocks and returns the members of any
+//        top-level array one at a time.
 func NewMapJsonReader(jsonReader io.Reader) (Map, error) {
 	jb, err := getJson(jsonReader)
-	if err != nil || len(*jb) == 0 {
+	if err != nil || len(jb) == 0 {
 		return nil, err
 	}
 
 	// Unmarshal the 'presumed' JSON string
-	return NewMapJson(*jb)
+	return NewMapJson(jb)
 }
 
 // Retrieve a Map value and raw JSON - []byte - from an io.Reader.
-//  NOTE: The raw JSON off the reader is buffered to []byte using a ByteReader. If the io.Reader is an
-//        os.File, there may be significant performance impact. If the io.Reader is wrapping a []byte
-//        value in-memory, however, such as http.Request.Body you CAN use it to efficiently unmarshal
-//        a JSON object and retrieve the raw JSON in a single call.
+//  The raw JSON is the value's bytes exactly as read, without the surrounding whitespace.
+//  See NewMapJsonReader() for the handling of the io.Reader.
 func NewMapJsonReaderRaw(jsonReader io.Reader) (Map, []byte, error) {
 	jb, err := getJson(jsonReader)
-	if err != nil || len(*jb) == 0 {
-		return nil, *jb, err
+	if err != nil || len(jb) == 0 {
+		return nil, jb, err
 	}
 
 	// Unmarshal the 'presumed' JSON string
-	m, merr := NewMapJson(*jb)
-	return m, *jb, merr
-}
-
-// Pull the next JSON string off the stream: just read from first '{' to its closing '}'.
-// Returning a pointer to the slice saves 16 bytes - maybe unnecessary, but internal to package.
-func getJson(rdr io.Reader) (*[]byte, error) {
-	bval := make([]byte, 1)
-	jb := make([]byte, 0)
-	var inQuote, inJson bool
-	var parenCnt int
-	var previous byte
-
-	// scan the input for a matched set of {...}
-	// json.Unmarshal will handle syntax checking.
-	for {
-		_, err := rdr.Read(bval)
-		if err != nil {
-			if err == io.EOF && inJson && parenCnt > 0 {
-				return &jb, fmt.Errorf("no closing } for JSON string: %s", string(jb))
-			}
-			return &jb, err
-		}
-		switch bval[0] {
-		case '{':
-			if !inQuote {
-				parenCnt++
-				inJson = true
-			}
-		case '}':
-			if !inQuote {
-				parenCnt--
-			}
-			if parenCnt < 0 {
-				return nil, fmt.Errorf("closing } without opening {: %s", string(jb))
-			}
-		case '"':
-			if inQuote {
-				if previous == '\\' {
-					break
-				}
-				inQuote = false
-			} else {
-				inQuote = true
-			}
-		case '\n', '\r', '\t', ' ':
-			if !inQuote {
-				continue
-			}
-		}
-		if inJson {
-			jb = append(jb, bval[0])
-			if parenCnt == 0 {
-				break
-			}
-		}
-		previous = bval[0]
-	}
-
-	return &jb, nil
+	m, merr := NewMapJson(jb)
+	return m, jb, merr
 }
 
 // ------------------------------- JSON Reader handler via Map values  -----------------------
@@ -251,7 +197,12 @@ var jhandlerPollInterval = time.Duration(1e6)
 //	Note: mapHandler() and errHandler() calls are blocking, so reading and processing of messages is serialized.
 //	      This means that you can stop reading the file on error or after processing a particular message.
 //	      To have reading and handling run concurrently, pass argument to a go routine in handler and return 'true'.
+//	      Or use HandleJsonReaderConcurrent() to decode and handle with a pool of workers.
+//	      The io.Reader is read in blocks, as by a JsonReader, so if a handler stops the processing,
+//	      bytes past the last value read may have been taken from it; pass a JsonReader to get them
+//	      back - its Read method returns the buffered bytes first.
 func HandleJsonReader(jsonReader io.Reader, mapHandler func(Map) bool, errHandler func(error) bool) error {
+	jsonReader = NewJsonReader(jsonReader) // stream top-level arrays, buffer reads
 	var n int
 	for {
 		m, merr := NewMapJsonReader(jsonReader)
@@ -290,7 +241,11 @@ func HandleJsonReader(jsonReader io.Reader, mapHandler func(Map) bool, errHandle
 //	Note: mapHandler() and errHandler() calls are blocking, so reading and processing of messages is serialized.
 //	      This means that you can stop reading the file on error or after processing a particular message.
 //	      To have reading and handling run concurrently, pass argument(s) to a go routine in handler and return 'true'.
+//	      The io.Reader is read in blocks, as by a JsonReader, so if a handler stops the processing,
+//	      bytes past the last value read may have been taken from it; pass a JsonReader to get them
+//	      back - its Read method returns the buffered bytes first.
 func HandleJsonReaderRaw(jsonReader io.Reader, mapHandler func(Map, []byte) bool, errHandler func(error, []byte) bool) error {
+	jsonReader = NewJsonReader(jsonReader) // stream top-level arrays, buffer reads
 	var n int
 	for {
 		m, raw, merr := NewMapJsonReaderRaw(jsonReader)
@@ -0,0 +1,505 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// jsonpatch.go - JSON Patch (RFC 6902) operations on Map values, with RFC 6901
+// JSON Pointers for the paths.
+
+package mxj
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PatchOp is a JSON Patch operation - "add", "remove", "replace", "move", "copy" or
+// "test".  'Path' and 'From' are JSON Pointers - e.g., "/doc/items/item/0/price".
+type PatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+
+	noValue bool // decoded without a "value" member
+}
+
+// MarshalJSON encodes the operation with a "value" member for "add", "replace" and
+// "test" operations, even if the value is nil.
+func (op PatchOp) MarshalJSON() ([]byte, error) {
+	type patchOp PatchOp // no MarshalJSON method
+	switch op.Op {
+	case "add", "replace", "test":
+		return json.Marshal(struct {
+			Op    string      `json:"op"`
+			Path  string      `json:"path"`
+			Value interface{} `json:"value"`
+		}{op.Op, op.Path, op.Value})
+	}
+	return json.Marshal(patchOp(op))
+}
+
+// UnmarshalJSON decodes the operation; the value is decoded as for NewMapJson() -
+// see JsonUseNumber.  An "add", "replace" or "test" operation without a "value"
+// member can't be applied - the *PatchError wraps PatchNoValueError.
+func (op *PatchOp) UnmarshalJSON(b []byte) error {
+	var o struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		From  string          `json:"from"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(b, &o); err != nil {
+		return err
+	}
+	*op = PatchOp{Op: o.Op, Path: o.Path, From: o.From, noValue: o.Value == nil}
+	if o.Value == nil {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(o.Value))
+	if JsonUseNumber {
+		dec.UseNumber()
+	}
+	return dec.Decode(&op.Value)
+}
+
+// Patch is a JSON Patch document - a list of operations that are applied in order.
+type Patch []PatchOp
+
+// ParsePatch decodes a JSON Patch document.  The values are decoded as for
+// NewMapJson() - see JsonUseNumber.
+func ParsePatch(b []byte) (Patch, error) {
+	var p Patch
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PatchTestError is wrapped by the *PatchError for a "test" operation that fails.
+var PatchTestError = errors.New("test failed")
+
+// PatchNoValueError is wrapped by the *PatchError for an "add", "replace" or "test"
+// operation that was decoded without a "value" member.
+var PatchNoValueError = errors.New("no value")
+
+// PatchError is returned when an operation of a Patch can't be applied.
+type PatchError struct {
+	Index int     // the index of the operation in the Patch
+	Op    PatchOp // the operation
+	Err   error   // PathNotExistError, PathIndexError, PatchTestError, etc.
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("patch op %d: %s %s: %s", e.Index, e.Op.Op, e.Op.Path, e.Err)
+}
+
+// Unwrap returns PathNotExistError, etc.
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// PatchOptions are the settings for ApplyPatch.
+type PatchOptions struct {
+	// CollapseLists has a list that an operation leaves with one member replaced
+	// by the member - as for RemoveValuesForPathCollapse().
+	CollapseLists bool
+	// ListOfOne has an index token on a map value that doesn't have the token as
+	// a key address the map as a list of one - as the XML decoder has a tag that
+	// isn't repeated.  Otherwise, the token is a map key, as RFC 6901 has it.
+	ListOfOne bool
+}
+
+// ApplyPatch applies the operations of the Patch in order.  The Patch is applied
+// all-or-nothing: if an operation fails the error is a *PatchError and the Map
+// isn't changed.
+//
+//	The JSON Pointer tokens are Map keys and list indexes - "-" for the end of a list.
+//	A token for a map value is a key.  An index on a value that isn't a list or a map
+//	is for a list of one - so "/doc/note/-" makes a single "note" value a list of two.
+//	As the XML decoder has a tag that isn't repeated as a single value rather than a
+//	list of one, with ListOfOne set in 'opts' that goes for map values, too:
+//	   {"op":"replace", "path":"/doc/items/item/0/price", "value":"15"}
+//	works whether there are several "item" elements or one, and
+//	   {"op":"add", "path":"/doc/items/item/-", "value":{"name":"nut"}}
+//	makes a single "item" value a list of two.  (A map that has the key "0", etc.,
+//	is a map, not a list.)  A list that an operation leaves with one member is
+//	replaced by the member if 'opts' has CollapseLists set.
+//	NOTE: the changed values are new copies - values retrieved from the Map before the
+//	      Patch was applied aren't in it anymore.
+func (mv Map) ApplyPatch(p Patch, opts ...*PatchOptions) error {
+	o := new(PatchOptions)
+	if len(opts) == 1 && opts[0] != nil {
+		o = opts[0]
+	}
+	doc := deepCopy(map[string]interface{}(mv))
+	for i, op := range p {
+		var err error
+		if doc, err = o.applyPatchOp(doc, op); err != nil {
+			return &PatchError{i, op, err}
+		}
+	}
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return &PatchError{len(p) - 1, p[len(p)-1], PathNotMapError}
+	}
+	for k := range mv {
+		delete(mv, k)
+	}
+	for k, v := range m {
+		mv[k] = v
+	}
+	return nil
+}
+
+// applyPatchOp returns the document with the operation applied.
+func (o *PatchOptions) applyPatchOp(doc interface{}, op PatchOp) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.noValue {
+			return nil, PatchNoValueError
+		}
+	}
+	switch op.Op {
+	case "add":
+		return o.pointerAdd(doc, path, deepCopy(op.Value))
+	case "remove":
+		if len(path) == 0 {
+			return nil, errors.New("can't remove the document")
+		}
+		return o.pointerRemoveValue(doc, path)
+	case "replace":
+		return o.pointerUpdate(doc, path, func(interface{}) (interface{}, error) {
+			return deepCopy(op.Value), nil
+		})
+	case "test":
+		v, err := o.pointerValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !patchEqual(v, op.Value) {
+			return nil, PatchTestError
+		}
+		return doc, nil
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := o.pointerValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return o.pointerAdd(doc, path, deepCopy(v))
+		}
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, errors.New("can't move a value into itself")
+		}
+		if reflect.DeepEqual(path, from) {
+			return doc, nil
+		}
+		if doc, err = o.pointerRemoveValue(doc, from); err != nil {
+			return nil, err
+		}
+		return o.pointerAdd(doc, path, v)
+	}
+	return nil, fmt.Errorf("unknown op: %q", op.Op)
+}
+
+// parsePointer returns the RFC 6901 JSON Pointer tokens, unescaped.
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("JSON Pointer doesn't start with '/': %q", s)
+	}
+	toks := strings.Split(s[1:], "/")
+	for i, t := range toks {
+		toks[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return toks, nil
+}
+
+// pointerEscape returns the key as a JSON Pointer token.
+func pointerEscape(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+// pointerIndex returns the list index for the token - len(list) for "-".
+func pointerIndex(tok string, n int) (int, error) {
+	if tok == "-" {
+		return n, nil
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || (len(tok) > 1 && tok[0] == '0') {
+		return 0, fmt.Errorf("bad list index: %q", tok)
+	}
+	return i, nil
+}
+
+// pointerList reports whether the token is for 'v' as a list - 'v' is a list, or
+// the token is an index and 'v' isn't a map - or, with ListOfOne set, isn't a map
+// with the token as a key.
+func (o *PatchOptions) pointerList(v interface{}, tok string) ([]interface{}, bool) {
+	switch vv := v.(type) {
+	case []interface{}:
+		return vv, true
+	case map[string]interface{}:
+		if _, ok := vv[tok]; ok || !o.ListOfOne {
+			return nil, false
+		}
+	}
+	if _, err := pointerIndex(tok, 1); err != nil {
+		return nil, false
+	}
+	return []interface{}{v}, true
+}
+
+// pointerValue returns the value for the JSON Pointer.
+func (o *PatchOptions) pointerValue(v interface{}, path []string) (interface{}, error) {
+	for _, tok := range path {
+		if a, ok := o.pointerList(v, tok); ok {
+			i, err := pointerIndex(tok, len(a))
+			if err != nil {
+				return nil, err
+			}
+			if i >= len(a) {
+				return nil, PathIndexError
+			}
+			v = a[i]
+			continue
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, PathNotMapError
+		}
+		if v, ok = m[tok]; !ok {
+			return nil, PathNotExistError
+		}
+	}
+	return v, nil
+}
+
+// pointerUpdate returns 'v' with the value for the JSON Pointer replaced by
+// what 'f' returns for it - or, if that's removed{}, removed.
+func (o *PatchOptions) pointerUpdate(v interface{}, path []string, f func(interface{}) (interface{}, error)) (interface{}, error) {
+	if len(path) == 0 {
+		return f(v)
+	}
+	tok := path[0]
+	if a, ok := o.pointerList(v, tok); ok {
+		i, err := pointerIndex(tok, len(a))
+		if err != nil {
+			return nil, err
+		}
+		if i >= len(a) {
+			return nil, PathIndexError
+		}
+		nv, err := o.pointerUpdate(a[i], path[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := nv.(removed); ok {
+			if len(a) == 1 {
+				if _, isList := v.([]interface{}); !isList {
+					return removed{}, nil // the value of a list of one
+				}
+			}
+			return append(a[:i:i], a[i+1:]...), nil
+		}
+		if _, isList := v.([]interface{}); !isList {
+			return nv, nil
+		}
+		a[i] = nv
+		return a, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, PathNotMapError
+	}
+	cv, ok := m[tok]
+	if !ok {
+		return nil, PathNotExistError
+	}
+	nv, err := o.pointerUpdate(cv, path[1:], f)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := nv.(removed); ok {
+		delete(m, tok)
+	} else {
+		m[tok] = nv
+	}
+	return m, nil
+}
+
+func pointerRemove(interface{}) (interface{}, error) {
+	return removed{}, nil
+}
+
+// pointerRemoveValue returns 'v' with the value for the JSON Pointer removed -
+// and, if CollapseLists is set and it was a list member, the list replaced by
+// the member that's left, if there's one.
+func (o *PatchOptions) pointerRemoveValue(v interface{}, path []string) (interface{}, error) {
+	v, err := o.pointerUpdate(v, path, pointerRemove)
+	if err != nil || !o.CollapseLists {
+		return v, err
+	}
+	parent := path[:len(path)-1]
+	if a, _ := o.pointerValue(v, parent); a != nil {
+		if a, ok := a.([]interface{}); ok && len(a) == 1 {
+			return o.pointerUpdate(v, parent, func(interface{}) (interface{}, error) {
+				return a[0], nil
+			})
+		}
+	}
+	return v, nil
+}
+
+// pointerAdd returns 'v' with the value added for the JSON Pointer - a map value
+// is set, a list value is inserted.
+func (o *PatchOptions) pointerAdd(v interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, last := path[:len(path)-1], path[len(path)-1]
+	return o.pointerUpdate(v, parent, func(pv interface{}) (interface{}, error) {
+		if a, ok := o.pointerList(pv, last); ok {
+			i, err := pointerIndex(last, len(a))
+			if err != nil {
+				return nil, err
+			}
+			if i > len(a) {
+				return nil, PathIndexError
+			}
+			na := make([]interface{}, 0, len(a)+1)
+			na = append(na, a[:i]...)
+			na = append(na, value)
+			return append(na, a[i:]...), nil
+		}
+		m, ok := pv.(map[string]interface{})
+		if !ok {
+			return nil, PathNotMapError
+		}
+		m[last] = value
+		return m, nil
+	})
+}
+
+// patchEqual reports whether the values are equal as JSON values - numbers are
+// compared by value.
+func patchEqual(a, b interface{}) bool {
+	if ar, ok := diffNumber(a); ok {
+		br, ok := diffNumber(b)
+		return ok && ar.Cmp(br) == 0
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			if bm, ok := b.(Map); ok {
+				bv = bm
+			} else {
+				return false
+			}
+		}
+		if len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			w, ok := bv[k]
+			if !ok || !patchEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !patchEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// CreatePatch returns the Patch that makes the Map into 'other'.
+//
+//	The maps are compared key by key and lists member by member; members are
+//	removed from, or added to, the end of a list.  A value that isn't a list or a
+//	map and a list are compared as a list of one and the list - see ApplyPatch();
+//	other values that differ in type are replaced.  The Patch is applied without
+//	ListOfOne set.
+func (mv Map) CreatePatch(other Map) Patch {
+	var p Patch
+	createPatch(&p, "", map[string]interface{}(mv), map[string]interface{}(other))
+	return p
+}
+
+func createPatch(p *Patch, path string, a, b interface{}) {
+	am, aMap := a.(map[string]interface{})
+	bm, bMap := b.(map[string]interface{})
+	if aMap && bMap {
+		keys := make([]string, 0, len(am)+len(bm))
+		for k := range am {
+			if _, ok := bm[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			*p = append(*p, PatchOp{Op: "remove", Path: path + "/" + pointerEscape(k)})
+		}
+		for _, k := range sortedKeys(bm) {
+			kp := path + "/" + pointerEscape(k)
+			if av, ok := am[k]; ok {
+				createPatch(p, kp, av, bm[k])
+			} else {
+				*p = append(*p, PatchOp{Op: "add", Path: kp, Value: deepCopy(bm[k])})
+			}
+		}
+		return
+	}
+
+	bl, bList := b.([]interface{})
+	if bList && !aMap {
+		al, aList := a.([]interface{})
+		if !aList {
+			al = []interface{}{a}
+		}
+		n := len(al)
+		if len(bl) < n {
+			n = len(bl)
+		}
+		for i := 0; i < n; i++ {
+			createPatch(p, path+"/"+strconv.Itoa(i), al[i], bl[i])
+		}
+		for i := len(al) - 1; i >= n; i-- {
+			*p = append(*p, PatchOp{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+		}
+		for i := n; i < len(bl); i++ {
+			*p = append(*p, PatchOp{Op: "add", Path: path + "/-", Value: deepCopy(bl[i])})
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*p = append(*p, PatchOp{Op: "replace", Path: path, Value: deepCopy(b)})
+	}
+}
@@ -0,0 +1,212 @@
+package mxj
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	fmt.Println("\n================== TestApplyPatch")
+	m, err := NewMapXml([]byte(`<doc>
+		<items>
+			<item sku="a1"><name>bolt</name><price>12</price></item>
+		</items>
+		<note>old</note>
+	</doc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ap := attrPrefix
+
+	p, err := ParsePatch([]byte(`[
+		{"op":"test", "path":"/doc/items/item/0/` + ap + `sku", "value":"a1"},
+		{"op":"replace", "path":"/doc/items/item/0/price", "value":"15"},
+		{"op":"add", "path":"/doc/items/item/-", "value":{"` + ap + `sku":"a2", "name":"nut"}},
+		{"op":"copy", "from":"/doc/items/item/1/name", "path":"/doc/items/item/0/alias"},
+		{"op":"move", "from":"/doc/note", "path":"/doc/meta"},
+		{"op":"add", "path":"/doc/a~1b~0c", "value":1},
+		{"op":"remove", "path":"/doc/items/item/0/name"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a single "item" is a list of one
+	if err = m.ApplyPatch(p, &PatchOptions{ListOfOne: true}); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(m.StringIndentNoTypeInfo())
+	want := map[string]interface{}{
+		"doc": map[string]interface{}{
+			"items": map[string]interface{}{
+				"item": []interface{}{
+					map[string]interface{}{ap + "sku": "a1", "price": "15", "alias": "nut"},
+					map[string]interface{}{ap + "sku": "a2", "name": "nut"},
+				},
+			},
+			"meta":  "old",
+			"a/b~c": float64(1),
+		},
+	}
+	if !reflect.DeepEqual(map[string]interface{}(m), want) {
+		t.Errorf("got %v", m)
+	}
+
+	// all-or-nothing
+	before, _ := m.Copy()
+	for _, tt := range []struct {
+		patch string
+		err   error
+	}{
+		{`[{"op":"remove", "path":"/doc/meta"}, {"op":"test", "path":"/doc/a~1b~0c", "value":2}]`, PatchTestError},
+		{`[{"op":"remove", "path":"/doc/meta"}, {"op":"replace", "path":"/doc/none", "value":2}]`, PathNotExistError},
+		{`[{"op":"remove", "path":"/doc/meta"}, {"op":"add", "path":"/doc/items/item/3", "value":2}]`, PathIndexError},
+		{`[{"op":"remove", "path":"/doc/meta"}, {"op":"add", "path":"/doc/meta/x", "value":2}]`, PathNotExistError},
+		{`[{"op":"move", "from":"/doc/items", "path":"/doc/items/x"}]`, nil},
+		{`[{"op":"frob", "path":"/doc"}]`, nil},
+		{`[{"op":"add", "path":"/doc/x"}]`, PatchNoValueError},
+		{`[{"op":"replace", "path":"/doc/meta"}]`, PatchNoValueError},
+		{`[{"op":"test", "path":"/doc/none"}]`, PatchNoValueError},
+	} {
+		p, err := ParsePatch([]byte(tt.patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.ApplyPatch(p)
+		var pe *PatchError
+		if !errors.As(err, &pe) || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: %v", tt.patch, err)
+		}
+	}
+	if !reflect.DeepEqual(m, before) {
+		t.Errorf("failed patches changed the Map: %v", m)
+	}
+
+	// a list of one is a list for an index
+	p = Patch{{Op: "remove", Path: "/doc/items/item/1"}, {Op: "remove", Path: "/doc/items/item/0"}}
+	if err = m.ApplyPatch(p); err != nil {
+		t.Fatal(err)
+	}
+	if v := m["doc"].(map[string]interface{})["items"].(map[string]interface{})["item"]; !reflect.DeepEqual(v, []interface{}{}) {
+		t.Errorf("items: %#v", v)
+	}
+	m["doc"].(map[string]interface{})["one"] = "x"
+	if err = m.ApplyPatch(Patch{{Op: "remove", Path: "/doc/one/0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := m.Exists("doc.one"); ok {
+		t.Error("doc.one not removed")
+	}
+
+	// collapse the list that's left with one member
+	m = Map{"a": []interface{}{"x", "y", "z"}, "b": []interface{}{"u", "v"}}
+	p = Patch{{Op: "remove", Path: "/a/0"}, {Op: "move", From: "/b/1", Path: "/c"}}
+	if err = m.ApplyPatch(p, &PatchOptions{CollapseLists: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Map{"a": []interface{}{"y", "z"}, "b": "u", "c": "v"}); !reflect.DeepEqual(m, want) {
+		t.Errorf("collapsed: %v", m)
+	}
+	if err = m.ApplyPatch(Patch{{Op: "remove", Path: "/a/0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m["a"], []interface{}{"z"}) {
+		t.Errorf("not collapsed: %v", m)
+	}
+}
+
+func TestApplyPatchNumericKeys(t *testing.T) {
+	fmt.Println("\n================== TestApplyPatchNumericKeys")
+	for _, tt := range []struct {
+		doc, patch, want string
+	}{
+		{`{"ids":{"9":"y"}}`, `[{"op":"add", "path":"/ids/0", "value":"x"}]`, `{"ids":{"0":"x","9":"y"}}`},
+		{`{"ids":{}}`, `[{"op":"add", "path":"/ids/123", "value":"x"}]`, `{"ids":{"123":"x"}}`},
+		{`{"a":{"b":1}}`, `[{"op":"remove", "path":"/a/b"}, {"op":"add", "path":"/a/0", "value":2}]`, `{"a":{"0":2}}`},
+		{`{"a":"s"}`, `[{"op":"add", "path":"/a/-", "value":"t"}]`, `{"a":["s","t"]}`},
+	} {
+		m, _ := NewMapJson([]byte(tt.doc))
+		p, err := ParsePatch([]byte(tt.patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = m.ApplyPatch(p); err != nil {
+			t.Fatal(tt.doc, tt.patch, err)
+		}
+		want, _ := NewMapJson([]byte(tt.want))
+		if !reflect.DeepEqual(m, want) {
+			t.Errorf("%s %s: got %v", tt.doc, tt.patch, m)
+		}
+	}
+
+	// an index on a map is a key - not for a list of one
+	m := Map{"a": map[string]interface{}{"b": "1"}}
+	for _, op := range []PatchOp{{Op: "test", Path: "/a/0", Value: "1"}, {Op: "remove", Path: "/a/0"}} {
+		if err := m.ApplyPatch(Patch{op}); !errors.Is(err, PathNotExistError) {
+			t.Errorf("%s: %v", op.Op, err)
+		}
+	}
+	if err := m.ApplyPatch(Patch{{Op: "remove", Path: "/a/0"}}, &PatchOptions{ListOfOne: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 0 {
+		t.Errorf("ListOfOne: %v", m)
+	}
+
+	// round trip
+	a := Map{"ids": map[string]interface{}{"1": "a"}, "l": map[string]interface{}{"k": "v"}}
+	b := Map{"ids": map[string]interface{}{"1": "a", "0": "b"}, "l": []interface{}{map[string]interface{}{"k": "v"}, "w"}}
+	p := a.CreatePatch(b)
+	fmt.Println(p)
+	if err := a.ApplyPatch(p); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("got %v\nwant %v", a, b)
+	}
+}
+
+func TestCreatePatch(t *testing.T) {
+	fmt.Println("\n================== TestCreatePatch")
+	a, err := NewMapXml(diffOld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewMapXml(diffNew)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b["feed"].(map[string]interface{})["x/y"] = []interface{}{"1", "2"}
+	a["feed"].(map[string]interface{})["x/y"] = "1"
+
+	p := a.CreatePatch(b)
+	j, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(j))
+	if p, err = ParsePatch(j); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.ApplyPatch(p); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("got %v\nwant %v", a, b)
+	}
+	if p = a.CreatePatch(b); len(p) != 0 {
+		t.Errorf("same: %v", p)
+	}
+
+	// the other way
+	a, _ = NewMapXml(diffOld)
+	p = b.CreatePatch(a)
+	if err = b.ApplyPatch(p); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("got %v\nwant %v", b, a)
+	}
+}
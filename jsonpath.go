@@ -0,0 +1,1142 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// jsonpath.go - JSONPath (RFC 9535) queries on Map values.
+
+package mxj
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// QueryResult is a node selected by mv.Query().
+type QueryResult struct {
+	Path  string      // the normalized path - e.g., $['catalog']['book'][0]['-id']
+	Value interface{} // the value at the path
+}
+
+// Query returns the nodes of the Map selected by a JSONPath query - RFC 9535 -
+// with their normalized paths.
+//
+//	All of the RFC 9535 syntax is supported:
+//	   $                 the root node - the Map
+//	   .name, ['name']   the member 'name' of an object
+//	   .*, [*]           all members of an object or elements of an array
+//	   [0], [-1]         an array element, counting from the end if negative
+//	   [1:5:2]           an array slice - start:end:step, each optional
+//	   ['a','b',0]       a union of selectors
+//	   ..name, ..[0]     descendant segments - the selectors applied at any depth
+//	   [?expr]           a filter of the members/elements of the node:
+//	                        @.price < 10 && @.author == 'Gaddis'
+//	                        !@.isbn || ($.limit >= @.qty)
+//	                        length(@.title) > 10, count(@.*) == 2, value(@..id) == 'x'
+//	                        match(@.date, '2026-.*'), search(@.title, '(?i)moby')
+//	Keys that aren't RFC 9535 member names - e.g., XML attribute keys, "-id", "#text"
+//	keys and "prefix:local" names - can be used in dot notation: $.book.-id, $.book.#text.
+//	For Map values decoded from XML, remember that a single element isn't a list: with
+//	one book $.catalog.book is the book and $.catalog.book[*] its members - "-id",
+//	"title", etc.; with several, $.catalog.book - and $..book - is the list as one node
+//	and $.catalog.book[*] the books.  ValuesForPath("catalog.book") returns the books
+//	whether there's one or several.
+//	Object members are selected in key order, so the results are in a consistent order.
+//	Any of the numeric types produced by the decoders - float64, int64, json.Number, etc. -
+//	compare as numbers; XML values that were not cast are strings.
+func (mv Map) Query(jsonpath string) ([]QueryResult, error) {
+	q, err := parseJsonPath(jsonpath)
+	if err != nil {
+		return nil, err
+	}
+	root := &jpNode{value: map[string]interface{}(mv)}
+	nodes := q.eval(root, root)
+	res := make([]QueryResult, len(nodes))
+	for i, n := range nodes {
+		res[i] = QueryResult{n.path(), n.value}
+	}
+	return res, nil
+}
+
+// ------------------------------ query evaluation ------------------------------
+
+// jpNode is a selected node; the parent chain gives the path.
+type jpNode struct {
+	parent *jpNode
+	key    interface{} // string member name or int array index
+	value  interface{}
+}
+
+// path returns the normalized path of the node - RFC 9535, section 2.7.
+func (n *jpNode) path() string {
+	var keys []interface{}
+	for ; n.parent != nil; n = n.parent {
+		keys = append(keys, n.key)
+	}
+	var b strings.Builder
+	b.WriteString("$")
+	for i := len(keys) - 1; i >= 0; i-- {
+		switch k := keys[i].(type) {
+		case int:
+			b.WriteString("[" + strconv.Itoa(k) + "]")
+		case string:
+			b.WriteString("['" + jpEscape(k) + "']")
+		}
+	}
+	return b.String()
+}
+
+func jpEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// jpObject returns the value as a map, if it is one.
+func jpObject(v interface{}) (map[string]interface{}, bool) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		return vv, true
+	case Map:
+		return map[string]interface{}(vv), true
+	}
+	return nil, false
+}
+
+// jpChildren appends the member values or elements of the node to 'out'.
+func jpChildren(n *jpNode, out []*jpNode) []*jpNode {
+	if m, ok := jpObject(n.value); ok {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = append(out, &jpNode{n, k, m[k]})
+		}
+	} else if a, ok := n.value.([]interface{}); ok {
+		for i, v := range a {
+			out = append(out, &jpNode{n, i, v})
+		}
+	}
+	return out
+}
+
+type jpQuery struct {
+	root bool // '$' rather than '@'
+	segs []jpSegment
+}
+
+type jpSegment struct {
+	desc bool // ".." - the selectors apply to the node and all its descendants
+	sels []jpSelector
+}
+
+const (
+	jpName = iota
+	jpWild
+	jpIndex
+	jpSlice
+	jpFilter
+)
+
+type jpSelector struct {
+	kind             int
+	name             string
+	index            int
+	start, end, step *int
+	filter           jpLogical
+}
+
+// eval returns the nodes selected by the query.
+func (q *jpQuery) eval(root, cur *jpNode) []*jpNode {
+	nodes := []*jpNode{cur}
+	if q.root {
+		nodes[0] = root
+	}
+	for _, seg := range q.segs {
+		var next []*jpNode
+		for _, n := range nodes {
+			if seg.desc {
+				next = seg.descend(root, n, next)
+			} else {
+				next = seg.apply(root, n, next)
+			}
+		}
+		nodes = next
+		if len(nodes) == 0 {
+			break
+		}
+	}
+	return nodes
+}
+
+// singular reports whether the query selects at most one node - RFC 9535, section 2.3.5.1.
+func (q *jpQuery) singular() bool {
+	for _, seg := range q.segs {
+		if seg.desc || len(seg.sels) != 1 {
+			return false
+		}
+		if k := seg.sels[0].kind; k != jpName && k != jpIndex {
+			return false
+		}
+	}
+	return true
+}
+
+// value is the value of a singular query; 'false' is Nothing.
+func (q *jpQuery) value(root, cur *jpNode) (interface{}, bool) {
+	nodes := q.eval(root, cur)
+	if len(nodes) != 1 {
+		return nil, false
+	}
+	return nodes[0].value, true
+}
+
+func (s *jpSegment) apply(root, n *jpNode, out []*jpNode) []*jpNode {
+	for i := range s.sels {
+		out = s.sels[i].apply(root, n, out)
+	}
+	return out
+}
+
+func (s *jpSegment) descend(root, n *jpNode, out []*jpNode) []*jpNode {
+	out = s.apply(root, n, out)
+	for _, c := range jpChildren(n, nil) {
+		out = s.descend(root, c, out)
+	}
+	return out
+}
+
+func (s *jpSelector) apply(root, n *jpNode, out []*jpNode) []*jpNode {
+	switch s.kind {
+	case jpName:
+		if m, ok := jpObject(n.value); ok {
+			if v, ok := m[s.name]; ok {
+				out = append(out, &jpNode{n, s.name, v})
+			}
+		}
+	case jpWild:
+		out = jpChildren(n, out)
+	case jpIndex:
+		if a, ok := n.value.([]interface{}); ok {
+			i := s.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i >= 0 && i < len(a) {
+				out = append(out, &jpNode{n, i, a[i]})
+			}
+		}
+	case jpSlice:
+		if a, ok := n.value.([]interface{}); ok {
+			for _, i := range s.slice(len(a)) {
+				out = append(out, &jpNode{n, i, a[i]})
+			}
+		}
+	case jpFilter:
+		for _, c := range jpChildren(n, nil) {
+			if s.filter.test(root, c) {
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// slice returns the indexes of an array of length 'n' selected by start:end:step -
+// RFC 9535, section 2.3.4.2.2.
+func (s *jpSelector) slice(n int) []int {
+	step := 1
+	if s.step != nil {
+		step = *s.step
+	}
+	if step == 0 {
+		return nil
+	}
+	norm := func(i int) int {
+		if i < 0 {
+			return n + i
+		}
+		return i
+	}
+	clamp := func(i, lo, hi int) int {
+		if i < lo {
+			return lo
+		}
+		if i > hi {
+			return hi
+		}
+		return i
+	}
+	var idx []int
+	if step > 0 {
+		start, end := 0, n
+		if s.start != nil {
+			start = norm(*s.start)
+		}
+		if s.end != nil {
+			end = norm(*s.end)
+		}
+		for i := clamp(start, 0, n); i < clamp(end, 0, n); i += step {
+			idx = append(idx, i)
+		}
+		return idx
+	}
+	start, end := n-1, -n-1
+	if s.start != nil {
+		start = norm(*s.start)
+	}
+	if s.end != nil {
+		end = norm(*s.end)
+	}
+	for i := clamp(start, -1, n-1); clamp(end, -1, n-1) < i; i += step {
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+// --------------------------- filter expressions ---------------------------
+
+// jpLogical is a filter expression that is 'true' or 'false' for a node.
+type jpLogical interface {
+	test(root, cur *jpNode) bool
+}
+
+// jpComparable is a filter expression with a value; 'false' is Nothing.
+type jpComparable interface {
+	value(root, cur *jpNode) (interface{}, bool)
+}
+
+type jpOr []jpLogical
+
+func (e jpOr) test(root, cur *jpNode) bool {
+	for _, x := range e {
+		if x.test(root, cur) {
+			return true
+		}
+	}
+	return false
+}
+
+type jpAnd []jpLogical
+
+func (e jpAnd) test(root, cur *jpNode) bool {
+	for _, x := range e {
+		if !x.test(root, cur) {
+			return false
+		}
+	}
+	return true
+}
+
+type jpNot struct{ e jpLogical }
+
+func (e jpNot) test(root, cur *jpNode) bool {
+	return !e.e.test(root, cur)
+}
+
+// jpExists is a query used as a test - 'true' if it selects any node.
+type jpExists struct{ q *jpQuery }
+
+func (e jpExists) test(root, cur *jpNode) bool {
+	return len(e.q.eval(root, cur)) > 0
+}
+
+type jpCompare struct {
+	op   string
+	l, r jpComparable
+}
+
+func (e jpCompare) test(root, cur *jpNode) bool {
+	a, aok := e.l.value(root, cur)
+	b, bok := e.r.value(root, cur)
+	switch e.op {
+	case "==":
+		return jpEqual(a, aok, b, bok)
+	case "!=":
+		return !jpEqual(a, aok, b, bok)
+	case "<":
+		return jpLess(a, aok, b, bok)
+	case "<=":
+		return jpLess(a, aok, b, bok) || jpEqual(a, aok, b, bok)
+	case ">":
+		return jpLess(b, bok, a, aok)
+	case ">=":
+		return jpLess(b, bok, a, aok) || jpEqual(a, aok, b, bok)
+	}
+	return false
+}
+
+type jpLiteral struct{ v interface{} }
+
+func (e jpLiteral) value(root, cur *jpNode) (interface{}, bool) {
+	return e.v, true
+}
+
+// jpNumber returns the value as a float64 if it's one of the numeric types.
+func jpNumber(v interface{}) (float64, bool) {
+	switch vv := v.(type) {
+	case float64:
+		return vv, true
+	case float32:
+		return float64(vv), true
+	case int:
+		return float64(vv), true
+	case int32:
+		return float64(vv), true
+	case int64:
+		return float64(vv), true
+	case uint64:
+		return float64(vv), true
+	case json.Number:
+		f, err := vv.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func jpEqual(a interface{}, aok bool, b interface{}, bok bool) bool {
+	if !aok || !bok {
+		return aok == bok // Nothing == Nothing
+	}
+	if fa, ok := jpNumber(a); ok {
+		fb, ok := jpNumber(b)
+		return ok && fa == fb
+	}
+	if ma, ok := jpObject(a); ok {
+		mb, ok := jpObject(b)
+		if !ok || len(ma) != len(mb) {
+			return false
+		}
+		for k, va := range ma {
+			vb, ok := mb[k]
+			if !ok || !jpEqual(va, true, vb, true) {
+				return false
+			}
+		}
+		return true
+	}
+	if la, ok := a.([]interface{}); ok {
+		lb, ok := b.([]interface{})
+		if !ok || len(la) != len(lb) {
+			return false
+		}
+		for i := range la {
+			if !jpEqual(la[i], true, lb[i], true) {
+				return false
+			}
+		}
+		return true
+	}
+	switch a.(type) {
+	case string, bool, nil:
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func jpLess(a interface{}, aok bool, b interface{}, bok bool) bool {
+	if !aok || !bok {
+		return false
+	}
+	if fa, ok := jpNumber(a); ok {
+		fb, ok := jpNumber(b)
+		return ok && fa < fb
+	}
+	if sa, ok := a.(string); ok {
+		sb, ok := b.(string)
+		return ok && sa < sb
+	}
+	return false
+}
+
+// ------------------------------ function extensions ------------------------------
+
+// the function extension types - RFC 9535, section 2.4.1
+const (
+	jpValueType = iota
+	jpLogicalType
+	jpNodesType
+)
+
+type jpFunc struct {
+	name string
+	args []interface{}  // jpComparable values or *jpQuery nodes
+	re   *regexp.Regexp // match() and search() with a literal pattern
+}
+
+// jpFuncs are the RFC 9535 functions: result type and argument types.
+var jpFuncs = map[string]struct {
+	typ  int
+	args []int
+}{
+	"length": {jpValueType, []int{jpValueType}},
+	"count":  {jpValueType, []int{jpNodesType}},
+	"match":  {jpLogicalType, []int{jpValueType, jpValueType}},
+	"search": {jpLogicalType, []int{jpValueType, jpValueType}},
+	"value":  {jpValueType, []int{jpNodesType}},
+}
+
+func (f *jpFunc) value(root, cur *jpNode) (interface{}, bool) {
+	switch f.name {
+	case "length":
+		v, ok := f.args[0].(jpComparable).value(root, cur)
+		if !ok {
+			return nil, false
+		}
+		if s, ok := v.(string); ok {
+			return float64(utf8.RuneCountInString(s)), true
+		}
+		if a, ok := v.([]interface{}); ok {
+			return float64(len(a)), true
+		}
+		if m, ok := jpObject(v); ok {
+			return float64(len(m)), true
+		}
+		return nil, false
+	case "count":
+		return float64(len(f.args[0].(*jpQuery).eval(root, cur))), true
+	case "value":
+		nodes := f.args[0].(*jpQuery).eval(root, cur)
+		if len(nodes) != 1 {
+			return nil, false
+		}
+		return nodes[0].value, true
+	}
+	return nil, false
+}
+
+func (f *jpFunc) test(root, cur *jpNode) bool {
+	v, ok := f.args[0].(jpComparable).value(root, cur)
+	s, sok := v.(string)
+	if !ok || !sok {
+		return false
+	}
+	re := f.re
+	if re == nil {
+		p, ok := f.args[1].(jpComparable).value(root, cur)
+		ps, pok := p.(string)
+		if !ok || !pok {
+			return false
+		}
+		var err error
+		if re, err = iRegexp(ps, f.name == "match"); err != nil {
+			return false
+		}
+	}
+	return re.MatchString(s)
+}
+
+// iRegexp compiles an I-Regexp - RFC 9485 - pattern: "." doesn't match line
+// breaks and, for match(), the whole string must match.
+func iRegexp(p string, full bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+	var inClass bool
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '\\' && i+1 < len(p):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(p[i])
+			continue
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '.' && !inClass:
+			b.WriteString(`[^\n\r]`)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	if full {
+		return regexp.Compile(`^(?:` + b.String() + `)$`)
+	}
+	return regexp.Compile(b.String())
+}
+
+// ------------------------------ parse a query ------------------------------
+
+type jpParser struct {
+	s string
+	i int
+}
+
+func parseJsonPath(s string) (*jpQuery, error) {
+	p := &jpParser{s: s}
+	if !p.consume("$") {
+		return nil, p.errorf("query must start with $")
+	}
+	q, err := p.segments(true)
+	if err != nil {
+		return nil, err
+	}
+	if p.i != len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.i:])
+	}
+	return q, nil
+}
+
+func (p *jpParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("jsonpath %q: offset %d: %s", p.s, p.i, fmt.Sprintf(format, args...))
+}
+
+func (p *jpParser) peek() byte {
+	if p.i < len(p.s) {
+		return p.s[p.i]
+	}
+	return 0
+}
+
+func (p *jpParser) consume(tok string) bool {
+	if strings.HasPrefix(p.s[p.i:], tok) {
+		p.i += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *jpParser) skipSpace() {
+	for p.i < len(p.s) {
+		switch p.s[p.i] {
+		case ' ', '\t', '\n', '\r':
+			p.i++
+		default:
+			return
+		}
+	}
+}
+
+// segments parses the segments following '$' or '@'.
+func (p *jpParser) segments(root bool) (*jpQuery, error) {
+	q := &jpQuery{root: root}
+	for {
+		start := p.i
+		p.skipSpace()
+		var seg jpSegment
+		switch {
+		case p.consume(".."):
+			seg.desc = true
+			if p.peek() == '[' {
+				p.i++
+				sels, err := p.brackets()
+				if err != nil {
+					return nil, err
+				}
+				seg.sels = sels
+			} else {
+				sel, err := p.dotSelector()
+				if err != nil {
+					return nil, err
+				}
+				seg.sels = []jpSelector{sel}
+			}
+		case p.consume("."):
+			sel, err := p.dotSelector()
+			if err != nil {
+				return nil, err
+			}
+			seg.sels = []jpSelector{sel}
+		case p.consume("["):
+			sels, err := p.brackets()
+			if err != nil {
+				return nil, err
+			}
+			seg.sels = sels
+		default:
+			p.i = start // whitespace isn't part of the query
+			return q, nil
+		}
+		q.segs = append(q.segs, seg)
+	}
+}
+
+// isNameFirst and isNameChar allow "-", "#" and ":" as well as the RFC 9535
+// member-name-shorthand characters, for XML attribute, "#text" and "prefix:local" keys.
+func isNameFirst(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80 || c == '-' || c == '#'
+}
+
+func isNameChar(c byte) bool {
+	return isNameFirst(c) || c >= '0' && c <= '9' || c == ':'
+}
+
+// dotSelector parses the '*' or member name following '.' or '..'.
+func (p *jpParser) dotSelector() (jpSelector, error) {
+	if p.consume("*") {
+		return jpSelector{kind: jpWild}, nil
+	}
+	start := p.i
+	if !isNameFirst(p.peek()) {
+		return jpSelector{}, p.errorf("expected member name or *")
+	}
+	for p.i < len(p.s) && isNameChar(p.s[p.i]) {
+		p.i++
+	}
+	return jpSelector{kind: jpName, name: p.s[start:p.i]}, nil
+}
+
+// brackets parses the selectors following '['.
+func (p *jpParser) brackets() ([]jpSelector, error) {
+	var sels []jpSelector
+	for {
+		p.skipSpace()
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+		p.skipSpace()
+		if p.consume("]") {
+			return sels, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected , or ]")
+		}
+	}
+}
+
+func (p *jpParser) selector() (jpSelector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		s, err := p.stringLiteral()
+		return jpSelector{kind: jpName, name: s}, err
+	case c == '*':
+		p.i++
+		return jpSelector{kind: jpWild}, nil
+	case c == '?':
+		p.i++
+		p.skipSpace()
+		e, err := p.orExpr()
+		return jpSelector{kind: jpFilter, filter: e}, err
+	case c == ':' || c == '-' || c >= '0' && c <= '9':
+		var sel jpSelector
+		var n *int
+		if c != ':' {
+			i, err := p.integer()
+			if err != nil {
+				return sel, err
+			}
+			p.skipSpace()
+			if p.peek() != ':' {
+				return jpSelector{kind: jpIndex, index: i}, nil
+			}
+			n = &i
+		}
+		// a slice - start:end:step
+		sel.kind = jpSlice
+		sel.start = n
+		p.i++ // ':'
+		p.skipSpace()
+		if c := p.peek(); c == '-' || c >= '0' && c <= '9' {
+			i, err := p.integer()
+			if err != nil {
+				return sel, err
+			}
+			sel.end = &i
+			p.skipSpace()
+		}
+		if p.consume(":") {
+			p.skipSpace()
+			if c := p.peek(); c == '-' || c >= '0' && c <= '9' {
+				i, err := p.integer()
+				if err != nil {
+					return sel, err
+				}
+				sel.step = &i
+			}
+		}
+		return sel, nil
+	}
+	return jpSelector{}, p.errorf("invalid selector")
+}
+
+// integer parses an RFC 9535 int - no leading zeros, no "-0", within +/-(2^53-1).
+func (p *jpParser) integer() (int, error) {
+	start := p.i
+	p.consume("-")
+	digits := p.i
+	for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+		p.i++
+	}
+	s := p.s[start:p.i]
+	if p.i == digits || (p.s[digits] == '0' && (p.i-digits > 1 || digits > start)) {
+		return 0, p.errorf("invalid integer %q", s)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n > 1<<53-1 || n < -(1<<53-1) {
+		return 0, p.errorf("integer out of range %q", s)
+	}
+	return int(n), nil
+}
+
+// stringLiteral parses a single or double quoted string.
+func (p *jpParser) stringLiteral() (string, error) {
+	q := p.s[p.i]
+	p.i++
+	var b strings.Builder
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		p.i++
+		switch {
+		case c == q:
+			return b.String(), nil
+		case c < 0x20:
+			return "", p.errorf("control character in string")
+		case c != '\\':
+			b.WriteByte(c)
+			continue
+		}
+		if p.i == len(p.s) {
+			break
+		}
+		c = p.s[p.i]
+		p.i++
+		switch c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '/', '\\', '\'', '"':
+			b.WriteByte(c)
+		case 'u':
+			r, err := p.hex4()
+			if err != nil {
+				return "", err
+			}
+			if utf16.IsSurrogate(r) {
+				if !p.consume(`\u`) {
+					return "", p.errorf("invalid surrogate pair")
+				}
+				r2, err := p.hex4()
+				if err != nil {
+					return "", err
+				}
+				if r = utf16.DecodeRune(r, r2); r == utf8.RuneError {
+					return "", p.errorf("invalid surrogate pair")
+				}
+			}
+			b.WriteRune(r)
+		default:
+			return "", p.errorf("invalid escape \\%c", c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *jpParser) hex4() (rune, error) {
+	if p.i+4 > len(p.s) {
+		return 0, p.errorf("invalid \\u escape")
+	}
+	n, err := strconv.ParseUint(p.s[p.i:p.i+4], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid \\u escape")
+	}
+	p.i += 4
+	return rune(n), nil
+}
+
+// orExpr parses a logical-or-expr; it's the filter expression.
+func (p *jpParser) orExpr() (jpLogical, error) {
+	var or jpOr
+	for {
+		e, err := p.andExpr()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, e)
+		p.skipSpace()
+		if !p.consume("||") {
+			break
+		}
+		p.skipSpace()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *jpParser) andExpr() (jpLogical, error) {
+	var and jpAnd
+	for {
+		e, err := p.basicExpr()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, e)
+		p.skipSpace()
+		if !p.consume("&&") {
+			break
+		}
+		p.skipSpace()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// basicExpr parses a paren-expr, comparison-expr or test-expr.
+func (p *jpParser) basicExpr() (jpLogical, error) {
+	if p.peek() == '!' && !strings.HasPrefix(p.s[p.i:], "!=") {
+		p.i++
+		p.skipSpace()
+		e, err := p.basicExpr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := e.(jpCompare); ok {
+			return nil, p.errorf("! before a comparison needs parentheses")
+		}
+		return jpNot{e}, nil
+	}
+	if p.consume("(") {
+		p.skipSpace()
+		e, err := p.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return e, nil
+	}
+
+	start := p.i
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	var op string
+	for _, o := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		// a test-expr
+		switch e := l.(type) {
+		case *jpQuery:
+			return jpExists{e}, nil
+		case *jpFunc:
+			if jpFuncs[e.name].typ == jpLogicalType {
+				return e, nil
+			}
+		}
+		p.i = start
+		return nil, p.errorf("expected a comparison, query or logical function")
+	}
+	p.skipSpace()
+	rstart := p.i
+	r, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	lc, ok := p.comparable(l)
+	if !ok {
+		p.i = start
+		return nil, p.errorf("not comparable - a literal, singular query or value function is required")
+	}
+	rc, ok := p.comparable(r)
+	if !ok {
+		p.i = rstart
+		return nil, p.errorf("not comparable - a literal, singular query or value function is required")
+	}
+	return jpCompare{op, lc, rc}, nil
+}
+
+// comparable returns the operand as a jpComparable if it has a value.
+func (p *jpParser) comparable(e interface{}) (jpComparable, bool) {
+	switch x := e.(type) {
+	case jpLiteral:
+		return x, true
+	case *jpQuery:
+		return x, x.singular()
+	case *jpFunc:
+		return x, jpFuncs[x.name].typ == jpValueType
+	}
+	return nil, false
+}
+
+// operand parses a literal, query or function expression.
+func (p *jpParser) operand() (interface{}, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.i++
+		return p.segments(c == '$')
+	case c == '\'' || c == '"':
+		s, err := p.stringLiteral()
+		return jpLiteral{s}, err
+	case c == '-' || c >= '0' && c <= '9':
+		return p.number()
+	case c >= 'a' && c <= 'z':
+		start := p.i
+		for p.i < len(p.s) {
+			c := p.s[p.i]
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+				break
+			}
+			p.i++
+		}
+		name := p.s[start:p.i]
+		if p.peek() != '(' {
+			switch name {
+			case "true":
+				return jpLiteral{true}, nil
+			case "false":
+				return jpLiteral{false}, nil
+			case "null":
+				return jpLiteral{nil}, nil
+			}
+			p.i = start
+			return nil, p.errorf("unknown literal %q", name)
+		}
+		return p.function(name, start)
+	}
+	return nil, p.errorf("expected a literal, query or function")
+}
+
+// number parses a JSON number literal.
+func (p *jpParser) number() (interface{}, error) {
+	start := p.i
+	p.consume("-")
+	digits := p.i
+	for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+		p.i++
+	}
+	if p.i == digits || (p.s[digits] == '0' && p.i-digits > 1) {
+		return nil, p.errorf("invalid number")
+	}
+	if p.consume(".") {
+		frac := p.i
+		for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+			p.i++
+		}
+		if p.i == frac {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	if c := p.peek(); c == 'e' || c == 'E' {
+		p.i++
+		if c := p.peek(); c == '+' || c == '-' {
+			p.i++
+		}
+		exp := p.i
+		for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+			p.i++
+		}
+		if p.i == exp {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	f, err := strconv.ParseFloat(p.s[start:p.i], 64)
+	if err != nil {
+		return nil, p.errorf("invalid number")
+	}
+	return jpLiteral{f}, nil
+}
+
+// function parses the arguments of a function expression and checks their types.
+func (p *jpParser) function(name string, start int) (interface{}, error) {
+	def, ok := jpFuncs[name]
+	if !ok {
+		p.i = start
+		return nil, p.errorf("unknown function %s()", name)
+	}
+	p.i++ // '('
+	f := &jpFunc{name: name}
+	for {
+		p.skipSpace()
+		if len(f.args) == 0 && p.consume(")") {
+			break
+		}
+		astart := p.i
+		a, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		n := len(f.args)
+		if n == len(def.args) {
+			p.i = astart
+			return nil, p.errorf("too many arguments for %s()", name)
+		}
+		switch def.args[n] {
+		case jpValueType:
+			c, ok := p.comparable(a)
+			if !ok {
+				p.i = astart
+				return nil, p.errorf("%s() argument %d must be a value", name, n+1)
+			}
+			a = c
+		case jpNodesType:
+			if _, ok := a.(*jpQuery); !ok {
+				p.i = astart
+				return nil, p.errorf("%s() argument %d must be a query", name, n+1)
+			}
+		}
+		f.args = append(f.args, a)
+		p.skipSpace()
+		if p.consume(")") {
+			break
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected , or )")
+		}
+	}
+	if len(f.args) != len(def.args) {
+		return nil, p.errorf("%s() takes %d argument(s)", name, len(def.args))
+	}
+	if name == "match" || name == "search" {
+		if l, ok := f.args[1].(jpLiteral); ok {
+			if s, ok := l.v.(string); ok {
+				re, err := iRegexp(s, name == "match")
+				if err != nil {
+					return nil, p.errorf("%s() pattern: %s", name, err)
+				}
+				f.re = re
+			}
+		}
+	}
+	return f, nil
+}
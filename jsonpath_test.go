@@ -0,0 +1,177 @@
+package mxj
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// the RFC 9535 example
+var jsonPathStore = []byte(`{ "store": {
+    "book": [
+      { "category": "reference",
+        "author": "Nigel Rees",
+        "title": "Sayings of the Century",
+        "price": 8.95
+      },
+      { "category": "fiction",
+        "author": "Evelyn Waugh",
+        "title": "Sword of Honour",
+        "price": 12.99
+      },
+      { "category": "fiction",
+        "author": "Herman Melville",
+        "title": "Moby Dick",
+        "isbn": "0-553-21311-3",
+        "price": 8.99
+      },
+      { "category": "fiction",
+        "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings",
+        "isbn": "0-395-19395-8",
+        "price": 22.99
+      }
+    ],
+    "bicycle": {
+      "color": "red",
+      "price": 399
+    }
+  }
+}`)
+
+func queryPaths(t *testing.T, m Map, q string) string {
+	res, err := m.Query(q)
+	if err != nil {
+		t.Fatalf("%s: %s", q, err)
+	}
+	paths := make([]string, len(res))
+	for i, r := range res {
+		paths[i] = r.Path
+	}
+	return strings.Join(paths, " ")
+}
+
+func TestQuery(t *testing.T) {
+	fmt.Println("\n================== TestQuery")
+	m, err := NewMapJson(jsonPathStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		q, paths string
+	}{
+		{`$`, `$`},
+		{`$.store.book[*].author`, `$['store']['book'][0]['author'] $['store']['book'][1]['author'] $['store']['book'][2]['author'] $['store']['book'][3]['author']`},
+		{`$..author`, `$['store']['book'][0]['author'] $['store']['book'][1]['author'] $['store']['book'][2]['author'] $['store']['book'][3]['author']`},
+		{`$.store.*`, `$['store']['bicycle'] $['store']['book']`},
+		{`$.store..price`, `$['store']['bicycle']['price'] $['store']['book'][0]['price'] $['store']['book'][1]['price'] $['store']['book'][2]['price'] $['store']['book'][3]['price']`},
+		{`$..book[2]`, `$['store']['book'][2]`},
+		{`$..book[-1]`, `$['store']['book'][3]`},
+		{`$..book[0,1]`, `$['store']['book'][0] $['store']['book'][1]`},
+		{`$..book[:2]`, `$['store']['book'][0] $['store']['book'][1]`},
+		{`$..book[::-2]`, `$['store']['book'][3] $['store']['book'][1]`},
+		{`$..book[1:3]`, `$['store']['book'][1] $['store']['book'][2]`},
+		{`$..book[?@.isbn]`, `$['store']['book'][2] $['store']['book'][3]`},
+		{`$..book[?@.price<10]`, `$['store']['book'][0] $['store']['book'][2]`},
+		{`$..book[?@.price < 10 && @.category == "fiction"]`, `$['store']['book'][2]`},
+		{`$..book[?!@.isbn || @.price > 20].title`, `$['store']['book'][0]['title'] $['store']['book'][1]['title'] $['store']['book'][3]['title']`},
+		{`$..book[?(@.price > $.store.bicycle.price / 1)]`, ``},
+		{`$..book[?@.price < $.store.bicycle.price && @.author != 'Nigel Rees'].author`, `$['store']['book'][1]['author'] $['store']['book'][2]['author'] $['store']['book'][3]['author']`},
+		{`$..book[?length(@.title) > 20].title`, `$['store']['book'][0]['title'] $['store']['book'][3]['title']`},
+		{`$..book[?match(@.author, 'J.*')].author`, `$['store']['book'][3]['author']`},
+		{`$..book[?search(@.title, 'of')].title`, `$['store']['book'][0]['title'] $['store']['book'][1]['title'] $['store']['book'][3]['title']`},
+		{`$.store[?count(@.*) == 2]`, `$['store']['bicycle']`},
+		{`$.store[?value(@..color) == 'red']`, `$['store']['bicycle']`},
+		{`$["store"]['bicycle']["color", 'price']`, `$['store']['bicycle']['color'] $['store']['bicycle']['price']`},
+		{`$..*[?@ == 399]`, `$['store']['bicycle']['price']`},
+		{`$.store.book[?@.price == 8.95 || @.price == 22.99].price`, `$['store']['book'][0]['price'] $['store']['book'][3]['price']`},
+		{`$.store.nothere`, ``},
+		{`$.store.book[4]`, ``},
+	}
+	for _, tt := range tests {
+		if strings.Contains(tt.q, " / ") {
+			// not valid - no arithmetic
+			if _, err := m.Query(tt.q); err == nil {
+				t.Errorf("%s: no error", tt.q)
+			}
+			continue
+		}
+		if got := queryPaths(t, m, tt.q); got != tt.paths {
+			t.Errorf("%s\n got: %s\nwant: %s", tt.q, got, tt.paths)
+		}
+	}
+
+	res, _ := m.Query(`$..book[?@.author == 'Herman Melville'].price`)
+	if len(res) != 1 || res[0].Value != float64(8.99) {
+		t.Errorf("value: %v", res)
+	}
+}
+
+func TestQueryXml(t *testing.T) {
+	fmt.Println("\n================== TestQueryXml")
+	data := []byte(`<catalog>
+		<book id="bk101"><author>Gaddis</author><title>The Recognitions</title><price>12.5</price></book>
+		<book id="bk102"><author>Gaddis</author><title>JR</title><price>9</price></book>
+		<book id="bk103"><author>Pynchon</author><title>V.</title><price>8</price></book>
+	</catalog>`)
+	m, err := NewMapXml(data, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := attrPrefix + "id"
+	got := queryPaths(t, m, `$.catalog.book[?@.author == 'Gaddis' && @.price > 10].`+id)
+	if got != `$['catalog']['book'][0]['`+id+`']` {
+		t.Errorf("got: %s", got)
+	}
+	res, err := m.Query(`$..book[?@['` + id + `'] == 'bk103'].title`)
+	if err != nil || len(res) != 1 || res[0].Value != "V." {
+		t.Errorf("%v, %v", res, err)
+	}
+
+	// the same values from JSON
+	j, _ := m.Json()
+	mj, _ := NewMapJson(j)
+	for _, q := range []string{`$..book[?@.price < 10].title`, `$..book[-1:].` + id} {
+		a, b := queryPaths(t, m, q), queryPaths(t, mj, q)
+		if a != b || a == "" {
+			t.Errorf("%s: xml %s, json %s", q, a, b)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	fmt.Println("\n================== TestQueryErrors")
+	m := Map{"a": 1}
+	for _, q := range []string{
+		``, `a`, `$.`, `$[`, `$[01]`, `$[-0]`, `$['a`, `$..`, `$[?@.a == ]`,
+		`$[?@..a == 1]`,       // not singular
+		`$[?@.* == 1]`,        // not singular
+		`$[?length(@.*) > 1]`, // length() takes a value
+		`$[?count(1) > 1]`,    // count() takes a query
+		`$[?length(@.a)]`,     // not a test
+		`$[?foo(@.a)]`,        // unknown function
+		`$[?@.a == 1 && ]`,
+		`$[?!@.a == 1]`,
+		`$.a $`,
+	} {
+		_, err := m.Query(q)
+		if err == nil {
+			t.Errorf("%q: no error", q)
+			continue
+		}
+		fmt.Println(err)
+	}
+}
+
+func TestQueryNormalizedPath(t *testing.T) {
+	fmt.Println("\n================== TestQueryNormalizedPath")
+	m := Map{"o": map[string]interface{}{"j j": map[string]interface{}{"k.k": 3}, "'\\\n": 1}}
+	got := queryPaths(t, m, `$.o..*`)
+	want := `$['o']['\'\\\n'] $['o']['j j'] $['o']['j j']['k.k']`
+	if got != want {
+		t.Errorf("\n got: %s\nwant: %s", got, want)
+	}
+	if got := queryPaths(t, m, `$.o['j j']["k.k"]`); got != `$['o']['j j']['k.k']` {
+		t.Errorf("got: %s", got)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// jsonreader.go - pull the next JSON value off a stream for NewMapJsonReader(), etc.
+// Handles a series of values - "{...} {...}", NDJSON, etc. - and, with a JsonReader,
+// streams the members of a top-level array as separate values.
+
+package mxj
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// JsonReader is a buffered reader for a stream of JSON values.  Pass it to
+// NewMapJsonReader() or NewMapJsonReaderRaw() to get the values one at a time:
+//
+//	jr := mxj.NewJsonReader(os.Stdin)
+//	for {
+//		m, raw, err := mxj.NewMapJsonReaderRaw(jr)
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+//
+// The stream can be a series of objects, NDJSON - including scalar values - or
+// whitespace or comma separated values; the members of a top-level array, "[{...}, {...}]",
+// are returned one at a time.  Since the stream is read in blocks, don't use the
+// underlying io.Reader after wrapping it.
+type JsonReader struct {
+	r       *bufio.Reader
+	inArray bool // streaming the members of a top-level array
+}
+
+// NewJsonReader returns a JsonReader for 'r'.
+func NewJsonReader(r io.Reader) *JsonReader {
+	if jr, ok := r.(*JsonReader); ok {
+		return jr
+	}
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &JsonReader{r: br}
+}
+
+// Read implements io.Reader for the unread, buffered JSON.
+func (j *JsonReader) Read(p []byte) (int, error) {
+	return j.r.Read(p)
+}
+
+// next returns the raw JSON of the next value in the stream.
+func (j *JsonReader) next() ([]byte, error) {
+	return readJson(j.r, &j.inArray)
+}
+
+// getJson returns the raw JSON of the next value read from 'rdr'.
+// Unless 'rdr' is a JsonReader, no more than the value is read from 'rdr', and
+// only the members of a top-level array of objects are returned one at a time;
+// other arrays are returned as a single value.  If 'rdr' is not an io.ByteScanner,
+// the byte that ends a number or literal - a separator or the first byte of
+// the next value, '{', '[' or '"' - can't be unread and is lost.
+func getJson(rdr io.Reader) ([]byte, error) {
+	switch r := rdr.(type) {
+	case *JsonReader:
+		return r.next()
+	case io.ByteScanner:
+		return readJson(r, nil)
+	case io.ByteReader:
+		return readJson(&byteScanner{r: r}, nil)
+	}
+	return readJson(&byteScanner{r: myByteReader(rdr).(io.ByteReader)}, nil)
+}
+
+// byteScanner adds UnreadByte to an io.ByteReader.
+type byteScanner struct {
+	r      io.ByteReader
+	last   byte
+	unread bool
+}
+
+func (b *byteScanner) ReadByte() (byte, error) {
+	if b.unread {
+		b.unread = false
+		return b.last, nil
+	}
+	c, err := b.r.ReadByte()
+	if err == nil {
+		b.last = c
+	}
+	return c, err
+}
+
+func (b *byteScanner) UnreadByte() error {
+	b.unread = true
+	return nil
+}
+
+func isJsonSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// readJson scans the next JSON value from 'r' and returns its bytes exactly as
+// read.  Syntax checking is left to the decoder; only the nesting of {...}
+// and [...] outside of strings is tracked to find the end of the value.
+// If 'inArray' is not nil, a top-level array is streamed: its members are
+// returned as values and the state is kept in *inArray between calls.
+// Otherwise, only an array of objects is streamed, as getJson() notes.
+func readJson(r io.ByteScanner, inArray *bool) ([]byte, error) {
+	var c byte
+	var err error
+
+	// skip to the start of the value
+	for {
+		if c, err = r.ReadByte(); err != nil {
+			if err == io.EOF && inArray != nil && *inArray {
+				*inArray = false
+				return nil, fmt.Errorf("no closing ] for JSON array")
+			}
+			return nil, err
+		}
+		// commas between values are accepted - "{...}, {...}" - as in an array
+		if isJsonSpace(c) || c == ',' {
+			continue
+		}
+		if inArray != nil {
+			if *inArray {
+				if c == ']' {
+					*inArray = false
+					continue
+				}
+			} else if c == '[' {
+				*inArray = true
+				continue
+			}
+		} else if c == ']' {
+			// the end of an array of objects stepped into below
+			continue
+		}
+		break
+	}
+
+	jb := []byte{c}
+	if c == '[' && inArray == nil {
+		// Without a JsonReader there's no state kept between calls, so only an
+		// array of objects is streamed: if '{' follows, it starts the next value
+		// and the closing ']' is skipped, above, on a later call.
+		for {
+			if c, err = r.ReadByte(); err != nil {
+				if err == io.EOF {
+					return jb, fmt.Errorf("no closing ] for JSON value: %s", string(jb))
+				}
+				return jb, err
+			}
+			if !isJsonSpace(c) {
+				break
+			}
+			jb = append(jb, c)
+		}
+		if c == '{' {
+			jb = []byte{c}
+		} else {
+			r.UnreadByte()
+			c = '['
+		}
+	}
+	switch c {
+	case '{', '[':
+		// nesting stack of the expected closing characters
+		closers := []byte{closerFor(c)}
+		var inQuote, escaped bool
+		for len(closers) > 0 {
+			if c, err = r.ReadByte(); err != nil {
+				if err == io.EOF {
+					return jb, fmt.Errorf("no closing %c for JSON value: %s", closers[len(closers)-1], string(jb))
+				}
+				return jb, err
+			}
+			jb = append(jb, c)
+			if inQuote {
+				switch {
+				case escaped:
+					escaped = false
+				case c == '\\':
+					escaped = true
+				case c == '"':
+					inQuote = false
+				}
+				continue
+			}
+			switch c {
+			case '"':
+				inQuote = true
+			case '{', '[':
+				closers = append(closers, closerFor(c))
+			case '}', ']':
+				if c != closers[len(closers)-1] {
+					return jb, fmt.Errorf("unexpected %c in JSON value: %s", c, string(jb))
+				}
+				closers = closers[:len(closers)-1]
+			}
+		}
+	case '"':
+		var escaped bool
+		for {
+			if c, err = r.ReadByte(); err != nil {
+				if err == io.EOF {
+					return jb, fmt.Errorf("no closing \" for JSON string: %s", string(jb))
+				}
+				return jb, err
+			}
+			jb = append(jb, c)
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				break
+			}
+		}
+	case '}', ']':
+		return jb, fmt.Errorf("closing %c without opening %c", c, openerFor(c))
+	default:
+		// number, true, false, null - or junk for the decoder to reject
+		for {
+			if c, err = r.ReadByte(); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return jb, err
+			}
+			if isJsonSpace(c) || c == ',' || c == ']' || c == '}' || c == '{' || c == '[' || c == '"' {
+				r.UnreadByte()
+				break
+			}
+			jb = append(jb, c)
+		}
+	}
+	return jb, nil
+}
+
+func closerFor(c byte) byte {
+	if c == '{' {
+		return '}'
+	}
+	return ']'
+}
+
+func openerFor(c byte) byte {
+	if c == '}' {
+		return '{'
+	}
+	return '['
+}
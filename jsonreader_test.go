@@ -0,0 +1,177 @@
+package mxj
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestJsonReaderValues(t *testing.T) {
+	fmt.Println("\n================== TestJsonReaderValues")
+	data := `{"a":"x\\"} {"b" : [1, {"c":"}]"}]}{"d":"\"{"}
+[{"e":1}, {"f":2}]
+42
+"text"
+[]
+[true,null]`
+	want := []string{
+		`{"a":"x\\"}`,
+		`{"b" : [1, {"c":"}]"}]}`,
+		`{"d":"\"{"}`,
+		`{"e":1}`,
+		`{"f":2}`,
+		`42`,
+		`"text"`,
+		`true`,
+		`null`,
+	}
+	jr := NewJsonReader(strings.NewReader(data))
+	var got []string
+	for {
+		m, raw, err := NewMapJsonReaderRaw(jr)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Println(string(raw), m)
+		got = append(got, string(raw))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestJsonReaderScalar(t *testing.T) {
+	fmt.Println("\n================== TestJsonReaderScalar")
+	jr := NewJsonReader(strings.NewReader("1.5\n\"s\"\n"))
+	m, err := NewMapJsonReader(jr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["object"] != 1.5 {
+		t.Fatal("m:", m)
+	}
+	m, err = NewMapJsonReader(jr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["object"] != "s" {
+		t.Fatal("m:", m)
+	}
+	if _, err = NewMapJsonReader(jr); err != io.EOF {
+		t.Fatal("expected io.EOF, got:", err)
+	}
+}
+
+func TestNewMapJsonReaderArray(t *testing.T) {
+	fmt.Println("\n================== TestNewMapJsonReaderArray")
+	// without a JsonReader the members of an array of objects are returned one at
+	// a time, as before JsonReader; other arrays are a single value
+	data := `[{"a":1},{"b":2}] {"c":3} [ 1, [2] ] [ {"d":4} ]`
+	want := []string{`{"a":1}`, `{"b":2}`, `{"c":3}`, `[ 1, [2] ]`, `{"d":4}`}
+	plain := struct{ io.Reader }{strings.NewReader(data)} // not an io.ByteReader
+	for _, r := range []io.Reader{strings.NewReader(data), bytes.NewBufferString(data), plain} {
+		var got []string
+		for {
+			m, raw, err := NewMapJsonReaderRaw(r)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			fmt.Println(string(raw), m)
+			got = append(got, string(raw))
+		}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Fatalf("%T got: %v", r, got)
+		}
+	}
+
+	r := strings.NewReader(`[{"a":1},{"a":2}]`)
+	for _, v := range []float64{1, 2} {
+		m, err := NewMapJsonReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m["a"] != v {
+			t.Fatal("m:", m)
+		}
+	}
+	if _, err := NewMapJsonReader(r); err != io.EOF {
+		t.Fatal("expected io.EOF, got:", err)
+	}
+
+	// no more than the value is read
+	b := bytes.NewBufferString(`{"a":1} {"c":3}`)
+	if _, err := NewMapJsonReader(b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != ` {"c":3}` {
+		t.Fatal("read too much:", b.String())
+	}
+}
+
+func TestJsonReaderErrors(t *testing.T) {
+	fmt.Println("\n================== TestJsonReaderErrors")
+	for _, s := range []string{`{"a":1`, `{"a":[1}`, `}`, `[{"a":1}`, `{"a":"1}`} {
+		jr := NewJsonReader(strings.NewReader(s))
+		var err error
+		for err == nil {
+			_, err = NewMapJsonReader(jr)
+		}
+		if err == io.EOF {
+			t.Fatal("no error for:", s)
+		}
+		fmt.Println(s, "-", err)
+	}
+}
+
+func TestHandleJsonReaderRawArray(t *testing.T) {
+	fmt.Println("\n================== TestHandleJsonReaderRawArray")
+	var raws []string
+	err := HandleJsonReaderRaw(strings.NewReader(`[ {"a": 1}, {"b": 2} ]`),
+		func(m Map, raw []byte) bool {
+			raws = append(raws, string(raw))
+			return true
+		},
+		func(err error, raw []byte) bool {
+			t.Fatal(err)
+			return false
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(raws, "|") != `{"a": 1}|{"b": 2}` {
+		t.Fatal("raws:", raws)
+	}
+}
+
+func TestHandleJsonReaderRest(t *testing.T) {
+	fmt.Println("\n================== TestHandleJsonReaderRest")
+	// with a JsonReader the bytes past the last value handled can be read
+	jr := NewJsonReader(strings.NewReader(`{"a":1} {"b":2} tail`))
+	err := HandleJsonReader(jr,
+		func(m Map) bool {
+			return false
+		},
+		func(err error) bool {
+			t.Fatal(err)
+			return false
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(jr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != ` {"b":2} tail` {
+		t.Fatalf("rest: %q", b)
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// jsonseq.go - JSON to/from MapSeq values keeping the order of object members.
+// The member order is recorded with "#seq" keys as NewMapXmlSeq() does for elements.
+
+package mxj
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+)
+
+// NewMapJsonSeq converts a JSON object into a MapSeq value, recording the order
+// of the object members.
+//
+//	The MapSeq value has the same layout as a NewMapXmlSeq() value:
+//	   {"a":1, "b":{"c":"x"}, "d":[true, {"e":null}]}
+//	decodes to:
+//	   map["a"]map["#text"]1, ["#seq"]0
+//	      ["b"]map["c"]map["#text"]"x", ["#seq"]0
+//	              ["#seq"]1
+//	      ["d"][]interface{}{map["#text"]true, ["#seq"]2, map["e"]map["#text"]nil, ["#seq"]0
+//	                                                      ["#seq"]3}
+//	so mv.Json() re-encodes the members in the original order and a value decoded from
+//	XML can be converted to JSON in document order.
+//
+//	NOTES:
+//	1. As for NewMapJson(), a top-level array or scalar value is given the key "object".
+//	2. "#text" members of objects with other members and the members of "#procinst"
+//	   objects are not wrapped; "#attr" members are numbered separately.  This matches
+//	   NewMapXmlSeq() values so that msv.Xml() can encode the MapSeq value.
+//	3. If JsonUseNumber is 'true', numbers are decoded as json.Number values.
+func NewMapJsonSeq(jsonVal []byte) (MapSeq, error) {
+	// empty or nil begets empty
+	if len(bytes.TrimSpace(jsonVal)) == 0 {
+		return make(MapSeq, 0), nil
+	}
+	if b := bytes.TrimLeft(jsonVal, " \t\r\n"); b[0] != '{' {
+		jsonVal = []byte(`{"object":` + string(jsonVal) + `}`)
+	}
+	dec := json.NewDecoder(bytes.NewReader(jsonVal))
+	if JsonUseNumber {
+		dec.UseNumber()
+	}
+	if _, err := dec.Token(); err != nil { // the '{'
+		return nil, err
+	}
+	m, err := jsonSeqObject(dec, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after the JSON object")
+	}
+	return MapSeq(m), nil
+}
+
+// NewMapJsonSeqReader gets the next JSON value from an io.Reader as a MapSeq value.
+// See NewMapJsonReader() for the handling of the io.Reader.
+func NewMapJsonSeqReader(jsonReader io.Reader) (MapSeq, error) {
+	jb, err := getJson(jsonReader)
+	if err != nil || len(jb) == 0 {
+		return nil, err
+	}
+	return NewMapJsonSeq(jb)
+}
+
+// jsonSeqObject decodes the members of an object; the '{' has been read.
+// If 'raw' is 'true', scalar members aren't wrapped and no "#seq" keys are added.
+func jsonSeqObject(dec *json.Decoder, raw bool) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	var seq int
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := t.(string)
+		switch {
+		case raw || key == textK:
+			if m[key], err = jsonSeqValue(dec, key, true, false); err != nil {
+				return nil, err
+			}
+			continue
+		case key == attrK:
+			// attributes are numbered separately, as with NewMapXmlSeq()
+			t, err = dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if t != json.Delim('{') {
+				return nil, errors.New("the " + attrK + " value is not an object")
+			}
+			if m[key], err = jsonSeqObject(dec, false); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		v, err := jsonSeqValue(dec, key, false, false)
+		if err != nil {
+			return nil, err
+		}
+		if vv, ok := v.([]interface{}); ok && len(vv) == 0 {
+			v = map[string]interface{}{textK: vv} // so it has a "#seq" key
+		}
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			vv[seqK] = seq
+			seq++
+		case []interface{}:
+			// each list member has its own "#seq" value, as with NewMapXmlSeq()
+			for _, e := range vv {
+				e.(map[string]interface{})[seqK] = seq
+				seq++
+			}
+		}
+		m[key] = v
+	}
+	if _, err := dec.Token(); err != nil { // the '}'
+		return nil, err
+	}
+	return m, nil
+}
+
+// jsonSeqValue decodes the next value for member 'key'. Unless 'raw' is 'true',
+// scalars - and arrays that are array members, 'inList' - are wrapped as map["#text"]value.
+func jsonSeqValue(dec *json.Decoder, key string, raw, inList bool) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		return jsonSeqObject(dec, key == procinstK)
+	case json.Delim('['):
+		a := make([]interface{}, 0)
+		for dec.More() {
+			v, err := jsonSeqValue(dec, key, false, true)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		if _, err := dec.Token(); err != nil { // the ']'
+			return nil, err
+		}
+		if raw || !inList {
+			return a, nil
+		}
+		return map[string]interface{}{textK: a}, nil
+	}
+	if raw {
+		return t, nil
+	}
+	return map[string]interface{}{textK: t}, nil
+}
+
+// ------------------------------ encode JSON ----------------------------------
+
+// Json encodes a MapSeq value as JSON with the object members in "#seq" order.
+// The "#seq" keys are not encoded and map["#text"]value is encoded as the value.
+// Members without a "#seq" key - e.g., "#text" with other members - are encoded
+// first and "#attr" is encoded as an object of the attribute values.
+// If 'safeEncoding' is 'true', then "safe" encoding of '<', '>' and '&' is preserved.
+func (mv MapSeq) Json(safeEncoding ...bool) ([]byte, error) {
+	var s bool
+	if len(safeEncoding) == 1 {
+		s = safeEncoding[0]
+	}
+
+	var b bytes.Buffer
+	err := writeJsonSeqObject(&b, map[string]interface{}(mv))
+	if err != nil {
+		return nil, err
+	}
+	if !s {
+		return unsafeJson(b.Bytes()), nil
+	}
+	return b.Bytes(), nil
+}
+
+// JsonIndent is mv.Json() with indentation - see json.MarshalIndent.
+func (mv MapSeq) JsonIndent(prefix, indent string, safeEncoding ...bool) ([]byte, error) {
+	b, err := mv.Json(safeEncoding...)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err = json.Indent(&out, b, prefix, indent); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// JsonWriter writes the MapSeq value as JSON on the Writer.
+// If 'safeEncoding' is 'true', then "safe" encoding of '<', '>' and '&' is preserved.
+func (mv MapSeq) JsonWriter(jsonWriter io.Writer, safeEncoding ...bool) error {
+	b, err := mv.Json(safeEncoding...)
+	if err != nil {
+		return err
+	}
+
+	_, err = jsonWriter.Write(b)
+	return err
+}
+
+// JsonIndentWriter writes the MapSeq value as pretty JSON on the Writer.
+// If 'safeEncoding' is 'true', then "safe" encoding of '<', '>' and '&' is preserved.
+func (mv MapSeq) JsonIndentWriter(jsonWriter io.Writer, prefix, indent string, safeEncoding ...bool) error {
+	b, err := mv.JsonIndent(prefix, indent, safeEncoding...)
+	if err != nil {
+		return err
+	}
+
+	_, err = jsonWriter.Write(b)
+	return err
+}
+
+// unsafeJson undoes the json.Marshal escaping of '<', '>' and '&'.
+func unsafeJson(b []byte) []byte {
+	b = bytes.Replace(b, []byte("\\u003c"), []byte("<"), -1)
+	b = bytes.Replace(b, []byte("\\u003e"), []byte(">"), -1)
+	b = bytes.Replace(b, []byte("\\u0026"), []byte("&"), -1)
+	return b
+}
+
+// jsonSeqOrder returns the position of a member value: its "#seq" value or, for a
+// list, the smallest "#seq" value of the list members.  Values without a
+// "#seq" key are first.
+func jsonSeqOrder(v interface{}) int {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		switch n := vv[seqK].(type) {
+		case int:
+			return n
+		case float64:
+			return int(n)
+		}
+	case []interface{}:
+		order := -1
+		for _, e := range vv {
+			if n := jsonSeqOrder(e); order < 0 || (n >= 0 && n < order) {
+				order = n
+			}
+		}
+		return order
+	}
+	return -1
+}
+
+// isJsonSeqText reports whether the map is a wrapped value - only "#text",
+// "#seq" and "#cdata" keys.
+func isJsonSeqText(m map[string]interface{}) bool {
+	if _, ok := m[textK]; !ok {
+		return false
+	}
+	for k := range m {
+		if k != textK && k != seqK && k != cdataK {
+			return false
+		}
+	}
+	return true
+}
+
+func writeJsonSeqObject(b *bytes.Buffer, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k == seqK || k == cdataK {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // so members without "#seq" keys are in a consistent order
+	sort.SliceStable(keys, func(i, j int) bool {
+		return jsonSeqOrder(m[keys[i]]) < jsonSeqOrder(m[keys[j]])
+	})
+
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, _ := json.Marshal(k)
+		b.Write(kb)
+		b.WriteByte(':')
+		if err := writeJsonSeqValue(b, m[k]); err != nil {
+			return err
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+func writeJsonSeqValue(b *bytes.Buffer, v interface{}) error {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if isJsonSeqText(vv) {
+			return writeJsonSeqValue(b, vv[textK])
+		}
+		return writeJsonSeqObject(b, vv)
+	case MapSeq:
+		return writeJsonSeqObject(b, map[string]interface{}(vv))
+	case []interface{}:
+		b.WriteByte('[')
+		for i, e := range vv {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJsonSeqValue(b, e); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+		return nil
+	}
+	vb, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b.Write(vb)
+	return nil
+}
@@ -0,0 +1,89 @@
+package mxj
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewMapJsonSeq(t *testing.T) {
+	fmt.Println("\n================== TestNewMapJsonSeq")
+	data := `{"z":1,"b":{"y":"x","a":[3,{"q":null,"c":true}],"m":[]},"a":"<&>"}`
+	m, err := NewMapJsonSeq([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(m.StringIndent())
+	j, err := m.Json()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(j))
+	if string(j) != data {
+		t.Fatalf("got:  %s\nwant: %s", j, data)
+	}
+
+	j, err = m.JsonIndent("", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(j))
+	if !strings.Contains(string(j), `"a": "<&>"`) {
+		t.Fatal("safe encoding:", string(j))
+	}
+	if strings.Index(string(j), `"z"`) > strings.Index(string(j), `"b"`) {
+		t.Fatal("order lost:", string(j))
+	}
+}
+
+func TestNewMapJsonSeqScalar(t *testing.T) {
+	fmt.Println("\n================== TestNewMapJsonSeqScalar")
+	for _, data := range []string{`[2,1]`, `"s"`, `7`} {
+		m, err := NewMapJsonSeq([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		j, err := m.Json()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(j) != `{"object":`+data+`}` {
+			t.Fatal(data, "got:", string(j))
+		}
+	}
+	if _, err := NewMapJsonSeq([]byte(`{"a":1} x`)); err == nil {
+		t.Fatal("no error for trailing data")
+	}
+}
+
+func TestXmlSeqToJson(t *testing.T) {
+	fmt.Println("\n================== TestXmlSeqToJson")
+	x := `<doc z="1" a="2"><title>t</title><item>1</item><note>n</note><item>2</item><empty/></doc>`
+	m, err := NewMapXmlSeq([]byte(x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := m.Json()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(j))
+	want := `{"doc":{"` + attrK + `":{"z":"1","a":"2"},"title":"t","item":["1","2"],"note":"n","empty":""}}`
+	if string(j) != want {
+		t.Fatalf("got:  %s\nwant: %s", j, want)
+	}
+
+	// and back to XML
+	mm, err := NewMapJsonSeq(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xx, err := mm.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(xx))
+	if string(xx) != `<doc z="1" a="2"><title>t</title><item>1</item><item>2</item><note>n</note><empty/></doc>` {
+		t.Fatal("xml:", string(xx))
+	}
+}
@@ -156,6 +156,15 @@ func hasKey(iv interface{}, key string, ret *[]interface{}, cnt *int, subkeys ma
 //          - If a node in the path is '*', then everything beyond is walked.
 //          - 'path' can contain indexed array references, such as, "*.data[1]" and "msgs[2].data[0].field" -
 //            even "*[2].*[0].field".
+//          - Negative indexes count from the end of the list - "entry[-1]" is the last entry - and
+//            "entry[2:5]", "entry[-2:]" and "entry[::2]" are slices, as for Python lists.  A value
+//            that isn't in a list is handled as a list of one member - "entry[-1]" is the entry.
+//          - "..key" is 'key' at any depth - "doc..id" is all the "id" values in "doc".
+//          - 'path' can contain predicates that select list members at any depth, such as,
+//            "books.book[author='Gaddis'].title", "items.item[price>10]", "x[-id=~'^A']" and
+//            "a[?exists(b)]".  The operators are =, !=, <, <=, >, >=, =~ and !~ (regexp);
+//            a leading '!' negates the predicate.  Indexes and predicates can be chained -
+//            "book[price<10][0]".  (See parsePredicate in pathpredicates.go.)
 //   'subkeys' (optional) are "key:val[:type]" strings representing attributes or elements in a list.
 //             - By default 'val' is of type string. "key:val:bool" and "key:val:float" to coerce them.
 //             - For attributes prefix the label with the attribute prefix character, by default a 
@@ -166,8 +175,8 @@ func hasKey(iv interface{}, key string, ret *[]interface{}, cnt *int, subkeys ma
 //               exclusion critera - e.g., "!author:William T. Gaddis".
 //             - If val contains ":" symbol, use SetFieldSeparator to a unused symbol, perhaps "|".
 func (mv Map) ValuesForPath(path string, subkeys ...string) ([]interface{}, error) {
-	// If there are no array indexes in path, use legacy ValuesForPath() logic.
-	if strings.Index(path, "[") < 0 {
+	// If there are no array indexes or recursive descents in path, use legacy ValuesForPath() logic.
+	if strings.Index(path, "[") < 0 && strings.Index(path, "..") < 0 {
 		return mv.oldValuesForPath(path, subkeys...)
 	}
 
@@ -208,91 +217,112 @@ func valuesForArray(keys []*key, m Map) ([]interface{}, error) {
 
 	lastkey := len(keys) - 1
 	for i := 0; i <= lastkey; i++ {
-		if !haveFirst {
-			tmppath = keys[i].name
-			haveFirst = true
+		if keys[i].descend {
+			// "..key" - the values for 'key' at any depth.  The look-ahead, below, makes
+			// it the first key - we recurse for each value of the path preceding it.
+			// As for "a.key[0]", the selectors apply to the value(s) of each 'key'.
+			vals = nil
+			for _, v := range descendantValues(map[string]interface{}(m), keys[i].name) {
+				vals = append(vals, keys[i].selectValues(v)...)
+			}
 		} else {
-			tmppath += "." + keys[i].name
-		}
-
-		// Look-ahead: explode wildcards and unindexed arrays.
-		// Need to handle un-indexed list recursively:
-		// e.g., path is "stuff.data[0]" rather than "stuff[0].data[0]".
-		// Need to treat it as "stuff[0].data[0]", "stuff[1].data[0]", ...
-		if !keys[i].isArray && i < lastkey && keys[i+1].isArray {
-			// Can't pass subkeys because we may not be at literal end of path.
-			vv, vverr := m.oldValuesForPath(tmppath)
-			if vverr != nil {
-				return nil, vverr
+			if !haveFirst {
+				tmppath = keys[i].name
+				haveFirst = true
+			} else {
+				tmppath += "." + keys[i].name
 			}
-			for _, v := range vv {
-				// See if we can walk the value.
-				am, ok := v.(map[string]interface{})
-				if !ok {
-					continue
+
+			// Look-ahead: explode wildcards and unindexed arrays.
+			// Need to handle un-indexed list recursively:
+			// e.g., path is "stuff.data[0]" rather than "stuff[0].data[0]".
+			// Need to treat it as "stuff[0].data[0]", "stuff[1].data[0]", ...
+			if len(keys[i].sels) == 0 && i < lastkey && (len(keys[i+1].sels) > 0 || keys[i+1].descend) {
+				// Can't pass subkeys because we may not be at literal end of path.
+				vv, vverr := m.oldValuesForPath(tmppath)
+				if vverr != nil {
+					return nil, vverr
 				}
-				// Work the backend.
-				nvals, nvalserr := valuesForArray(keys[i+1:], Map(am))
-				if nvalserr != nil {
-					return nil, nvalserr
+				for _, v := range vv {
+					// See if we can walk the value.
+					am, ok := v.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					// Work the backend.
+					nvals, nvalserr := valuesForArray(keys[i+1:], Map(am))
+					if nvalserr != nil {
+						return nil, nvalserr
+					}
+					vals = append(vals, nvals...)
 				}
-				vals = append(vals, nvals...)
+				break // have recursed the whole path - return
 			}
-			break // have recursed the whole path - return
-		}
 
-		if keys[i].isArray || i == lastkey {
-			// Don't pass subkeys because may not be at literal end of path.
-			vals, verr = m.oldValuesForPath(tmppath)
-		} else {
-			continue
-		}
-		if verr != nil {
-			return nil, verr
-		}
-
-		if i == lastkey && !keys[i].isArray {
-			break
-		}
-
-		// Now we're looking at an array - supposedly.
-		// Is index in range of vals?
-		if len(vals) <= keys[i].position {
-			vals = nil
-			break
+			if len(keys[i].sels) > 0 || i == lastkey {
+				// Don't pass subkeys because may not be at literal end of path.
+				vals, verr = m.oldValuesForPath(tmppath)
+			} else {
+				continue
+			}
+			if verr != nil {
+				return nil, verr
+			}
+			// Now we're looking at an array - supposedly.
+			vals = keys[i].selectValues(vals)
 		}
 
-		// Return the array member of interest, if at end of path.
+		// Return the array members of interest, if at end of path.
 		if i == lastkey {
-			vals = vals[keys[i].position:(keys[i].position + 1)]
 			break
 		}
 
-		// Extract the array member of interest.
-		am := vals[keys[i].position:(keys[i].position + 1)]
-
-		// must be a map[string]interface{} value so we can keep walking the path
-		amm, ok := am[0].(map[string]interface{})
-		if !ok {
-			vals = nil
-			break
+		// Walk the rest of the path for each member of interest -
+		// must be a map[string]interface{} value so we can keep walking the path.
+		members := vals
+		vals = nil
+		for _, v := range members {
+			amm, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			nvals, nvalserr := valuesForArray(keys[i+1:], Map(amm))
+			if nvalserr != nil {
+				return nil, nvalserr
+			}
+			vals = append(vals, nvals...)
 		}
-
-		m = Map(amm)
-		haveFirst = false
+		break
 	}
 
 	return vals, nil
 }
 
 type key struct {
-	name     string
-	isArray  bool
-	position int
+	name    string
+	descend bool          // "..name" - at any depth
+	sels    []keySelector // "[N]" indexes, "[N:M]" slices and "[...]" predicates, in path order
+}
+
+// selectValues applies the indexes, slices and predicates to 'vals', in order.
+// A list selected by an index - "a[0][1]" - is expanded for the next selector.
+func (k *key) selectValues(vals []interface{}) []interface{} {
+	for n, sel := range k.sels {
+		if n > 0 {
+			vals = expandLists(vals)
+		}
+		idx := sel.selectIndexes(vals)
+		sv := make([]interface{}, len(idx))
+		for i, j := range idx {
+			sv[i] = vals[j]
+		}
+		vals = sv
+	}
+	return vals
 }
 
 func parsePath(s string) ([]*key, error) {
-	keys := strings.Split(s, ".")
+	keys := splitPath(s)
 
 	ret := make([]*key, 0)
 
@@ -302,31 +332,78 @@ func parsePath(s string) ([]*key, error) {
 		}
 
 		newkey := new(key)
-		if strings.Index(keys[i], "[") < 0 {
+		// "a..b" and "..b" - an empty key that isn't the first
+		newkey.descend = i > 1 && keys[i-1] == ""
+		n := strings.Index(keys[i], "[")
+		if n < 0 {
 			newkey.name = keys[i]
 			ret = append(ret, newkey)
 			continue
 		}
+		newkey.name = keys[i][:n]
 
-		p := strings.Split(keys[i], "[")
-		newkey.name = p[0]
-		p = strings.Split(p[1], "]")
-		if p[0] == "" { // no right bracket
-			return nil, fmt.Errorf("no right bracket on key index: %s", keys[i])
-		}
-		// convert p[0] to a int value
-		pos, nerr := strconv.ParseInt(p[0], 10, 32)
-		if nerr != nil {
-			return nil, fmt.Errorf("cannot convert index to int value: %s", p[0])
+		// one or more "[...]" selectors
+		for rest := keys[i][n:]; len(rest) > 0 && rest[0] == '['; {
+			end := selectorEnd(rest)
+			if end < 0 || end == 1 { // no right bracket
+				return nil, fmt.Errorf("no right bracket on key index: %s", keys[i])
+			}
+			sel := rest[1:end]
+			rest = rest[end+1:]
+			if !strings.ContainsAny(sel, "=<>!~?") {
+				if strings.Index(sel, ":") >= 0 {
+					slice, serr := parseSlice(sel)
+					if serr != nil {
+						return nil, serr
+					}
+					newkey.sels = append(newkey.sels, slice)
+					continue
+				}
+				// convert sel to a int value
+				pos, nerr := strconv.ParseInt(sel, 10, 32)
+				if nerr != nil {
+					return nil, fmt.Errorf("cannot convert index to int value: %s", sel)
+				}
+				newkey.sels = append(newkey.sels, keyIndex(pos))
+				continue
+			}
+			pred, perr := parsePredicate(sel)
+			if perr != nil {
+				return nil, perr
+			}
+			newkey.sels = append(newkey.sels, pred)
 		}
-		newkey.position = int(pos)
-		newkey.isArray = true
 		ret = append(ret, newkey)
 	}
 
 	return ret, nil
 }
 
+// selectorEnd returns the index of the "]" that closes the "[" at s[0], or -1.
+func selectorEnd(s string) int {
+	var quote byte
+	var depth int
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
 // legacy ValuesForPath() - now wrapped to handle special case of indexed arrays in 'path'.
 func (mv Map) oldValuesForPath(path string, subkeys ...string) ([]interface{}, error) {
 	m := map[string]interface{}(mv)
@@ -0,0 +1,285 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// merge.go - deep merge of Map values, with per-path strategies for conflicts.
+
+package mxj
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MergeStrategy is how a value of the Map being merged is combined with the
+// value for the same key in the Map it's merged into.
+type MergeStrategy string
+
+const (
+	MergeOverride MergeStrategy = "override" // the value replaces the existing value
+	MergeKeep     MergeStrategy = "keep"     // the existing value is kept
+	MergeAppend   MergeStrategy = "append"   // the value is appended to the existing value as a list
+	MergeUnion    MergeStrategy = "union"    // as for MergeAppend, but values that are in the list already are skipped; see MergeUnionBy()
+)
+
+// MergeUnionBy returns the MergeStrategy for the union of lists of maps that are
+// identified by the value of 'key' - e.g., MergeUnionBy("-id") or MergeUnionBy("sku").
+// Members with the same 'key' value are merged; the others are appended.
+func MergeUnionBy(key string) MergeStrategy {
+	return MergeStrategy(string(MergeUnion) + ":" + key)
+}
+
+// MergeOptions sets the strategies for mv.Merge().  The zero value overrides
+// existing values and appends on list conflicts.
+//
+//	opts := &mxj.MergeOptions{
+//	   Paths: map[string]mxj.MergeStrategy{
+//	      "doc.-version":       mxj.MergeKeep,
+//	      "doc.items.item":     mxj.MergeUnionBy("-sku"),
+//	      "doc.*.note":         mxj.MergeAppend,
+//	   },
+//	}
+//	err := defaults.Merge(override, opts)
+type MergeOptions struct {
+	// Strategy is for the values that no Paths entry matches; MergeOverride if "".
+	// Two maps are merged key by key, so it applies to the simple values and lists
+	// in them - MergeAppend doesn't make a list of the root maps.
+	Strategy MergeStrategy
+	// Paths sets the strategy for the values at the paths.  As for CastSchema the paths
+	// are dot-separated keys starting at the root and "*" matches any key at that level;
+	// if more than one path matches a value, the one with the fewest wildcards is used.
+	// The members of a list have the path of the list.  A Paths strategy applies to two
+	// maps, too - "doc.items.item": MergeAppend lists a single "item" from each doc.
+	// The strategy for a map value applies to the values in it that no path matches,
+	// as for 'Strategy' - except for the members that MergeUnionBy() merges, which get
+	// 'Strategy'.
+	Paths map[string]MergeStrategy
+	// ListConflicts is the strategy when one value is a list and the other isn't - as
+	// the XML decoder has it when a tag is repeated in one doc only; MergeAppend if "".
+	// It applies if no Paths entry matches the value and the strategy is MergeOverride or
+	// MergeKeep; MergeAppend and MergeUnion take a value that isn't a list as a list of one.
+	ListConflicts MergeStrategy
+}
+
+// Merge merges 'other' into the Map - the maps in both are merged key by key, and
+// for the same key with other values the MergeOptions strategy is applied.
+// The values from 'other' are copied - the Map doesn't share maps and lists with 'other'.
+//
+//	NOTES:
+//	   1. A simple value and a map - an element with attributes, or a MapSeq element - are
+//	      merged as if the simple value were the "#text" value of a map; so merging
+//	      "12" and {"-currency":"USD", "#text":"15"} gives {"-currency":"USD", "#text":"12"}
+//	      or {"-currency":"USD", "#text":"15"}, not a list or the bare value.
+//	   2. Attribute values - keys with the attribute prefix (see SetAttrPrefix()) - and
+//	      "#text" and "#seq" values are never lists; with MergeAppend and MergeUnion the
+//	      value from 'other' replaces the existing one.
+func (mv Map) Merge(other Map, opts ...*MergeOptions) error {
+	o := &MergeOptions{}
+	if len(opts) == 1 && opts[0] != nil {
+		o = opts[0]
+	}
+	mo := &merger{def: o.Strategy, conflicts: o.ListConflicts}
+	if mo.def == "" {
+		mo.def = MergeOverride
+	}
+	if mo.conflicts == "" {
+		mo.conflicts = MergeAppend
+	}
+	if err := checkMergeStrategy(mo.def); err != nil {
+		return err
+	}
+	if err := checkMergeStrategy(mo.conflicts); err != nil {
+		return err
+	}
+	for path, s := range o.Paths {
+		if err := checkMergeStrategy(s); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		keys := strings.Split(path, ".")
+		var wild int
+		for _, k := range keys {
+			if k == "*" {
+				wild++
+			}
+		}
+		mo.rules = append(mo.rules, mergeRule{keys, wild, s})
+	}
+	sort.Slice(mo.rules, func(i, j int) bool {
+		if mo.rules[i].wild != mo.rules[j].wild {
+			return mo.rules[i].wild < mo.rules[j].wild
+		}
+		return strings.Join(mo.rules[i].keys, ".") < strings.Join(mo.rules[j].keys, ".")
+	})
+
+	mo.mergeMaps(map[string]interface{}(mv), map[string]interface{}(other), nil, mo.def)
+	return nil
+}
+
+func checkMergeStrategy(s MergeStrategy) error {
+	switch s {
+	case MergeOverride, MergeKeep, MergeAppend, MergeUnion:
+		return nil
+	}
+	if strings.HasPrefix(string(s), string(MergeUnion)+":") && len(s) > len(MergeUnion)+1 {
+		return nil
+	}
+	return fmt.Errorf("unknown merge strategy: %q", s)
+}
+
+// mergeRule is a MergeOptions.Paths entry with the path split into keys.
+type mergeRule struct {
+	keys     []string
+	wild     int
+	strategy MergeStrategy
+}
+
+type merger struct {
+	def       MergeStrategy
+	conflicts MergeStrategy
+	rules     []mergeRule
+}
+
+// strategy returns the strategy for the value at 'path' and whether a rule
+// matched - 'inherited' if no rule matches.
+func (mo *merger) strategy(path []string, inherited MergeStrategy) (MergeStrategy, bool) {
+	for _, r := range mo.rules {
+		if matchPath(r.keys, path) {
+			return r.strategy, true
+		}
+	}
+	return inherited, false
+}
+
+// matchPath reports whether the rule 'keys' - with "*" wildcards - match 'path'.
+func matchPath(keys, path []string) bool {
+	if len(keys) != len(path) {
+		return false
+	}
+	for i, k := range path {
+		if keys[i] != "*" && keys[i] != k {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeMaps merges 'src' into 'dst', which is at 'path' and merged with strategy 's'.
+func (mo *merger) mergeMaps(dst, src map[string]interface{}, path []string, s MergeStrategy) {
+	for _, k := range sortedKeys(src) {
+		sv := src[k]
+		dv, ok := dst[k]
+		if !ok {
+			dst[k] = deepCopy(sv)
+			continue
+		}
+		p := make([]string, len(path)+1)
+		copy(p, path)
+		p[len(path)] = k
+		dst[k] = mo.mergeValues(dv, sv, p, s)
+	}
+}
+
+// mergeValues returns the merge of the values at 'path'.
+func (mo *merger) mergeValues(dv, sv interface{}, path []string, inherited MergeStrategy) interface{} {
+	s, matched := mo.strategy(path, inherited)
+	key := path[len(path)-1]
+	if key == textK || key == seqK || attrPrefix != "" && strings.HasPrefix(key, attrPrefix) {
+		if s == MergeKeep {
+			return dv
+		}
+		if _, ok := sv.(map[string]interface{}); !ok {
+			return deepCopy(sv)
+		}
+	}
+
+	_, dList := dv.([]interface{})
+	_, sList := sv.([]interface{})
+	// MergeAppend and MergeUnion take a value that isn't a list as a list of one
+	if dList != sList && !matched && (s == MergeOverride || s == MergeKeep) {
+		s = mo.conflicts
+	}
+	// unless a Paths entry says otherwise, two maps are merged key by key
+	dm, dMap := dv.(map[string]interface{})
+	sm, sMap := sv.(map[string]interface{})
+	if dMap && sMap && !matched {
+		mo.mergeMaps(dm, sm, path, s)
+		return dm
+	}
+
+	switch s {
+	case MergeAppend:
+		return append(mergeList(dv), mergeList(deepCopy(sv))...)
+	case MergeKeep, MergeOverride:
+		if dList || sList {
+			if s == MergeKeep {
+				return dv
+			}
+			return deepCopy(sv)
+		}
+		switch {
+		case dMap && sMap:
+		case dMap:
+			sm = map[string]interface{}{textK: sv}
+		case sMap:
+			dm = map[string]interface{}{textK: dv}
+		case s == MergeKeep:
+			return dv
+		default:
+			return deepCopy(sv)
+		}
+		mo.mergeMaps(dm, sm, path, s)
+		return dm
+	}
+
+	// MergeUnion, by value or key
+	var ukey string
+	if i := strings.Index(string(s), ":"); i > 0 {
+		ukey = string(s)[i+1:]
+	}
+	a := mergeList(dv)
+	for _, v := range mergeList(sv) {
+		var found bool
+		for i, m := range a {
+			if ukey == "" {
+				if found = reflect.DeepEqual(m, v); found {
+					break
+				}
+				continue
+			}
+			mm, ok := m.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			vm, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			mk, ok1 := mm[ukey]
+			vk, ok2 := vm[ukey]
+			if !ok1 || !ok2 || fmt.Sprint(textValue(mk)) != fmt.Sprint(textValue(vk)) {
+				continue
+			}
+			mo.mergeMaps(mm, vm, path, mo.def)
+			a[i], found = mm, true
+			break
+		}
+		if !found {
+			a = append(a, deepCopy(v))
+		}
+	}
+	if len(a) == 1 && !dList && !sList {
+		return a[0]
+	}
+	return a
+}
+
+// mergeList returns the value as a list - a list that can be appended to
+// without changing 'v'.
+func mergeList(v interface{}) []interface{} {
+	if a, ok := v.([]interface{}); ok {
+		return append([]interface{}(nil), a...)
+	}
+	return []interface{}{v}
+}
@@ -0,0 +1,258 @@
+package mxj
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var mergeDefaults = []byte(`<config version="1">
+	<server><host>localhost</host><port>80</port></server>
+	<timeout unit="s">30</timeout>
+	<users>
+		<user id="u1"><name>ann</name><role>admin</role></user>
+		<user id="u2"><name>bob</name></user>
+	</users>
+	<tags><tag>a</tag><tag>b</tag></tags>
+	<note>default</note>
+</config>`)
+
+var mergeOverride = []byte(`<config version="2">
+	<server><port>8080</port><tls>true</tls></server>
+	<timeout>60</timeout>
+	<users>
+		<user id="u2"><name>bob</name><role>dev</role></user>
+		<user id="u3"><name>cy</name></user>
+	</users>
+	<tags><tag>b</tag><tag>c</tag></tags>
+	<note>local</note>
+</config>`)
+
+func TestMerge(t *testing.T) {
+	fmt.Println("\n================== TestMerge")
+	ap := attrPrefix
+	m, err := NewMapXml(mergeDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewMapXml(mergeOverride)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &MergeOptions{
+		Paths: map[string]MergeStrategy{
+			"config." + ap + "version": MergeKeep,
+			"config.users.user":        MergeUnionBy(ap + "id"),
+			"config.tags.tag":          MergeUnion,
+			"config.*.note":            MergeKeep, // no match
+			"config.note":              MergeAppend,
+		},
+	}
+	if err = m.Merge(o, opts); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(m.StringIndentNoTypeInfo())
+
+	checks := map[string]interface{}{
+		"config." + ap + "version":          "1",
+		"config.server.host":                "localhost",
+		"config.server.port":                "8080",
+		"config.server.tls":                 "true",
+		"config.timeout." + textK:           "60", // "#text" set, attribute kept
+		"config.timeout." + ap + "unit":     "s",
+		"config.users.user[0].role":         "admin",
+		"config.users.user[1].role":         "dev",
+		"config.users.user[2]." + ap + "id": "u3",
+	}
+	for path, want := range checks {
+		if v, err := m.ValueForPath(path); err != nil || v != want {
+			t.Errorf("%s: got %v, %v, want %v", path, v, err, want)
+		}
+	}
+	if v, _ := m.ValuesForPath("config.users.user"); len(v) != 3 {
+		t.Errorf("users: %v", v)
+	}
+	if v, _ := m.ValuesForPath("config.tags.tag"); !reflect.DeepEqual(v, []interface{}{"a", "b", "c"}) {
+		t.Errorf("tags: %v", v)
+	}
+	if v, _ := m.ValuesForPath("config.note"); !reflect.DeepEqual(v, []interface{}{"default", "local"}) {
+		t.Errorf("note: %v", v)
+	}
+
+	// 'other' isn't changed or shared
+	if _, err = m.UpdateValuesForPath("name:BOB", "config.users.user[1]"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := o.ValueForPath("config.users.user[1].name"); v != "cy" {
+		t.Errorf("other: %v", v)
+	}
+	if _, err = m.UpdateValuesForPath("name:CY", "config.users.user[2]"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := o.ValueForPath("config.users.user[1].name"); v != "cy" {
+		t.Errorf("shared: %v", v)
+	}
+
+	if err = m.Merge(o, &MergeOptions{Strategy: "replace"}); err == nil {
+		t.Error("no error for unknown strategy")
+	}
+}
+
+func TestMergeStrategies(t *testing.T) {
+	fmt.Println("\n================== TestMergeStrategies")
+	ap := attrPrefix
+	mk := func() Map {
+		return Map{"doc": map[string]interface{}{
+			"a":     "1",
+			"b":     []interface{}{"x", "y"},
+			"c":     map[string]interface{}{ap + "lang": "en", textK: "hi"},
+			"d":     map[string]interface{}{"e": "2"},
+			"plain": "p",
+		}}
+	}
+	other := Map{"doc": map[string]interface{}{
+		"a":     "9",
+		"b":     "z",
+		"c":     "hello",
+		"d":     map[string]interface{}{"e": "3", "f": "4"},
+		"plain": map[string]interface{}{ap + "id": "7", textK: "q"},
+	}}
+
+	tests := []struct {
+		opts *MergeOptions
+		want map[string]interface{}
+	}{
+		{nil, map[string]interface{}{
+			"a":     "9",
+			"b":     []interface{}{"x", "y", "z"},
+			"c":     map[string]interface{}{ap + "lang": "en", textK: "hello"},
+			"d":     map[string]interface{}{"e": "3", "f": "4"},
+			"plain": map[string]interface{}{ap + "id": "7", textK: "q"},
+		}},
+		{&MergeOptions{Strategy: MergeKeep}, map[string]interface{}{
+			"a":     "1",
+			"b":     []interface{}{"x", "y", "z"},
+			"c":     map[string]interface{}{ap + "lang": "en", textK: "hi"},
+			"d":     map[string]interface{}{"e": "2", "f": "4"},
+			"plain": map[string]interface{}{ap + "id": "7", textK: "p"},
+		}},
+		{&MergeOptions{Strategy: MergeKeep, ListConflicts: MergeOverride}, map[string]interface{}{
+			"a":     "1",
+			"b":     "z",
+			"c":     map[string]interface{}{ap + "lang": "en", textK: "hi"},
+			"d":     map[string]interface{}{"e": "2", "f": "4"},
+			"plain": map[string]interface{}{ap + "id": "7", textK: "p"},
+		}},
+		{&MergeOptions{Paths: map[string]MergeStrategy{"doc.*": MergeAppend}}, map[string]interface{}{
+			"a":     []interface{}{"1", "9"},
+			"b":     []interface{}{"x", "y", "z"},
+			"c":     []interface{}{map[string]interface{}{ap + "lang": "en", textK: "hi"}, "hello"},
+			"d":     []interface{}{map[string]interface{}{"e": "2"}, map[string]interface{}{"e": "3", "f": "4"}},
+			"plain": []interface{}{"p", map[string]interface{}{ap + "id": "7", textK: "q"}},
+		}},
+		{&MergeOptions{Paths: map[string]MergeStrategy{"doc.d": MergeKeep, "doc.d.f": MergeKeep}}, map[string]interface{}{
+			"a":     "9",
+			"b":     []interface{}{"x", "y", "z"},
+			"c":     map[string]interface{}{ap + "lang": "en", textK: "hello"},
+			"d":     map[string]interface{}{"e": "2", "f": "4"},
+			"plain": map[string]interface{}{ap + "id": "7", textK: "q"},
+		}},
+	}
+	for i, tt := range tests {
+		m := mk()
+		if err := m.Merge(other, tt.opts); err != nil {
+			t.Fatal(i, err)
+		}
+		if got := m["doc"]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%d: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestMergeListConflicts(t *testing.T) {
+	fmt.Println("\n================== TestMergeListConflicts")
+	ap := attrPrefix
+	items := []byte(`<doc><items>
+		<item sku="1"><q>1</q></item>
+		<item sku="2"><q>2</q></item>
+		<item sku="3"><q>3</q></item>
+	</items></doc>`)
+	one := []byte(`<doc><items><item sku="2"><q>200</q></item></items></doc>`)
+
+	// a singleton is merged into the list by key - and the other way
+	m, _ := NewMapXml(items)
+	o, _ := NewMapXml(one)
+	opts := &MergeOptions{Paths: map[string]MergeStrategy{"doc.items.item": MergeUnionBy(ap + "sku")}}
+	if err := m.Merge(o, opts); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.ValuesForPath("doc.items.item.q"); !reflect.DeepEqual(v, []interface{}{"1", "200", "3"}) {
+		t.Errorf("union by key: %v", v)
+	}
+	m, _ = NewMapXml(one)
+	o, _ = NewMapXml(items)
+	if err := m.Merge(o, opts); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.ValuesForPath("doc.items.item.q"); !reflect.DeepEqual(v, []interface{}{"2", "1", "3"}) {
+		t.Errorf("union by key, singleton: %v", v)
+	}
+
+	// a Paths override is an override
+	m, _ = NewMapXml(items)
+	o, _ = NewMapXml(one)
+	if err := m.Merge(o, &MergeOptions{Paths: map[string]MergeStrategy{"doc.items.item": MergeOverride}}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.ValuesForPath("doc.items.item.q"); !reflect.DeepEqual(v, []interface{}{"200"}) {
+		t.Errorf("override: %v", v)
+	}
+	// ... but not the default
+	m, _ = NewMapXml(items)
+	if err := m.Merge(o); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.ValuesForPath("doc.items.item.q"); !reflect.DeepEqual(v, []interface{}{"1", "2", "3", "200"}) {
+		t.Errorf("default: %v", v)
+	}
+}
+
+func TestMergeStrategyMaps(t *testing.T) {
+	fmt.Println("\n================== TestMergeStrategyMaps")
+	doc := []byte(`<doc><n>1</n><e><v>a</v></e></doc>`)
+	for _, tt := range []struct {
+		s    MergeStrategy
+		n, v []interface{}
+	}{
+		{MergeAppend, []interface{}{"1", "1"}, []interface{}{"a", "a"}},
+		{MergeUnion, []interface{}{"1"}, []interface{}{"a"}},
+	} {
+		m, _ := NewMapXml(doc)
+		o, _ := NewMapXml(doc)
+		if err := m.Merge(o, &MergeOptions{Strategy: tt.s}); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Println(tt.s, m)
+		// the maps are merged - one root, one "e"
+		if _, ok := m["doc"].(map[string]interface{}); !ok {
+			t.Fatalf("%s: doc: %v", tt.s, m)
+		}
+		if v, _ := m.ValuesForPath("doc.n"); !reflect.DeepEqual(v, tt.n) {
+			t.Errorf("%s: n: %v", tt.s, v)
+		}
+		if v, _ := m.ValuesForPath("doc.e.v"); !reflect.DeepEqual(v, tt.v) {
+			t.Errorf("%s: e.v: %v", tt.s, v)
+		}
+	}
+
+	// a Paths entry for the maps lists them
+	m, _ := NewMapXml(doc)
+	o, _ := NewMapXml(doc)
+	if err := m.Merge(o, &MergeOptions{Strategy: MergeAppend, Paths: map[string]MergeStrategy{"doc.e": MergeAppend}}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.ValuesForPath("doc.e"); len(v) != 2 {
+		t.Errorf("Paths: e: %v", v)
+	}
+}
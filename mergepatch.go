@@ -0,0 +1,102 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// mergepatch.go - JSON Merge Patch (RFC 7396) for Map values.
+
+package mxj
+
+import (
+	"reflect"
+)
+
+// MergePatch applies the JSON Merge Patch 'patch' to the Map as RFC 7396 has it:
+// a nil (JSON null) value removes the key, a map value is merged into the map for
+// the key and any other value - including a list - replaces the value for the key.
+// The values from 'patch' are copied.
+//
+//	'xmlEmptyIsNull' (optional) - if 'true', "" values are null values, too; so
+//	    a patch decoded with NewMapXml() can remove a key with an empty element:
+//	       <doc><price/></doc>
+//	(mv.Xml() writes nil values as empty elements, as well.)
+func (mv Map) MergePatch(patch Map, xmlEmptyIsNull ...bool) {
+	var empty bool
+	if len(xmlEmptyIsNull) == 1 {
+		empty = xmlEmptyIsNull[0]
+	}
+	mergePatch(map[string]interface{}(mv), map[string]interface{}(patch), empty)
+}
+
+// mergePatch applies the patch to the 'target' map and returns it.
+func mergePatch(target, patch map[string]interface{}, empty bool) map[string]interface{} {
+	for k, v := range patch {
+		if mergePatchNull(v, empty) {
+			delete(target, k)
+			continue
+		}
+		pm, ok := v.(map[string]interface{})
+		if !ok {
+			target[k] = deepCopy(v)
+			continue
+		}
+		tm, ok := target[k].(map[string]interface{})
+		if !ok {
+			tm = make(map[string]interface{}, len(pm))
+		}
+		target[k] = mergePatch(tm, pm, empty)
+	}
+	return target
+}
+
+func mergePatchNull(v interface{}, empty bool) bool {
+	return v == nil || empty && v == ""
+}
+
+// CreateMergePatch returns the JSON Merge Patch that makes 'original' into
+// 'modified' - see mv.MergePatch().  Keys that aren't in 'modified' have nil
+// values in the patch; the lists that are different are in the patch whole.
+//
+//	'xmlEmptyIsNull' (optional) - if 'true', "" values are null values: a key with
+//	    a "" value in 'modified' is removed by the patch - it has a nil value.
+//	NOTE: RFC 7396 merge patches can't set null values; a nil value in 'modified' is
+//	      a key that's removed.
+func CreateMergePatch(original, modified Map, xmlEmptyIsNull ...bool) Map {
+	var empty bool
+	if len(xmlEmptyIsNull) == 1 {
+		empty = xmlEmptyIsNull[0]
+	}
+	return Map(createMergePatch(map[string]interface{}(original), map[string]interface{}(modified), empty))
+}
+
+func createMergePatch(orig, mod map[string]interface{}, empty bool) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for k, ov := range orig {
+		if mergePatchNull(ov, empty) {
+			continue
+		}
+		if mv, ok := mod[k]; !ok || mergePatchNull(mv, empty) {
+			patch[k] = nil
+		}
+	}
+	for k, mv := range mod {
+		if mergePatchNull(mv, empty) {
+			continue
+		}
+		ov, ok := orig[k]
+		if !ok || mergePatchNull(ov, empty) {
+			patch[k] = deepCopy(mv)
+			continue
+		}
+		om, oMap := ov.(map[string]interface{})
+		mm, mMap := mv.(map[string]interface{})
+		switch {
+		case oMap && mMap:
+			if p := createMergePatch(om, mm, empty); len(p) > 0 {
+				patch[k] = p
+			}
+		case !reflect.DeepEqual(ov, mv):
+			patch[k] = deepCopy(mv)
+		}
+	}
+	return patch
+}
@@ -0,0 +1,99 @@
+package mxj
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	fmt.Println("\n================== TestMergePatch")
+	// RFC 7396, Appendix A - with the values in an object
+	tests := []struct{ target, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`{"a":"foo"}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`,
+			`{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`,
+			`{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`},
+	}
+	for _, tt := range tests {
+		m, _ := NewMapJson([]byte(tt.target))
+		p, _ := NewMapJson([]byte(tt.patch))
+		want, _ := NewMapJson([]byte(tt.want))
+		m.MergePatch(p)
+		if !reflect.DeepEqual(m, want) {
+			t.Errorf("%s + %s: got %v, want %v", tt.target, tt.patch, m, want)
+		}
+
+		// and back
+		o, _ := NewMapJson([]byte(tt.target))
+		cp := CreateMergePatch(o, want)
+		o.MergePatch(cp)
+		if !reflect.DeepEqual(o, want) {
+			t.Errorf("%s, CreateMergePatch %v: got %v, want %v", tt.target, cp, o, want)
+		}
+	}
+}
+
+func TestMergePatchXml(t *testing.T) {
+	fmt.Println("\n================== TestMergePatchXml")
+	m, err := NewMapXml([]byte(`<doc><title>JR</title><price>12</price><note>x</note><tags><tag>a</tag></tags></doc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewMapXml([]byte(`<doc><price/><note>y</note><tags><tag>a</tag><tag>b</tag></tags><isbn>123</isbn></doc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m1, _ := m.Copy()
+	m1.MergePatch(p)
+	if v, _ := m1.ValueForPath("doc.price"); v != "" {
+		t.Errorf("empty: %v", v)
+	}
+
+	m.MergePatch(p, true)
+	want := Map{"doc": map[string]interface{}{
+		"title": "JR",
+		"note":  "y",
+		"tags":  map[string]interface{}{"tag": []interface{}{"a", "b"}},
+		"isbn":  "123",
+	}}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v", m)
+	}
+
+	// CreateMergePatch - and as XML
+	orig, _ := NewMapXml([]byte(`<doc><title>JR</title><price>12</price><note>x</note><tags><tag>a</tag></tags></doc>`))
+	cp := CreateMergePatch(orig, m, true)
+	x, err := cp.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(x))
+	p, err = NewMapXml(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig.MergePatch(p, true)
+	if !reflect.DeepEqual(orig, want) {
+		t.Errorf("round trip: got %v", orig)
+	}
+
+	// "" values are null values
+	m["doc"].(map[string]interface{})["note"] = ""
+	if cp = CreateMergePatch(want, m, true); !reflect.DeepEqual(cp, Map{"doc": map[string]interface{}{"note": nil}}) {
+		t.Errorf("empty: %v", cp)
+	}
+	if cp = CreateMergePatch(want, m); !reflect.DeepEqual(cp, Map{"doc": map[string]interface{}{"note": ""}}) {
+		t.Errorf("not empty: %v", cp)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// move.go - move and copy values from one path to another.
+
+package mxj
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CopyValue copies the value for the path 'from' to the path 'to'.  The value is
+// a deep copy - the maps and lists in it are new.
+//
+//	'from' is as for ValuesForPath() - e.g., "vendor.items.item[0]"; if there are several
+//	       values for the path - "vendor..sku" - they're copied as a list.
+//	'to' is as for SetValueForPathCreate() - the maps and lists that are missing are created.
+//	       With an index on the last key the value is inserted in the list before the
+//	       member at the index - -1 is the last member, as for ValuesForPath(), and
+//	       len(list) appends the value; a list value's members are inserted.
+//	          m.CopyValue("vendor.items.item", "doc.products.product[2]") // 2 products
+//	'overwrite' (optional) - if 'true', the value for 'to' - or, with an index, the list
+//	       member at the index - is replaced; otherwise, if there's a value for 'to' the
+//	       error is a *SetPathError that wraps PathExistsError.
+//	If there's no value for 'from' the error is PathNotExistError.
+func (mv Map) CopyValue(from, to string, overwrite ...bool) error {
+	return mv.moveValue(from, to, false, overwrite...)
+}
+
+// MoveValue moves the value for the path 'from' to the path 'to' - as for
+// CopyValue(), but the value isn't copied and it's removed from 'from', as by
+// RemoveValuesForPath().
+//
+//	The indexes in 'to' are those of the lists before the value is removed; so, if
+//	there are 3 items,
+//	   m.MoveValue("doc.items.item[0]", "doc.items.item[3]")
+//	makes the first item the last one.
+//	'to' can't be in the value for 'from'.  If the value can't be moved the Map isn't
+//	changed - except for maps and lists created for 'to'.
+func (mv Map) MoveValue(from, to string, overwrite ...bool) error {
+	return mv.moveValue(from, to, true, overwrite...)
+}
+
+func (mv Map) moveValue(from, to string, move bool, overwrite ...bool) error {
+	var over bool
+	if len(overwrite) == 1 {
+		over = overwrite[0]
+	}
+
+	fkeys, err := parsePath(from)
+	if err != nil {
+		return err
+	}
+	tkeys, err := parseSetPath(to)
+	if err != nil {
+		return &SetPathError{Path: to, Err: err}
+	}
+
+	m := map[string]interface{}(mv)
+	locs := locsForPath(m, fkeys, true)
+	if len(locs) == 0 {
+		return PathNotExistError
+	}
+	vals := locValues(locs)
+	var value interface{}
+	if len(vals) == 1 {
+		value = vals[0]
+	} else {
+		value = expandLists(vals)
+	}
+	if !move {
+		value = deepCopy(value)
+	}
+
+	parent, at, err := createPath(m, to, tkeys[:len(tkeys)-1], false)
+	if err != nil {
+		return err
+	}
+	k := tkeys[len(tkeys)-1]
+	at = k.at(at)
+
+	var lists []*pathLoc
+	if move {
+		// 'to' can't be in the value - or be the list - that's removed
+		rm := make([]*pathLoc, 0, len(locs))
+		var rmVals []interface{}
+		for i, l := range locs {
+			if contains(l.value(), parent) {
+				return fmt.Errorf("MoveValue: %s is in the value for %s", to, from)
+			}
+			if len(l.idx) == 0 && l.key == k.name && sameMap(l.m, parent) {
+				if k.index != nil {
+					return fmt.Errorf("MoveValue: %s is in the value for %s", to, from)
+				}
+				if len(locs) == 1 {
+					return nil // nowhere to go
+				}
+				continue // it's replaced, not removed
+			}
+			rm = append(rm, l)
+			rmVals = append(rmVals, vals[i])
+		}
+		locs, vals = rm, rmVals
+		_, lists = markRemoved(locs, nil)
+	}
+	// restore the marked list members if the value can't be set
+	restore := func() {
+		if !move {
+			return
+		}
+		for i, l := range locs {
+			if len(l.idx) > 0 {
+				l.set(vals[i])
+			}
+		}
+	}
+
+	v, exists := parent[k.name]
+	if k.index == nil {
+		if exists && !over {
+			restore()
+			return &SetPathError{to, at, v, PathExistsError}
+		}
+		parent[k.name] = value
+	} else {
+		a, _ := listFor(v, exists)
+		n := *k.index
+		if n < 0 {
+			n += len(a)
+		}
+		if n < 0 || n > len(a) {
+			restore()
+			return &SetPathError{to, at, v, PathIndexError}
+		}
+		ins := []interface{}{value}
+		if vl, ok := value.([]interface{}); ok {
+			ins = vl
+		}
+		rest := n
+		if over && n < len(a) {
+			rest++
+		}
+		nl := make([]interface{}, 0, len(a)+len(ins))
+		nl = append(nl, a[:n]...)
+		nl = append(nl, ins...)
+		nl = append(nl, a[rest:]...)
+		if len(nl) == 1 {
+			parent[k.name] = nl[0]
+		} else {
+			parent[k.name] = nl
+		}
+	}
+
+	if move {
+		sweepRemoved(locs, lists, nil, false)
+	}
+	return nil
+}
+
+// deepCopy returns a copy of the value with new maps and lists.
+func deepCopy(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for key, val := range vv {
+			m[key] = deepCopy(val)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(vv))
+		for i, val := range vv {
+			a[i] = deepCopy(val)
+		}
+		return a
+	}
+	return v
+}
+
+func sameMap(a, b map[string]interface{}) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// contains reports whether the map 'm' is 'v' or is in it.
+func contains(v interface{}, m map[string]interface{}) bool {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if sameMap(vv, m) {
+			return true
+		}
+		for _, val := range vv {
+			if contains(val, m) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, val := range vv {
+			if contains(val, m) {
+				return true
+			}
+		}
+	}
+	return false
+}
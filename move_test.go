@@ -0,0 +1,164 @@
+package mxj
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var moveData = []byte(`<doc>
+	<vendor>
+		<items>
+			<item sku="a1"><name>bolt</name></item>
+			<item sku="a2"><name>nut</name></item>
+			<item sku="a3"><name>washer</name></item>
+		</items>
+		<contact>Bob</contact>
+	</vendor>
+	<products>
+		<product sku="p1"><name>screw</name></product>
+	</products>
+</doc>`)
+
+func TestCopyValue(t *testing.T) {
+	fmt.Println("\n================== TestCopyValue")
+	m, err := NewMapXml(moveData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ap := attrPrefix
+
+	// append the items to the products - a deep copy
+	if err = m.CopyValue("doc.vendor.items.item", "doc.products.product[1]"); err != nil {
+		t.Fatal(err)
+	}
+	v, _ := m.ValuesForPath("doc.products.product.name")
+	if !reflect.DeepEqual(v, []interface{}{"screw", "bolt", "nut", "washer"}) {
+		t.Errorf("products: %v", v)
+	}
+	if _, err = m.UpdateValuesForPath("name:BOLT", "doc.products.product[1]"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ = m.ValuesForPath("doc.vendor.items.item[0].name"); !reflect.DeepEqual(v, []interface{}{"bolt"}) {
+		t.Errorf("not a copy: %v", v)
+	}
+
+	// insert at an index; create the path
+	if err = m.CopyValue("doc.vendor.contact", "doc.products.product[0].contact"); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.CopyValue("doc.vendor.items.item["+ap+"sku='a2']", "doc.products.product[1]"); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.CopyValue("doc.vendor..name", "doc.archive.names.name"); err != nil {
+		t.Fatal(err)
+	}
+	checks := map[string]interface{}{
+		"doc.products.product[0].contact":        "Bob",
+		"doc.products.product[1].name":           "nut",
+		"doc.products.product[2].name":           "BOLT",
+		"doc.archive.names.name[2]":              "washer",
+		"doc.products.product[-1]." + ap + "sku": "a3",
+	}
+	for path, want := range checks {
+		if v, err := m.ValueForPath(path); err != nil || v != want {
+			t.Errorf("%s: got %v, %v, want %v", path, v, err, want)
+		}
+	}
+
+	// the destination exists
+	err = m.CopyValue("doc.vendor.contact", "doc.products.product[0].contact")
+	var se *SetPathError
+	if !errors.As(err, &se) || !errors.Is(err, PathExistsError) {
+		t.Fatalf("exists: %v", err)
+	}
+	if err = m.CopyValue("doc.vendor.contact", "doc.products.product[0].contact", true); err != nil {
+		t.Fatal(err)
+	}
+	// overwrite a list member
+	if err = m.CopyValue("doc.vendor.items.item[2]", "doc.products.product[0]", true); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.ValueForPath("doc.products.product[0].name"); v != "washer" {
+		t.Errorf("overwrite member: %v", v)
+	}
+
+	if err = m.CopyValue("doc.none", "doc.x"); err != PathNotExistError {
+		t.Errorf("no from: %v", err)
+	}
+	// -1 is the last member
+	if err = m.CopyValue("doc.vendor.items.item[0]", "doc.products.product[-1]", true); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.ValueForPath("doc.products.product[-1].name"); v != "bolt" {
+		t.Errorf("overwrite last member: %v", v)
+	}
+	if err = m.CopyValue("doc.vendor.contact", "doc.products.product[9]"); !errors.Is(err, PathIndexError) {
+		t.Errorf("index: %v", err)
+	}
+	if err = m.CopyValue("doc.vendor.contact", "doc.vendor.contact.x"); !errors.Is(err, PathNotMapError) {
+		t.Errorf("not map: %v", err)
+	}
+}
+
+func TestMoveValue(t *testing.T) {
+	fmt.Println("\n================== TestMoveValue")
+	m, err := NewMapXml(moveData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reorder a list
+	if err = m.MoveValue("doc.vendor.items.item[0]", "doc.vendor.items.item[3]"); err != nil {
+		t.Fatal(err)
+	}
+	v, _ := m.ValuesForPath("doc.vendor.items.item.name")
+	if !reflect.DeepEqual(v, []interface{}{"nut", "washer", "bolt"}) {
+		t.Errorf("reorder: %v", v)
+	}
+
+	// restructure
+	if err = m.MoveValue("doc.vendor.contact", "doc.meta.contact"); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.MoveValue("doc.vendor.items.item", "doc.products.product[1]"); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(m.StringIndentNoTypeInfo())
+	if v, _ = m.ValuesForPath("doc.products.product.name"); !reflect.DeepEqual(v, []interface{}{"screw", "nut", "washer", "bolt"}) {
+		t.Errorf("products: %v", v)
+	}
+	if ok, _ := m.Exists("doc.vendor.items.item"); ok {
+		t.Error("items not removed")
+	}
+	if v, _ := m.ValueForPath("doc.meta.contact"); v != "Bob" {
+		t.Errorf("contact: %v", v)
+	}
+
+	// a failed move doesn't change the Map
+	before, _ := m.Copy()
+	if err = m.MoveValue("doc.products.product[0]", "doc.meta.contact"); !errors.Is(err, PathExistsError) {
+		t.Fatalf("exists: %v", err)
+	}
+	if err = m.MoveValue("doc.products.product[0]", "doc.products.product[0].sub"); err == nil {
+		t.Fatal("moved into itself")
+	}
+	if err = m.MoveValue("doc.products", "doc.products.product[0]"); err == nil {
+		t.Fatal("moved into itself")
+	}
+	if !reflect.DeepEqual(m, before) {
+		t.Errorf("failed moves changed the Map:\n%s", m.StringIndentNoTypeInfo())
+	}
+
+	// moving members out of a list
+	if err = m.MoveValue("doc.products.product[name=~'^(nut|bolt)$']", "doc.sold.product"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ = m.ValuesForPath("doc.sold.product.name"); !reflect.DeepEqual(v, []interface{}{"nut", "bolt"}) {
+		t.Errorf("sold: %v", v)
+	}
+	if v, _ = m.ValuesForPath("doc.products.product.name"); !reflect.DeepEqual(v, []interface{}{"screw", "washer"}) {
+		t.Errorf("left: %v", v)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// namespace.go - name space handling for NewMapXml() and mv.Xml().
+// By default NewMapXml() keys are the local name of the element or attribute, so
+// <a:id> and <b:id> are both decoded with the "id" key.
+
+package mxj
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// NamespaceMode selects how NewMapXml(), etc., represent XML name spaces in Map keys.
+// It is set with the DecoderOptions.Namespaces field.
+type NamespaceMode int
+
+const (
+	// NamespaceLocal - the default - uses just the local name: <a:id> is "id".
+	NamespaceLocal NamespaceMode = iota
+	// NamespacePrefix uses "prefix:local" keys: <a:id> is "a:id" and the xml:lang
+	// attribute is "-xml:lang". Elements in the default name space use the local name.
+	// Name space declarations are decoded as "-xmlns" and "-xmlns:a" attributes, so
+	// mv.Xml() re-encodes a valid document.
+	NamespacePrefix
+	// NamespaceClark uses Clark notation, "{uri}local", for names in a name space:
+	// <a:id xmlns:a="urn:a"> is "{urn:a}id". Unprefixed attributes and elements
+	// without a name space use the local name. mv.Xml() resolves "{uri}local" keys
+	// to prefixed names using the "-xmlns:a" attributes that are in scope and
+	// declares "ns1", "ns2", ... prefixes for URIs that are not.
+	NamespaceClark
+)
+
+const (
+	xmlnsPrefix = "xmlns"
+	xmlPrefix   = "xml"
+	xmlURL      = "http://www.w3.org/XML/1998/namespace"
+)
+
+// nsBinding is a name space declaration; prefix "" is the default name space.
+type nsBinding struct {
+	prefix, uri string
+}
+
+// nsScope is the chain of name space declarations in scope for an element.
+type nsScope struct {
+	parent   *nsScope
+	bindings []nsBinding
+}
+
+// lookupURI returns the name space URI bound to 'prefix'.
+func (s *nsScope) lookupURI(prefix string) (string, bool) {
+	for ; s != nil; s = s.parent {
+		for i := len(s.bindings) - 1; i >= 0; i-- {
+			if s.bindings[i].prefix == prefix {
+				return s.bindings[i].uri, true
+			}
+		}
+	}
+	return "", false
+}
+
+// lookupPrefix returns the innermost, non-default prefix that is bound to 'uri'
+// and that has not been redeclared for some other URI.
+func (s *nsScope) lookupPrefix(uri string) (string, bool) {
+	for ss := s; ss != nil; ss = ss.parent {
+		for i := len(ss.bindings) - 1; i >= 0; i-- {
+			b := ss.bindings[i]
+			if b.prefix == "" || b.uri != uri {
+				continue
+			}
+			if u, _ := s.lookupURI(b.prefix); u == uri {
+				return b.prefix, true
+			}
+		}
+	}
+	return "", false
+}
+
+// nsBindings extracts the name space declarations from the xml.Attr list as
+// returned by xml.Decoder.Token().
+func nsBindings(attrs []xml.Attr) []nsBinding {
+	var b []nsBinding
+	for _, a := range attrs {
+		if a.Name.Space == xmlnsPrefix {
+			b = append(b, nsBinding{a.Name.Local, a.Value})
+		} else if a.Name.Space == "" && a.Name.Local == xmlnsPrefix {
+			b = append(b, nsBinding{"", a.Value})
+		}
+	}
+	return b
+}
+
+// ------------------------------ decoding -----------------------------------
+
+// pushNamespaces brings the name space declarations in 'attrs' into scope.
+func (o *DecoderOptions) pushNamespaces(attrs []xml.Attr) {
+	if o.Namespaces == NamespacePrefix {
+		o.ns = &nsScope{o.ns, nsBindings(attrs)}
+	}
+}
+
+// popNamespaces removes the declarations of the element that was just parsed.
+func (o *DecoderOptions) popNamespaces() {
+	if o.Namespaces == NamespacePrefix {
+		o.ns = o.ns.parent
+	}
+}
+
+// nsKey returns the Map key for an element or attribute name as returned by
+// xml.Decoder.Token() - with n.Space being the name space URI.
+func (o *DecoderOptions) nsKey(n xml.Name, isAttr bool) string {
+	if o.Namespaces == NamespaceLocal || n.Space == "" {
+		return n.Local
+	}
+	if n.Space == xmlnsPrefix {
+		return xmlnsPrefix + ":" + n.Local
+	}
+	if o.Namespaces == NamespaceClark {
+		return "{" + n.Space + "}" + n.Local
+	}
+	if n.Space == xmlURL {
+		return xmlPrefix + ":" + n.Local
+	}
+	if !isAttr {
+		if uri, ok := o.ns.lookupURI(""); ok && uri == n.Space {
+			return n.Local
+		}
+	}
+	if prefix, ok := o.ns.lookupPrefix(n.Space); ok {
+		return prefix + ":" + n.Local
+	}
+	// xml.Decoder.Token() leaves an unrecognized prefix as n.Space
+	return n.Space + ":" + n.Local
+}
+
+// ------------------------------ encoding -----------------------------------
+
+// isClark reports whether the key is in Clark notation - "{uri}local".
+func isClark(key string) bool {
+	return len(key) > 2 && key[0] == '{' && strings.IndexByte(key, '}') > 0
+}
+
+// isNsDecl reports whether the attribute name is a name space declaration.
+func isNsDecl(name string) bool {
+	return name == xmlnsPrefix || strings.HasPrefix(name, xmlnsPrefix+":")
+}
+
+// pushNamespaces brings the name space declarations in the attribute list into scope.
+func (p *pretty) pushNamespaces(attrs [][2]string) {
+	var b []nsBinding
+	for _, a := range attrs {
+		if a[0] == xmlnsPrefix {
+			b = append(b, nsBinding{"", a[1]})
+		} else if strings.HasPrefix(a[0], xmlnsPrefix+":") {
+			b = append(b, nsBinding{a[0][len(xmlnsPrefix)+1:], a[1]})
+		}
+	}
+	if len(b) > 0 {
+		p.ns = &nsScope{p.ns, b}
+	}
+}
+
+// xmlName resolves a Clark notation, "{uri}local", element or attribute key to
+// a "prefix:local" XML name using the name space declarations in scope.  If the
+// name space has not been declared, a prefix is generated and its declaration
+// is appended to 'decl' so it can be written with the element's attributes.
+func (p *pretty) xmlName(key string, isAttr bool, decl *[][2]string) string {
+	i := strings.IndexByte(key, '}')
+	uri, local := key[1:i], key[i+1:]
+	if uri == xmlURL {
+		return xmlPrefix + ":" + local
+	}
+	if !isAttr {
+		def, _ := p.ns.lookupURI("")
+		if def == uri {
+			return local
+		}
+		if uri == "" {
+			// undeclare the default name space
+			p.ns = &nsScope{p.ns, []nsBinding{{"", ""}}}
+			*decl = append(*decl, [2]string{xmlnsPrefix, ""})
+			return local
+		}
+	} else if uri == "" {
+		return local
+	}
+	if prefix, ok := p.ns.lookupPrefix(uri); ok {
+		return prefix + ":" + local
+	}
+	// generate a prefix that isn't in scope
+	var prefix string
+	for n := 1; ; n++ {
+		prefix = "ns" + strconv.Itoa(n)
+		if _, ok := p.ns.lookupURI(prefix); !ok {
+			break
+		}
+	}
+	p.ns = &nsScope{p.ns, []nsBinding{{prefix, uri}}}
+	*decl = append(*decl, [2]string{xmlnsPrefix + ":" + prefix, uri})
+	return prefix + ":" + local
+}
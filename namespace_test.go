@@ -2,6 +2,7 @@ package mxj
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -19,3 +20,129 @@ func TestBeautifyXml(t *testing.T) {
 	fmt.Println(flatxml)
 	fmt.Println(string(v))
 }
+
+const nsDoc = `<doc xmlns="urn:default" xmlns:a="urn:a" xml:lang="en"><a:id a:type="x">1</a:id><id>2</id><b:id xmlns:b="urn:b">3</b:id></doc>`
+
+func TestNamespacePrefix(t *testing.T) {
+	fmt.Println("\n----------------  TestNamespacePrefix ...")
+	opts := NewDecoderOptions()
+	opts.Namespaces = NamespacePrefix
+	m, err := opts.NewMapXml([]byte(nsDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(m)
+	for path, want := range map[string]interface{}{
+		"doc." + attrPrefix + "xmlns":       "urn:default",
+		"doc." + attrPrefix + "xmlns:a":     "urn:a",
+		"doc." + attrPrefix + "xml:lang":    "en",
+		"doc.a:id." + textK:                 "1",
+		"doc.a:id." + attrPrefix + "a:type": "x",
+		"doc.id":                            "2",
+		"doc.b:id." + textK:                 "3",
+	} {
+		v, err := m.ValueForPath(path)
+		if err != nil {
+			t.Fatal(path, err)
+		}
+		if v != want {
+			t.Fatalf("%s: got %v, want %v", path, v, want)
+		}
+	}
+
+	// re-encode and check that nothing is lost
+	x, err := m.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(x))
+	mm, err := opts.NewMapXml(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, mm) {
+		t.Fatalf("round trip:\n%v\n%v", m, mm)
+	}
+}
+
+func TestNamespaceClark(t *testing.T) {
+	fmt.Println("\n----------------  TestNamespaceClark ...")
+	opts := NewDecoderOptions()
+	opts.Namespaces = NamespaceClark
+	m, err := opts.NewMapXml([]byte(nsDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(m)
+	if _, ok := m["doc"]; ok {
+		t.Fatal("unexpected local name key:", m)
+	}
+	doc := m["{urn:default}doc"].(map[string]interface{})
+	if v := doc["{urn:a}id"].(map[string]interface{})[attrPrefix+"{urn:a}type"]; v != "x" {
+		t.Fatal("{urn:a}type:", v)
+	}
+	if v := doc["{urn:default}id"]; v != "2" {
+		t.Fatal("{urn:default}id:", v)
+	}
+	if v := doc["{urn:b}id"].(map[string]interface{})[textK]; v != "3" {
+		t.Fatal("{urn:b}id:", v)
+	}
+	if v := doc[attrPrefix+"{"+xmlURL+"}lang"]; v != "en" {
+		t.Fatal("xml:lang:", v)
+	}
+
+	x, err := m.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(x))
+	mm, err := opts.NewMapXml(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, mm) {
+		t.Fatalf("round trip:\n%v\n%v", m, mm)
+	}
+}
+
+func TestNamespaceClarkEncode(t *testing.T) {
+	fmt.Println("\n----------------  TestNamespaceClarkEncode ...")
+	// no declarations - prefixes are generated
+	m := Map{"{urn:x}doc": map[string]interface{}{
+		attrPrefix + "{urn:y}attr": "a",
+		"{urn:x}item":              []interface{}{"1", "2"},
+		"{urn:y}other":             "3",
+		"plain":                    "4",
+	}}
+	x, err := m.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(x))
+	if err = checkIsValidXml(x); err != nil {
+		t.Fatal(err)
+	}
+	opts := NewDecoderOptions()
+	opts.Namespaces = NamespaceClark
+	mm, err := opts.NewMapXml(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := mm["{urn:x}doc"].(map[string]interface{})
+	for _, k := range []string{attrPrefix + "{urn:y}attr", "{urn:x}item", "{urn:y}other", "plain"} {
+		if _, ok := doc[k]; !ok {
+			t.Fatal("missing key:", k, mm)
+		}
+	}
+}
+
+func TestNamespaceLocalDefault(t *testing.T) {
+	fmt.Println("\n----------------  TestNamespaceLocalDefault ...")
+	m, err := NewMapXml([]byte(nsDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["doc"].(map[string]interface{})["id"].([]interface{}); !ok {
+		t.Fatal("expected local name 'id' list:", m)
+	}
+}
@@ -50,7 +50,7 @@ func (mv Map) NewMap(keypairs ...string) (Map, error) {
 		}
 
 		// initialize oldKey, newKey and check
-		vv := strings.Split(v, ":")
+		vv := splitKeypair(v)
 		if len(vv) > 2 {
 			return n, errors.New("oldKey:newKey keypair value not valid - " + v)
 		}
@@ -92,6 +92,29 @@ func (mv Map) NewMap(keypairs ...string) (Map, error) {
 	return n, nil
 }
 
+// splitKeypair splits "oldKey:newKey" on the ':' separators that aren't in a
+// "[...]" selector of 'oldKey' - e.g., "data[1:3]:newdata".
+func splitKeypair(s string) []string {
+	var ss []string
+	var depth, start int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			if depth > 0 {
+				depth--
+			}
+		case ':':
+			if depth == 0 {
+				ss = append(ss, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(ss, s[start:])
+}
+
 // navigate 'n' to end of path and add val
 func addNewVal(n *map[string]interface{}, path []string, val []interface{}) {
 	// newVal - either singleton or array
@@ -0,0 +1,168 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// pathlocs.go - where the values for a path are in a Map, so that they can be
+// modified.  The path grammar is as for ValuesForPath() - indexes, slices,
+// predicates, wildcards and "..key" recursive descent.
+
+package mxj
+
+// pathLoc is the location of a value for a path: m[key] or, with indexes, a
+// member of the list m[key] - or of a list in that list.
+type pathLoc struct {
+	m   map[string]interface{}
+	key string
+	idx []int
+}
+
+func (l *pathLoc) value() interface{} {
+	v := l.m[l.key]
+	for _, i := range l.idx {
+		v = v.([]interface{})[i]
+	}
+	return v
+}
+
+// set replaces the value at the location; a list member is replaced in place.
+func (l *pathLoc) set(v interface{}) {
+	if len(l.idx) == 0 {
+		l.m[l.key] = v
+		return
+	}
+	a := l.m[l.key]
+	for _, i := range l.idx[:len(l.idx)-1] {
+		a = a.([]interface{})[i]
+	}
+	a.([]interface{})[l.idx[len(l.idx)-1]] = v
+}
+
+// member returns the location of member 'i' of the list at the location.
+func (l *pathLoc) member(i int) *pathLoc {
+	idx := make([]int, len(l.idx)+1)
+	copy(idx, l.idx)
+	idx[len(l.idx)] = i
+	return &pathLoc{l.m, l.key, idx}
+}
+
+func locValues(locs []*pathLoc) []interface{} {
+	vals := make([]interface{}, len(locs))
+	for i, l := range locs {
+		vals[i] = l.value()
+	}
+	return vals
+}
+
+// expandLocs replaces the locations of lists with the locations of their members.
+func expandLocs(locs []*pathLoc) []*pathLoc {
+	ret := make([]*pathLoc, 0, len(locs))
+	for _, l := range locs {
+		if a, ok := l.value().([]interface{}); ok {
+			for i := range a {
+				ret = append(ret, l.member(i))
+			}
+		} else {
+			ret = append(ret, l)
+		}
+	}
+	return ret
+}
+
+// childLocs returns the locations of the values for 'key' - or of all values
+// for "*" - in 'm'; list members are located individually.
+func childLocs(m map[string]interface{}, key string) []*pathLoc {
+	var locs []*pathLoc
+	if key == "*" {
+		for _, k := range sortedKeys(m) {
+			locs = append(locs, &pathLoc{m: m, key: k})
+		}
+	} else if _, ok := m[key]; ok {
+		locs = append(locs, &pathLoc{m: m, key: key})
+	}
+	return expandLocs(locs)
+}
+
+// descendantLocs returns the locations of the values for 'key' - or of all
+// values for "*" - at any depth in 'm', grouped by the map they're in.  The
+// maps are walked in key order, so a value precedes the values nested in it.
+func descendantLocs(m map[string]interface{}, key string) [][]*pathLoc {
+	var groups [][]*pathLoc
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			for _, k := range sortedKeys(vv) {
+				if key == "*" || k == key {
+					groups = append(groups, childLocs(vv, k))
+				}
+				walk(vv[k])
+			}
+		case []interface{}:
+			for _, v := range vv {
+				walk(v)
+			}
+		}
+	}
+	walk(m)
+	return groups
+}
+
+// selectLocs applies the indexes, slices and predicates of the key to 'locs' -
+// as selectValues() does to values.
+func (k *key) selectLocs(locs []*pathLoc) []*pathLoc {
+	for n, sel := range k.sels {
+		if n > 0 {
+			locs = expandLocs(locs)
+		}
+		idx := sel.selectIndexes(locValues(locs))
+		sl := make([]*pathLoc, len(idx))
+		for i, j := range idx {
+			sl[i] = locs[j]
+		}
+		locs = sl
+	}
+	return locs
+}
+
+// locsForPath returns the locations of the values for the path 'keys' in 'm'.
+// The selectors of a key apply to the values for the key in each map, as for
+// ValuesForPath().  If 'whole' is set and the last key has no selectors, a
+// list value for it is located as a whole rather than by member.
+func locsForPath(m map[string]interface{}, keys []*key, whole bool) []*pathLoc {
+	nodes := []map[string]interface{}{m}
+	var locs []*pathLoc
+	for i, k := range keys {
+		last := i == len(keys)-1
+		locs = nil
+		for _, n := range nodes {
+			var groups [][]*pathLoc
+			switch {
+			case k.descend:
+				groups = descendantLocs(n, k.name)
+			case last && whole && len(k.sels) == 0:
+				if k.name == "*" {
+					for _, kk := range sortedKeys(n) {
+						groups = append(groups, []*pathLoc{{m: n, key: kk}})
+					}
+				} else if _, ok := n[k.name]; ok {
+					groups = append(groups, []*pathLoc{{m: n, key: k.name}})
+				}
+			default:
+				groups = [][]*pathLoc{childLocs(n, k.name)}
+			}
+			for _, g := range groups {
+				locs = append(locs, k.selectLocs(g)...)
+			}
+		}
+		if last {
+			break
+		}
+		nodes = nil
+		for _, l := range locs {
+			if mm, ok := l.value().(map[string]interface{}); ok {
+				nodes = append(nodes, mm)
+			}
+		}
+	}
+	return locs
+}
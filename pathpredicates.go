@@ -0,0 +1,384 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// pathpredicates.go - the "[...]" selectors in ValuesForPath() paths: list
+// indexes and slices, "key:val" style filters with comparison and regexp
+// operators, and existence tests - e.g., "books.book[author='Gaddis'].title" -
+// and "..key" recursive descent.
+
+package mxj
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// keySelector selects members of the values for a path key - e.g., "[2]" or "[price>10]".
+// It returns the indexes of the selected members of 'vals'.
+type keySelector interface {
+	selectIndexes(vals []interface{}) []int
+}
+
+// keyIndex is a "[N]" list index; "[-1]" is the last member.
+type keyIndex int
+
+func (i keyIndex) selectIndexes(vals []interface{}) []int {
+	n := int(i)
+	if n < 0 {
+		n += len(vals)
+	}
+	if n < 0 || n >= len(vals) {
+		return nil
+	}
+	return []int{n}
+}
+
+// keySlice is a "[start:end:step]" list slice - as for Python lists.
+//
+//	"[2:5]" is members 2, 3 and 4; "[-2:]" is the last two members; "[:3]" is
+//	the first three; "[::2]" is every other member and "[::-1]" is all of them
+//	in reverse order.  Indexes out of range are clipped.
+type keySlice struct {
+	start, end, step int
+	hasStart, hasEnd bool
+}
+
+func parseSlice(s string) (keySlice, error) {
+	ks := keySlice{step: 1}
+	p := strings.Split(s, ":")
+	if len(p) > 3 {
+		return ks, fmt.Errorf("cannot convert slice to int values: %s", s)
+	}
+	for i, v := range p {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return ks, fmt.Errorf("cannot convert slice to int values: %s", s)
+		}
+		switch i {
+		case 0:
+			ks.start, ks.hasStart = int(n), true
+		case 1:
+			ks.end, ks.hasEnd = int(n), true
+		case 2:
+			if n == 0 {
+				return ks, fmt.Errorf("slice step cannot be 0: %s", s)
+			}
+			ks.step = int(n)
+		}
+	}
+	return ks, nil
+}
+
+func (ks keySlice) selectIndexes(vals []interface{}) []int {
+	n := len(vals)
+	// bound converts a slice index to a vals index in [min, max]
+	bound := func(i, min, max int) int {
+		if i < 0 {
+			i += n
+		}
+		if i < min {
+			return min
+		}
+		if i > max {
+			return max
+		}
+		return i
+	}
+	var ret []int
+	if ks.step > 0 {
+		lo, hi := 0, n
+		if ks.hasStart {
+			lo = bound(ks.start, 0, n)
+		}
+		if ks.hasEnd {
+			hi = bound(ks.end, 0, n)
+		}
+		for i := lo; i < hi; i += ks.step {
+			ret = append(ret, i)
+		}
+		return ret
+	}
+	hi, lo := n-1, -1
+	if ks.hasStart {
+		hi = bound(ks.start, -1, n-1)
+	}
+	if ks.hasEnd {
+		lo = bound(ks.end, -1, n-1)
+	}
+	for i := hi; i > lo; i += ks.step {
+		ret = append(ret, i)
+	}
+	return ret
+}
+
+// expandLists replaces the list values in 'vals' with their members.
+func expandLists(vals []interface{}) []interface{} {
+	ret := make([]interface{}, 0, len(vals))
+	for _, v := range vals {
+		if a, ok := v.([]interface{}); ok {
+			ret = append(ret, a...)
+		} else {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// descendantValues returns the values for 'key' - or all values for "*" - at
+// any depth in 'm'; each value is returned as a list - its members if it's a
+// list.  See descendantLocs().
+func descendantValues(m map[string]interface{}, key string) [][]interface{} {
+	groups := descendantLocs(m, key)
+	vals := make([][]interface{}, len(groups))
+	for i, g := range groups {
+		vals[i] = locValues(g)
+	}
+	return vals
+}
+
+// pathPredicate is a "[key op value]" or "[?exists(key)]" filter.
+//
+//	The 'key' is a path relative to the list member - e.g., "author", "-id" or
+//	"author.last" - or "." for the member value itself; the predicate is 'true'
+//	if any of the values for the key matches.  The value of an element with
+//	attributes is its "#text" value.
+type pathPredicate struct {
+	not    bool // "[!...]"
+	exists bool // "[?exists(key)]"
+	key    string
+	op     string // "=", "!=", "<", "<=", ">", ">=", "=~" or "!~"
+	val    interface{}
+	re     *regexp.Regexp
+}
+
+// the comparison operators - two-character operators first
+var predicateOps = []string{"=~", "!~", "!=", "<=", ">=", "=", "<", ">"}
+
+// parsePredicate parses the content of a "[...]" path selector that isn't an index.
+//
+//	[key=value]         'value' is a quoted string - 'Gaddis' or "Gaddis" - a number,
+//	                    true or false; unquoted text is a string
+//	[key!=value], [key<value], [key<=value], [key>value], [key>=value]
+//	                    numbers are compared as numbers, strings lexically; bools are
+//	                    only = or !=
+//	[key=~'regexp']     the value matches the regexp; "!~" for doesn't match
+//	[?exists(key)]      the member has a value for 'key'
+//	[!...]              negates the predicate - e.g., [!?exists(key)]
+func parsePredicate(s string) (*pathPredicate, error) {
+	p := new(pathPredicate)
+	e := strings.TrimSpace(s)
+	if strings.HasPrefix(e, "!") {
+		p.not = true
+		e = strings.TrimSpace(e[1:])
+	}
+
+	if strings.HasPrefix(e, "?") {
+		e = strings.TrimSpace(e[1:])
+		if !strings.HasPrefix(e, "exists(") || !strings.HasSuffix(e, ")") {
+			return nil, fmt.Errorf("unknown path predicate function: %s", s)
+		}
+		p.exists = true
+		p.key = strings.TrimSpace(e[len("exists(") : len(e)-1])
+		if p.key == "" {
+			return nil, fmt.Errorf("no key in path predicate: %s", s)
+		}
+		return p, nil
+	}
+
+	i, op := predicateOp(e)
+	if i < 0 {
+		return nil, fmt.Errorf("no operator in path predicate: %s", s)
+	}
+	p.key = strings.TrimSpace(e[:i])
+	p.op = op
+	if p.key == "" {
+		return nil, fmt.Errorf("no key in path predicate: %s", s)
+	}
+	v := strings.TrimSpace(e[i+len(op):])
+	switch {
+	case len(v) >= 2 && (v[0] == '\'' || v[0] == '"') && v[len(v)-1] == v[0]:
+		p.val = v[1 : len(v)-1]
+	case v == "true" || v == "false":
+		p.val = v == "true"
+	default:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			p.val = f
+		} else {
+			p.val = v
+		}
+	}
+
+	if op == "=~" || op == "!~" {
+		vs, ok := p.val.(string)
+		if !ok {
+			vs = v
+		}
+		re, err := regexp.Compile(vs)
+		if err != nil {
+			return nil, fmt.Errorf("bad regexp in path predicate: %s: %s", s, err)
+		}
+		p.re = re
+	}
+	return p, nil
+}
+
+// predicateOp returns the index of the first comparison operator in 's' that
+// isn't in a quoted string or a nested "[...]" selector.
+func predicateOp(s string) (int, string) {
+	var quote byte
+	var depth int
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0:
+			for _, op := range predicateOps {
+				if strings.HasPrefix(s[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+func (p *pathPredicate) selectIndexes(vals []interface{}) []int {
+	var ret []int
+	for i, v := range vals {
+		if p.match(v) != p.not {
+			ret = append(ret, i)
+		}
+	}
+	return ret
+}
+
+// match reports whether the list member 'v' satisfies the predicate.
+func (p *pathPredicate) match(v interface{}) bool {
+	var vals []interface{}
+	if p.key == "." {
+		vals = []interface{}{v}
+	} else if m, ok := v.(map[string]interface{}); ok {
+		vals, _ = Map(m).ValuesForPath(p.key)
+	}
+	if p.exists {
+		return len(vals) > 0
+	}
+	for _, vv := range vals {
+		if p.compare(textValue(vv)) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare reports whether 'v op p.val' is true.  Values that can't be
+// compared to p.val are only "!=" or "!~".
+func (p *pathPredicate) compare(v interface{}) bool {
+	if p.re != nil {
+		s, ok := castString(v)
+		if !ok {
+			return p.op == "!~"
+		}
+		return p.re.MatchString(s) == (p.op == "=~")
+	}
+
+	var c int // -1, 0, 1 for less, equal, greater
+	switch pv := p.val.(type) {
+	case float64:
+		f, err := toFloat64(v)
+		if err != nil {
+			return p.op == "!="
+		}
+		switch {
+		case f < pv:
+			c = -1
+		case f > pv:
+			c = 1
+		}
+	case bool:
+		b, ok := v.(bool)
+		if s, isStr := v.(string); isStr {
+			var err error
+			b, err = strconv.ParseBool(strings.TrimSpace(s))
+			ok = err == nil
+		}
+		if !ok {
+			return p.op == "!="
+		}
+		// bools aren't ordered
+		switch p.op {
+		case "=":
+			return b == pv
+		case "!=":
+			return b != pv
+		}
+		return false
+	case string:
+		s, ok := castString(v)
+		if !ok {
+			return p.op == "!="
+		}
+		c = strings.Compare(s, pv)
+	}
+
+	switch p.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// splitPath splits a path on the "." separators that aren't in a "[...]"
+// selector - "a[b.c='x.y'].d" is "a[b.c='x.y']" and "d".
+func splitPath(s string) []string {
+	var keys []string
+	var quote byte
+	var depth, start int
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case depth > 0 && (c == '\'' || c == '"'):
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			if depth > 0 {
+				depth--
+			}
+		case c == '.' && depth == 0:
+			keys = append(keys, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(keys, s[start:])
+}
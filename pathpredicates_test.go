@@ -0,0 +1,233 @@
+package mxj
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var predicateData = []byte(`<doc>
+	<books>
+		<book id="A1" lang="en">
+			<title>The Recognitions</title>
+			<author>Gaddis</author>
+			<price>12.50</price>
+		</book>
+		<book id="B2" lang="fr">
+			<title>Les Misérables</title>
+			<author>Hugo</author>
+			<price>8</price>
+			<award>yes</award>
+		</book>
+		<book id="A3" lang="en">
+			<title>JR</title>
+			<author>Gaddis</author>
+			<price currency="USD">15</price>
+		</book>
+	</books>
+	<items>
+		<item><name>a</name><price>5</price><stock>true</stock></item>
+		<item><name>b</name><price>11</price><stock>false</stock></item>
+		<item><name>c.d</name><price>20</price><stock>true</stock></item>
+	</items>
+	<x id="A-x"><v>1</v></x>
+</doc>`)
+
+func TestValuesForPathPredicates(t *testing.T) {
+	fmt.Println("\n================== TestValuesForPathPredicates")
+	m, err := NewMapXml(predicateData, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ap := attrPrefix
+
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{"doc.books.book[author='Gaddis'].title", []interface{}{"The Recognitions", "JR"}},
+		{`doc.books.book[author="Hugo"].title`, []interface{}{"Les Misérables"}},
+		{"doc.books.book[author!='Gaddis'].title", []interface{}{"Les Misérables"}},
+		{"doc.items.item[price>10].name", []interface{}{"b", "c.d"}},
+		{"doc.items.item[price<=11].name", []interface{}{"a", "b"}},
+		{"doc.books.book[price>=15]." + ap + "id", []interface{}{"A3"}}, // "#text" value
+		{"doc.books.book[" + ap + "id=~'^A'].title", []interface{}{"The Recognitions", "JR"}},
+		{"doc.books.book[" + ap + "id!~'^A'].title", []interface{}{"Les Misérables"}},
+		{"doc.books.book[?exists(award)].title", []interface{}{"Les Misérables"}},
+		{"doc.books.book[!?exists(award)].title", []interface{}{"The Recognitions", "JR"}},
+		{"doc.books.book[!author='Gaddis'].title", []interface{}{"Les Misérables"}},
+		{"doc.books.book[?exists(price." + ap + "currency)].title", []interface{}{"JR"}},
+		{"doc.items.item[stock=true].name", []interface{}{"a", "c.d"}},
+		{"doc.items.item[name='c.d'].price", []interface{}{float64(20)}},
+		{"doc.items.item.price[.>10]", []interface{}{float64(11), float64(20)}},
+		{"doc.x[" + ap + "id=~'^A'].v", []interface{}{float64(1)}}, // not a list
+		// chained and with indexes
+		{"doc.books.book[" + ap + "lang='en'][1].title", []interface{}{"JR"}},
+		{"doc.books.book[1][author='Hugo'].title", []interface{}{"Les Misérables"}},
+		{"doc.books.book[author='Gaddis'][price<13].title", []interface{}{"The Recognitions"}},
+		{"doc.*.book[author='Hugo']." + ap + "lang", []interface{}{"fr"}},
+		{"doc.books.book[author='Nobody'].title", []interface{}{}},
+	}
+	for _, tt := range tests {
+		got, err := m.ValuesForPath(tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	// predicates with subkeys
+	vals, err := m.ValuesForPath("doc.books.book[author='Gaddis']", ap+"lang:en", "!title:JR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].(map[string]interface{})["title"] != "The Recognitions" {
+		t.Errorf("subkeys: %v", vals)
+	}
+
+	if v, err := m.ValueForPath("doc.books.book[price<10].author"); err != nil || v != "Hugo" {
+		t.Errorf("ValueForPath: %v, %v", v, err)
+	}
+}
+
+func TestValuesForPathPredicateErrors(t *testing.T) {
+	fmt.Println("\n================== TestValuesForPathPredicateErrors")
+	m := Map{"a": map[string]interface{}{"b": "x"}}
+	for path, msg := range map[string]string{
+		"a[b='x'":        "no right bracket",
+		"a[]":            "no right bracket",
+		"a[x]":           "cannot convert index",
+		"a[?size(b)]":    "unknown path predicate function",
+		"a[=1]":          "no key",
+		"a[b=~'(']":      "bad regexp",
+		"a[?exists()].b": "no key",
+	} {
+		_, err := m.ValuesForPath(path)
+		fmt.Println(path, "=>", err)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
+
+var sliceData = []byte(`{
+	"doc": {
+		"id": "d",
+		"entry": [
+			{"id": 0, "tags": ["a", "b", "c"]},
+			{"id": 1, "sub": {"id": "s1"}},
+			{"id": 2},
+			{"id": 3, "sub": [{"id": "s2"}, {"id": "s3"}]},
+			{"id": 4}
+		],
+		"single": {"id": 5},
+		"grid": [[1, 2], [3, 4]]
+	}
+}`)
+
+func TestValuesForPathSlices(t *testing.T) {
+	fmt.Println("\n================== TestValuesForPathSlices")
+	m, err := NewMapJson(sliceData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{"doc.entry[-1].id", []interface{}{float64(4)}},
+		{"doc.entry[-5].id", []interface{}{float64(0)}},
+		{"doc.entry[-6].id", nil},
+		{"doc.entry[2:5].id", []interface{}{float64(2), float64(3), float64(4)}},
+		{"doc.entry[:2].id", []interface{}{float64(0), float64(1)}},
+		{"doc.entry[-2:].id", []interface{}{float64(3), float64(4)}},
+		{"doc.entry[3:100].id", []interface{}{float64(3), float64(4)}},
+		{"doc.entry[::2].id", []interface{}{float64(0), float64(2), float64(4)}},
+		{"doc.entry[::-2].id", []interface{}{float64(4), float64(2), float64(0)}},
+		{"doc.entry[4:1:-1].id", []interface{}{float64(4), float64(3), float64(2)}},
+		{"doc.entry[0].tags[-1]", []interface{}{"c"}},
+		{"doc.entry[0].tags[1:]", []interface{}{"b", "c"}},
+		{"doc.entry[1:][?exists(sub)].id", []interface{}{float64(1), float64(3)}},
+		{"doc.entry[id>1][-1].id", []interface{}{float64(4)}},
+		// a singleton is a list of one member
+		{"doc.single[-1].id", []interface{}{float64(5)}},
+		{"doc.single[0:].id", []interface{}{float64(5)}},
+		{"doc.single[1:].id", nil},
+		{"doc.entry.sub[-1].id", []interface{}{"s1", "s3"}},
+		// lists of lists
+		{"doc.grid[1][0]", []interface{}{float64(3)}},
+		{"doc.grid[-1][-1]", []interface{}{float64(4)}},
+		// recursive descent
+		{"doc..sub.id", []interface{}{"s1", "s2", "s3"}},
+		{"doc.entry[3]..id", []interface{}{float64(3), "s2", "s3"}},
+		{"..sub[0].id", []interface{}{"s1", "s2"}},
+		{"doc..sub[id='s3']", []interface{}{map[string]interface{}{"id": "s3"}}},
+		{"doc..tags[0]", []interface{}{"a"}},
+		{"doc..nothing", nil},
+	}
+	for _, tt := range tests {
+		got, err := m.ValuesForPath(tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	// "doc..id" has all the "id" values
+	ids, err := m.ValuesForPath("doc..id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 10 {
+		t.Errorf("doc..id: %v", ids)
+	}
+	if v, err := m.ValueForPath("doc.entry[-1]"); err != nil || v.(map[string]interface{})["id"] != float64(4) {
+		t.Errorf("ValueForPath: %v, %v", v, err)
+	}
+	if ok, err := m.Exists("doc..sub[1]"); !ok || err != nil {
+		t.Errorf("Exists: %v, %v", ok, err)
+	}
+	if ok, _ := m.Exists("doc.entry[-6]"); ok {
+		t.Errorf("Exists: doc.entry[-6]")
+	}
+
+	n, err := m.NewMap("doc.entry[1:3]:items", "doc.entry[-1].id:last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(n)
+	if v, _ := n.ValuesForPath("items.id"); !reflect.DeepEqual(v, []interface{}{float64(1), float64(2)}) {
+		t.Errorf("NewMap: %v", n)
+	}
+	if n["last"] != float64(4) {
+		t.Errorf("NewMap: %v", n)
+	}
+
+	// LeafNodes paths
+	for _, l := range m.LeafNodes() {
+		v, err := m.ValueForPath(l.Path)
+		if err != nil || !reflect.DeepEqual(v, l.Value) {
+			t.Errorf("leaf %s: got %v, %v, want %v", l.Path, v, err, l.Value)
+		}
+	}
+
+	for _, path := range []string{"doc.entry[1:x]", "doc.entry[1:2:0]", "doc.entry[1:2:3:4]"} {
+		if _, err := m.ValuesForPath(path); err == nil {
+			t.Errorf("%s: no error", path)
+		}
+	}
+}
@@ -1,37 +1,148 @@
 package mxj
 
-import "strings"
+import (
+	"reflect"
+	"strings"
+)
 
 // Removes the path.
+// The 'path' can have wildcards, indexes and predicates as for ValuesForPath(); all
+// the values for the path are removed.  (See RemoveValuesForPath for the count.)
+// If the path's parent doesn't exist an error is returned.
 func (mv Map) Remove(path string) error {
-	m := map[string]interface{}(mv)
-	return remove(m, path)
+	n, err := mv.RemoveValuesForPath(path)
+	if err != nil || n > 0 {
+		return err
+	}
+	if keys := splitPath(path); len(keys) > 1 {
+		parent := strings.Join(keys[:len(keys)-1], ".")
+		if ok, _ := mv.Exists(parent); !ok {
+			return PathNotExistError
+		}
+	}
+	return nil
+}
+
+// RemoveValuesForPath removes all the values for the path and returns how many
+// were removed.
+//
+//	'path' is as for ValuesForPath() - "catalog.book[3]", "*.password", "doc..id", etc.
+//	       A list member is spliced out of its list; a list that's left empty is removed.
+//	'subkeys' (optional) select the values - or list members - to remove as for
+//	       ValuesForPath() - e.g., RemoveValuesForPath("items.item", "-status:deleted").
+//	See RemoveValuesForPathCollapse() to have lists that are left with one member
+//	replaced by the member, as when the XML doc is decoded.
+func (mv Map) RemoveValuesForPath(path string, subkeys ...string) (int, error) {
+	return mv.removeValuesForPath(path, subkeys, false)
+}
+
+// RemoveValuesForPathCollapse is RemoveValuesForPath, but lists that are left
+// with one member are replaced by the member - so "doc.item" is a map value,
+// as NewMapXml would decode it, rather than a list of one map.
+func (mv Map) RemoveValuesForPathCollapse(path string, subkeys ...string) (int, error) {
+	return mv.removeValuesForPath(path, subkeys, true)
 }
 
-func remove(m interface{}, path string) error {
-	val, err := prevValueByPath(m, path)
+func (mv Map) removeValuesForPath(path string, subkeys []string, collapse bool) (int, error) {
+	var subKeyMap map[string]interface{}
+	if len(subkeys) > 0 {
+		var err error
+		subKeyMap, err = getSubKeyMap(subkeys...)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	keys, err := parsePath(path)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
 	}
 
-	lastKey := lastKey(path)
-	delete(val, lastKey)
+	// without subkeys a list is removed as a whole - with them, by member
+	locs := locsForPath(map[string]interface{}(mv), keys, len(subKeyMap) == 0)
+	// mark list members first; then removing a key doesn't lose a list
+	count, lists := markRemoved(locs, subKeyMap)
+	count += sweepRemoved(locs, lists, subKeyMap, collapse)
+	return count, nil
+}
 
-	return nil
+// markRemoved marks the list members at 'locs' that have the subkeys as removed
+// and returns how many were marked and the lists they're in.
+func markRemoved(locs []*pathLoc, subkeys map[string]interface{}) (int, []*pathLoc) {
+	var count int
+	var lists []*pathLoc
+	type listKey struct {
+		m   uintptr
+		key string
+	}
+	seen := make(map[listKey]bool)
+	for _, l := range locs {
+		if len(l.idx) == 0 || !hasSubKeys(l.value(), subkeys) {
+			continue
+		}
+		l.set(removed{})
+		count++
+		if lk := (listKey{reflect.ValueOf(l.m).Pointer(), l.key}); !seen[lk] {
+			seen[lk] = true
+			lists = append(lists, &pathLoc{m: l.m, key: l.key})
+		}
+	}
+	return count, lists
 }
 
-// returns the last key of the path.
-// lastKey("a.b.c") would had returned "c"
-func lastKey(path string) string {
-	keys := strings.Split(path, ".")
-	key := keys[len(keys)-1]
-	return key
+// sweepRemoved removes the keys at 'locs' that aren't list members and have the
+// subkeys, and splices the marked members out of 'lists' - replacing a list
+// left with one member by the member if 'collapse' is set.  It returns how
+// many keys were removed.
+func sweepRemoved(locs, lists []*pathLoc, subkeys map[string]interface{}, collapse bool) int {
+	var count int
+	for i := len(locs) - 1; i >= 0; i-- {
+		l := locs[i]
+		if len(l.idx) > 0 {
+			continue
+		}
+		if _, ok := l.m[l.key]; !ok || !hasSubKeys(l.value(), subkeys) {
+			continue
+		}
+		delete(l.m, l.key)
+		count++
+	}
+	for _, l := range lists {
+		v, ok := l.m[l.key].([]interface{})
+		if !ok {
+			continue // already removed or replaced
+		}
+		a := spliceRemoved(v)
+		switch {
+		case len(a) == 0:
+			delete(l.m, l.key)
+		case len(a) == 1 && collapse:
+			l.m[l.key] = a[0]
+		default:
+			l.m[l.key] = a
+		}
+	}
+	return count
 }
 
-// returns the path without the last key
-// parentPath("a.b.c") whould had returned "a.b"
-func parentPath(path string) string {
-	keys := strings.Split(path, ".")
-	parentPath := strings.Join(keys[0:len(keys)-1], ".")
-	return parentPath
+// removed marks list members that are to be removed.
+type removed struct{}
+
+// spliceRemoved returns the list without the removed members - of it or of
+// the lists in it.
+func spliceRemoved(a []interface{}) []interface{} {
+	ret := make([]interface{}, 0, len(a))
+	for _, v := range a {
+		switch vv := v.(type) {
+		case removed:
+			continue
+		case []interface{}:
+			v = spliceRemoved(vv)
+		}
+		ret = append(ret, v)
+	}
+	return ret
 }
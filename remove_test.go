@@ -1,6 +1,8 @@
 package mxj
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -19,3 +21,100 @@ func TestRemove(t *testing.T) {
 		t.Fatal("removed key still remain")
 	}
 }
+
+var removeData = []byte(`<catalog>
+	<book id="b0" status="ok"><title>A</title><password>x</password></book>
+	<book id="b1" status="deleted"><title>B</title></book>
+	<book id="b2" status="ok"><title>C</title></book>
+	<book id="b3" status="deleted"><title>D</title></book>
+	<user><name>u</name><password>secret</password></user>
+	<admin><password>root</password></admin>
+</catalog>`)
+
+func TestRemoveValuesForPath(t *testing.T) {
+	fmt.Println("\n================== TestRemoveValuesForPath")
+	ap := attrPrefix
+	tests := []struct {
+		path    string
+		subkeys []string
+		n       int
+		check   string
+		want    []interface{}
+	}{
+		{"catalog.book[3]", nil, 1, "catalog.book." + ap + "id", []interface{}{"b0", "b1", "b2"}},
+		{"catalog.book[-1]", nil, 1, "catalog.book." + ap + "id", []interface{}{"b0", "b1", "b2"}},
+		{"catalog.book[1:3]", nil, 2, "catalog.book." + ap + "id", []interface{}{"b0", "b3"}},
+		{"catalog.book", []string{ap + "status:deleted"}, 2, "catalog.book.title", []interface{}{"A", "C"}},
+		{"catalog.book[" + ap + "status='deleted']", nil, 2, "catalog.book.title", []interface{}{"A", "C"}},
+		{"catalog.*.password", nil, 3, "catalog..password", nil},
+		{"catalog..password", nil, 3, "catalog..password", nil},
+		{"catalog.book.title[.='B']", nil, 1, "catalog.book.title", []interface{}{"A", "C", "D"}},
+		{"catalog.book", nil, 1, "catalog.book", nil},
+		{"catalog.book[9]", nil, 0, "catalog.book.title", []interface{}{"A", "B", "C", "D"}},
+	}
+	for _, tt := range tests {
+		m, err := NewMapXml(removeData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := m.RemoveValuesForPath(tt.path, tt.subkeys...)
+		if err != nil {
+			t.Fatal(tt.path, err)
+		}
+		if n != tt.n {
+			t.Errorf("%s %v: %d removed, want %d", tt.path, tt.subkeys, n, tt.n)
+		}
+		got, _ := m.ValuesForPath(tt.check)
+		if len(got) != 0 || len(tt.want) != 0 {
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s %v: %s is %v, want %v", tt.path, tt.subkeys, tt.check, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestRemoveValuesForPathCollapse(t *testing.T) {
+	fmt.Println("\n================== TestRemoveValuesForPathCollapse")
+	m, err := NewMapXml(removeData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.RemoveValuesForPath("catalog.book[1:]"); n != 3 {
+		t.Errorf("%d removed", n)
+	}
+	if _, ok := m["catalog"].(map[string]interface{})["book"].([]interface{}); !ok {
+		t.Errorf("not a list: %v", m)
+	}
+
+	m, _ = NewMapXml(removeData)
+	if n, _ := m.RemoveValuesForPathCollapse("catalog.book[1:]"); n != 3 {
+		t.Errorf("%d removed", n)
+	}
+	b, ok := m["catalog"].(map[string]interface{})["book"].(map[string]interface{})
+	if !ok || b["title"] != "A" {
+		t.Errorf("not collapsed: %v", m)
+	}
+
+	// a list in a list
+	m = Map{"a": []interface{}{[]interface{}{1, 2}, 3}}
+	if n, _ := m.RemoveValuesForPath("a[0][0]"); n != 1 || !reflect.DeepEqual(m["a"], []interface{}{[]interface{}{2}, 3}) {
+		t.Errorf("nested: %d, %v", n, m)
+	}
+}
+
+func TestRemoveErrors(t *testing.T) {
+	fmt.Println("\n================== TestRemoveErrors")
+	mv := Map{"a": map[string]interface{}{"b": 1}}
+	if err := mv.Remove("x.b"); err != PathNotExistError {
+		t.Errorf("x.b: %v", err)
+	}
+	if err := mv.Remove("a.c"); err != nil {
+		t.Errorf("a.c: %v", err)
+	}
+	if err := mv.Remove("a[x]"); err == nil {
+		t.Errorf("a[x]: no error")
+	}
+	if _, err := mv.RemoveValuesForPath("a", "b:1:int8"); err == nil {
+		t.Errorf("bad subkey: no error")
+	}
+}
@@ -2,12 +2,13 @@ package mxj
 
 import (
 	"errors"
-	"strings"
+	"fmt"
+	"reflect"
 )
 
 // RenameKey renames a key in a Map.
-// It works only for nested maps. 
-// It doesn't work for cases when the key is in a list.
+// The 'path' can have wildcards and indexes, and the key is renamed in every
+// list member, as for RenameKeys.
 func (mv Map) RenameKey(path string, newName string) error {
 	var v bool
 	var err error
@@ -16,46 +17,70 @@ func (mv Map) RenameKey(path string, newName string) error {
 	} else if err != nil {
 		return err
 	}
-	if v, err = mv.Exists(parentPath(path) + "." + newName); err == nil && v {
-		return errors.New("RenameKey: key already exists: " + newName)
-	} else if err != nil {
-		return err
-	}
-
-	m := map[string]interface{}(mv)
-	return renameKey(m, path, newName)
+	_, err = mv.RenameKeys(path, newName)
+	return err
 }
 
-func renameKey(m interface{}, path string, newName string) error {
-	val, err := prevValueByPath(m, path)
-	if err != nil {
-		return err
+// RenameKeys renames the last key of 'path' in all the maps that have it and
+// returns the number of keys renamed.
+//
+//	'path' is as for ValuesForPath() - the key is renamed in every list member
+//	       and every map a wildcard or "..key" matches:
+//	          n, err := m.RenameKeys("orders.order.custId", "customerId")
+//	          n, err := m.RenameKeys("doc.*[0].-id", "-ref")
+//	       The last key can't be a wildcard or have an index or predicate.
+//	'merge' (optional) - if a map has a 'newName' key already and 'merge' is 'true',
+//	       the values are merged into a list - the 'newName' value(s) first; otherwise,
+//	       an error is returned and no keys are renamed.
+func (mv Map) RenameKeys(path, newName string, merge ...bool) (int, error) {
+	var doMerge bool
+	if len(merge) == 1 {
+		doMerge = merge[0]
+	}
+	if newName == "" {
+		return 0, errors.New("RenameKeys: no newName")
 	}
 
-	oldName := lastKey(path)
-	val[newName] = val[oldName]
-	delete(val, oldName)
-
-	return nil
-}
-
-// returns a value which contains a last key in the path
-// For example: prevValueByPath("a.b.c", {a{b{c: 3}}}) returns {c: 3}
-func prevValueByPath(m interface{}, path string) (map[string]interface{}, error) {
-	keys := strings.Split(path, ".")
+	keys, err := parsePath(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	last := keys[len(keys)-1]
+	if last.name == "*" || last.name == "" || len(last.sels) > 0 {
+		return 0, fmt.Errorf("RenameKeys: last key must be a key name: %s", path)
+	}
+	if last.name == newName {
+		return 0, nil
+	}
 
-	switch mValue := m.(type) {
-	case map[string]interface{}:
-		for key, value := range mValue {
-			if key == keys[0] {
-				if len(keys) == 1 {
-					return mValue, nil
-				} else {
-					// keep looking for the full path to the key
-					return prevValueByPath(value, strings.Join(keys[1:], "."))
-				}
+	// the maps with the key - once, even if "..key" located list members
+	type mapKey uintptr
+	seen := make(map[mapKey]bool)
+	var maps []map[string]interface{}
+	for _, l := range locsForPath(map[string]interface{}(mv), keys, true) {
+		if p := mapKey(reflect.ValueOf(l.m).Pointer()); !seen[p] {
+			seen[p] = true
+			maps = append(maps, l.m)
+		}
+	}
+	if !doMerge {
+		for _, m := range maps {
+			if _, ok := m[newName]; ok {
+				return 0, errors.New("RenameKeys: key already exists: " + newName)
 			}
 		}
 	}
-	return nil, errors.New("prevValueByPath: didn't find path – " + path)
+
+	for _, m := range maps {
+		v := m[last.name]
+		if old, ok := m[newName]; ok {
+			v = append(expandLists([]interface{}{old}), expandLists([]interface{}{v})...)
+		}
+		m[newName] = v
+		delete(m, last.name)
+	}
+	return len(maps), nil
 }
@@ -2,6 +2,7 @@ package mxj
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -39,4 +40,86 @@ func TestRenameKey(t *testing.T) {
 	if err == nil {
 		t.Fatal("should raise an error if the newName already exists")
 	}
+
+	// the collision is checked for the maps that have the key
+	mv, _ = NewMapXml([]byte(`<doc><e n="a.b"><k>1</k></e><e n="c"><k>2</k><j>0</j></e></doc>`))
+	if err = mv.RenameKey("doc.e["+attrPrefix+"n='a.b'].k", "j"); err != nil {
+		t.Fatal(err)
+	}
+	if err = mv.RenameKey("doc.e["+attrPrefix+"n='c'].k", "j"); err == nil {
+		t.Fatal("should raise an error if the newName already exists")
+	}
+	if v, _ := mv.ValuesForPath("doc.e.j"); len(v) != 2 {
+		t.Fatal("renamed:", v)
+	}
+}
+
+func TestRenameKeys(t *testing.T) {
+	fmt.Println("\n================== TestRenameKeys")
+	data := []byte(`<orders>
+		<order id="1"><custId>A1</custId><item>x</item></order>
+		<order id="2"><custId>B2</custId><item>y</item><customerId>old</customerId></order>
+		<order id="3"><item>z</item></order>
+	</orders>`)
+	m, err := NewMapXml(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// collision w/o merge - nothing is renamed
+	if n, err := m.RenameKeys("orders.order.custId", "customerId"); err == nil || n != 0 {
+		t.Fatal("collision:", n, err)
+	}
+	if v, _ := m.ValuesForPath("orders.order.custId"); len(v) != 2 {
+		t.Fatal("renamed on error:", v)
+	}
+
+	n, err := m.RenameKeys("orders.order.custId", "customerId", true)
+	if err != nil || n != 2 {
+		t.Fatal(n, err)
+	}
+	v, _ := m.ValuesForPath("orders.order[0].customerId")
+	if !reflect.DeepEqual(v, []interface{}{"A1"}) {
+		t.Errorf("order[0]: %v", v)
+	}
+	mv, _ := m.ValuesForPath("orders.order[1].customerId")
+	if !reflect.DeepEqual(mv, []interface{}{"old", "B2"}) {
+		t.Errorf("merged: %v", mv)
+	}
+
+	// indexes and wildcards
+	if n, err = m.RenameKeys("orders.order[-1].item", "product"); err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	if n, err = m.RenameKeys("*.order."+attrPrefix+"id", attrPrefix+"ref"); err != nil || n != 3 {
+		t.Fatal(n, err)
+	}
+	if n, err = m.RenameKeys("orders..item", "product"); err != nil || n != 2 {
+		t.Fatal(n, err)
+	}
+	if v, _ = m.ValuesForPath("orders.order.product"); !reflect.DeepEqual(v, []interface{}{"x", "y", "z"}) {
+		t.Errorf("products: %v", v)
+	}
+	if v, _ = m.ValuesForPath("orders.order." + attrPrefix + "ref"); len(v) != 3 {
+		t.Errorf("refs: %v", v)
+	}
+	fmt.Println(m)
+
+	if n, _ = m.RenameKeys("orders.order.none", "x"); n != 0 {
+		t.Errorf("none: %d", n)
+	}
+	if _, err = m.RenameKeys("orders.order[0]", "x"); err == nil {
+		t.Error("no error for index on last key")
+	}
+	if _, err = m.RenameKeys("orders.*", "x"); err == nil {
+		t.Error("no error for wildcard last key")
+	}
+
+	// RenameKey works in lists, too
+	if err = m.RenameKey("orders.order.product", "item"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ = m.ValuesForPath("orders.order.item"); len(v) != 3 {
+		t.Errorf("RenameKey: %v", v)
+	}
 }
@@ -1,10 +1,14 @@
 package mxj
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 )
 
-// Sets the value for the path
+// Sets the value for the path.
+// If the parent value isn't a map[string]interface{} value the error is a *SetPathError
+// that wraps PathNotMapError.  (See SetValueForPathCreate to build the path as needed.)
 func (mv Map) SetValueForPath(value interface{}, path string) error {
 	pathAry := strings.Split(path, ".")
 	parentPathAry := pathAry[0 : len(pathAry)-1]
@@ -19,8 +23,237 @@ func (mv Map) SetValueForPath(value interface{}, path string) error {
 	}
 
 	key := pathAry[len(pathAry)-1]
-	cVal := val.(map[string]interface{})
+	cVal, ok := val.(map[string]interface{})
+	if !ok {
+		return &SetPathError{path, parentPath, val, PathNotMapError}
+	}
 	cVal[key] = value
 
 	return nil
 }
+
+var (
+	// PathNotMapError is wrapped by the *SetPathError from SetValueForPath, SetValueForPathCreate,
+	// CopyValue and MoveValue, and the *PatchError from ApplyPatch, for a key on a value that isn't a map.
+	PathNotMapError = errors.New("value in path is not a map")
+	// PathIndexError is wrapped by the *SetPathError from SetValueForPathCreate, CopyValue and
+	// MoveValue, and the *PatchError from ApplyPatch, for a list index that's out of range.
+	PathIndexError = errors.New("list index out of range")
+	// PathExistsError is wrapped by the *SetPathError from CopyValue and MoveValue when there's
+	// a value for 'to' and 'overwrite' isn't set.
+	PathExistsError = errors.New("value exists for path")
+)
+
+// SetPathError is returned when a value can't be set for a path.
+type SetPathError struct {
+	Path  string      // the path argument
+	Key   string      // the path to the key where it failed - e.g., "doc.items.item[3]"
+	Value interface{} // the value found there, if any
+	Err   error       // PathNotMapError, PathIndexError, etc., or a path syntax error
+}
+
+func (e *SetPathError) Error() string {
+	if e.Key == "" || e.Key == e.Path {
+		return fmt.Sprintf("set path %s: %s", e.Path, e.Err)
+	}
+	return fmt.Sprintf("set path %s: at %s: %s", e.Path, e.Key, e.Err)
+}
+
+// Unwrap returns PathNotMapError, etc.
+func (e *SetPathError) Unwrap() error {
+	return e.Err
+}
+
+// SetValueForPathCreate sets the value for the path, creating the missing
+// maps and lists on the way - as "mkdir -p" does for directories.
+//
+//	The path is dot-separated keys, each with an optional list index:
+//	   m := mxj.New()
+//	   m.SetValueForPathCreate("Gaddis", "doc.books.book[0].author")
+//	   m.SetValueForPathCreate("Hugo", "doc.books.book[1].author")
+//	   m.SetValueForPathCreate("en", "doc.books.book[1].-lang")
+//	An index can be that of an existing member, len(list) to append a member, or -1
+//	for the last member.  A value that isn't in a list is member [0] of a list.
+//	If 'toList' is 'true':
+//	   - a value that isn't in a list is made a list for index [1];
+//	   - if the last key has no index and there's a value for it already, 'value' is
+//	     appended - "doc.books.book" adds another book.
+//	The intermediate values must be maps; a simple value - e.g., a string -
+//	isn't replaced.  The error for a value that can't be set is a *SetPathError; the maps
+//	created before the error was found are kept.
+func (mv Map) SetValueForPathCreate(value interface{}, path string, toList ...bool) error {
+	var asList bool
+	if len(toList) == 1 {
+		asList = toList[0]
+	}
+
+	keys, err := parseSetPath(path)
+	if err != nil {
+		return &SetPathError{Path: path, Err: err}
+	}
+
+	m, at, err := createPath(map[string]interface{}(mv), path, keys[:len(keys)-1], asList)
+	if err != nil {
+		return err
+	}
+	k := keys[len(keys)-1]
+	at = k.at(at)
+
+	v, exists := m[k.name]
+	if k.index == nil {
+		switch {
+		case exists && asList:
+			if a, ok := v.([]interface{}); ok {
+				m[k.name] = append(a, value)
+			} else {
+				m[k.name] = []interface{}{v, value}
+			}
+		default:
+			m[k.name] = value
+		}
+		return nil
+	}
+
+	a, singleton := listFor(v, exists)
+	n := *k.index
+	if n < 0 {
+		n += len(a)
+	}
+	if n < 0 || n > len(a) || singleton && n == 1 && !asList {
+		return &SetPathError{path, at, v, PathIndexError}
+	}
+	if n == len(a) {
+		a = append(a, value)
+	} else {
+		a[n] = value
+	}
+	if singleton && len(a) == 1 {
+		m[k.name] = a[0]
+	} else {
+		m[k.name] = a
+	}
+	return nil
+}
+
+// createPath walks the path 'keys' in 'm', creating the maps and lists that are
+// missing, and returns the map at the end of it and the path to it.
+func createPath(m map[string]interface{}, path string, keys []setKey, asList bool) (map[string]interface{}, string, error) {
+	var at string // the path so far
+	for _, k := range keys {
+		at = k.at(at)
+
+		v, exists := m[k.name]
+		if k.index == nil {
+			if !exists {
+				nm := make(map[string]interface{})
+				m[k.name] = nm
+				m = nm
+				continue
+			}
+			nm, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, at, &SetPathError{path, at, v, PathNotMapError}
+			}
+			m = nm
+			continue
+		}
+
+		// an indexed key - get the list, making one if need be
+		a, singleton := listFor(v, exists)
+		n := *k.index
+		if n < 0 {
+			n += len(a)
+		}
+		if n < 0 || n > len(a) || singleton && n == 1 && !asList {
+			return nil, at, &SetPathError{path, at, v, PathIndexError}
+		}
+
+		var member interface{}
+		if n == len(a) {
+			// append a member
+			member = make(map[string]interface{})
+			a = append(a, member)
+		} else {
+			member = a[n]
+		}
+		if singleton && len(a) == 1 {
+			m[k.name] = a[0]
+		} else {
+			m[k.name] = a
+		}
+		nm, ok := member.(map[string]interface{})
+		if !ok {
+			return nil, at, &SetPathError{path, at, member, PathNotMapError}
+		}
+		m = nm
+	}
+	return m, at, nil
+}
+
+// listFor returns the value for an indexed key as a list; a value that isn't
+// a list is a 'singleton' list.
+func listFor(v interface{}, exists bool) (a []interface{}, singleton bool) {
+	switch vv := v.(type) {
+	case []interface{}:
+		return vv, false
+	case nil:
+		if exists {
+			return []interface{}{nil}, true
+		}
+		return nil, false
+	}
+	return []interface{}{v}, true
+}
+
+// setKey is a SetValueForPathCreate path key with an optional index.
+type setKey struct {
+	name  string
+	index *int
+}
+
+// at appends the key to the path 'at'.
+func (k setKey) at(at string) string {
+	if at != "" {
+		at += "."
+	}
+	at += k.name
+	if k.index != nil {
+		at += fmt.Sprintf("[%d]", *k.index)
+	}
+	return at
+}
+
+// parseSetPath parses the path for SetValueForPathCreate - keys with "[N]"
+// indexes, but no wildcards, slices or predicates.
+func parseSetPath(path string) ([]setKey, error) {
+	if strings.Index(path, "..") >= 0 {
+		return nil, fmt.Errorf("recursive descent not supported")
+	}
+	keys, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys in path")
+	}
+	sk := make([]setKey, len(keys))
+	for i, k := range keys {
+		if k.name == "" || k.name == "*" {
+			return nil, fmt.Errorf("key %q not supported", k.name)
+		}
+		sk[i].name = k.name
+		switch len(k.sels) {
+		case 0:
+		case 1:
+			n, ok := k.sels[0].(keyIndex)
+			if !ok {
+				return nil, fmt.Errorf("only [N] indexes supported: %s", k.name)
+			}
+			idx := int(n)
+			sk[i].index = &idx
+		default:
+			return nil, fmt.Errorf("only one [N] index supported: %s", k.name)
+		}
+	}
+	return sk, nil
+}
@@ -1,6 +1,9 @@
 package mxj
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -41,3 +44,110 @@ func TestSetValueForPath(t *testing.T) {
 		t.Fatal("existig key's value hasn't changed")
 	}
 }
+
+func TestSetValueForPathNotMap(t *testing.T) {
+	mv := Map{"a": map[string]interface{}{"b": "text"}}
+	err := mv.SetValueForPath("x", "a.b.c")
+	var serr *SetPathError
+	if !errors.As(err, &serr) || !errors.Is(err, PathNotMapError) || serr.Value != "text" {
+		t.Fatalf("err: %#v", err)
+	}
+	fmt.Println("err:", err)
+}
+
+func TestSetValueForPathCreate(t *testing.T) {
+	fmt.Println("\n================== TestSetValueForPathCreate")
+	m := New()
+	sets := []struct {
+		value interface{}
+		path  string
+	}{
+		{"Gaddis", "doc.books.book[0].author"},
+		{"The Recognitions", "doc.books.book[0].title"},
+		{"Hugo", "doc.books.book[1].author"},
+		{"fr", "doc.books.book[-1]." + attrPrefix + "lang"},
+		{"JR", "doc.books.book[2].title"},
+		{"new", "doc.note"},
+		{"a", "doc.tags.tag"},
+	}
+	for _, s := range sets {
+		if err := m.SetValueForPathCreate(s.value, s.path); err != nil {
+			t.Fatal(s.path, err)
+		}
+	}
+	// add list members
+	if err := m.SetValueForPathCreate("b", "doc.tags.tag", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetValueForPathCreate("c", "doc.tags.tag[2]"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetValueForPathCreate("other", "doc.note[1]", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetValueForPathCreate("NEW", "doc.note[0]"); err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := m.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(x))
+	want := Map{"doc": map[string]interface{}{
+		"books": map[string]interface{}{"book": []interface{}{
+			map[string]interface{}{"author": "Gaddis", "title": "The Recognitions"},
+			map[string]interface{}{"author": "Hugo", attrPrefix + "lang": "fr"},
+			map[string]interface{}{"title": "JR"},
+		}},
+		"note": []interface{}{"NEW", "other"},
+		"tags": map[string]interface{}{"tag": []interface{}{"a", "b", "c"}},
+	}}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got: %v\nwant: %v", m, want)
+	}
+
+	// a singleton is member [0]
+	m = Map{"a": map[string]interface{}{"b": "x"}}
+	if err := m.SetValueForPathCreate("y", "a[0].b"); err != nil || m["a"].(map[string]interface{})["b"] != "y" {
+		t.Errorf("singleton: %v, %v", m, err)
+	}
+}
+
+func TestSetValueForPathCreateErrors(t *testing.T) {
+	fmt.Println("\n================== TestSetValueForPathCreateErrors")
+	m := Map{"doc": map[string]interface{}{
+		"s":    "text",
+		"list": []interface{}{"a", "b"},
+		"one":  map[string]interface{}{"v": 1},
+	}}
+	tests := []struct {
+		path string
+		err  error
+		key  string
+	}{
+		{"doc.s.x", PathNotMapError, "doc.s"},
+		{"doc.list[0].x", PathNotMapError, "doc.list[0]"},
+		{"doc.list[3]", PathIndexError, "doc.list[3]"},
+		{"doc.list[-3]", PathIndexError, "doc.list[-3]"},
+		{"doc.one[1].v", PathIndexError, "doc.one[1]"},
+		{"doc.new[1]", PathIndexError, "doc.new[1]"},
+	}
+	for _, tt := range tests {
+		err := m.SetValueForPathCreate("x", tt.path)
+		fmt.Println(tt.path, "=>", err)
+		var serr *SetPathError
+		if !errors.As(err, &serr) || !errors.Is(err, tt.err) || serr.Key != tt.key {
+			t.Errorf("%s: %#v", tt.path, err)
+		}
+	}
+	for _, path := range []string{"doc.*.x", "doc..x", "doc.list[0:1]", "doc.list[a='b']", ""} {
+		if err := m.SetValueForPathCreate("x", path); err == nil {
+			t.Errorf("%s: no error", path)
+		}
+	}
+	// nothing was changed
+	if len(m["doc"].(map[string]interface{})) != 3 || len(m["doc"].(map[string]interface{})["list"].([]interface{})) != 2 {
+		t.Errorf("changed: %v", m)
+	}
+}
@@ -20,11 +20,11 @@ import (
 var CustomDecoder *xml.Decoder
 
 // useCustomDecoder copy over public attributes from customDecoder
-func useCustomDecoder(d *xml.Decoder) {
-	d.Strict = CustomDecoder.Strict
-	d.AutoClose = CustomDecoder.AutoClose
-	d.Entity = CustomDecoder.Entity
-	d.CharsetReader = CustomDecoder.CharsetReader
-	d.DefaultSpace = CustomDecoder.DefaultSpace
+func useCustomDecoder(d *xml.Decoder, customDecoder *xml.Decoder) {
+	d.Strict = customDecoder.Strict
+	d.AutoClose = customDecoder.AutoClose
+	d.Entity = customDecoder.Entity
+	d.CharsetReader = customDecoder.CharsetReader
+	d.DefaultSpace = customDecoder.DefaultSpace
 }
 
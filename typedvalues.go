@@ -0,0 +1,270 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// typedvalues.go - get the value for a path as an int64, float64, bool, etc.,
+// whatever type the decoder produced.
+
+package mxj
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PathTypeError is returned by IntForPath(), FloatForPath(), etc., when the
+// value for the path can't be converted to the requested type.
+type PathTypeError struct {
+	Path  string      // the path argument
+	Type  string      // the requested type - "int64", "float64", "bool", ...
+	Value interface{} // the value for the path, with any "#text" value unwrapped
+	Err   error       // the conversion error, if any
+}
+
+func (e *PathTypeError) Error() string {
+	s := fmt.Sprintf("path %s: can't convert %T value %v to %s", e.Path, e.Value, e.Value, e.Type)
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the conversion error, if any - e.g., a *strconv.NumError.
+func (e *PathTypeError) Unwrap() error {
+	return e.Err
+}
+
+// IntForPath returns the first value for the path as an int64.
+//
+//	The value can be any of the numeric types the decoders produce - int64, uint64,
+//	float64 or json.Number - or a string, e.g. from NewMapXml() without 'cast';
+//	float64 values must be whole numbers.  For an element with attributes, the
+//	"#text" value is used - so "book.price" and "book.price.#text" are the same.
+//	If there's no value, PathNotExistError is returned; if the value can't be
+//	converted, the error is a *PathTypeError.
+func (mv Map) IntForPath(path string) (int64, error) {
+	v, err := mv.textValueForPath(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := toInt64(v)
+	if err != nil {
+		return 0, &PathTypeError{path, "int64", v, errOrNil(err)}
+	}
+	return n, nil
+}
+
+// FloatForPath returns the first value for the path as a float64.
+// See IntForPath() for how the value is handled.
+func (mv Map) FloatForPath(path string) (float64, error) {
+	v, err := mv.textValueForPath(path)
+	if err != nil {
+		return 0, err
+	}
+	f, err := toFloat64(v)
+	if err != nil {
+		return 0, &PathTypeError{path, "float64", v, errOrNil(err)}
+	}
+	return f, nil
+}
+
+// BoolForPath returns the first value for the path as a bool.
+// Strings are converted using strconv.ParseBool; the numbers 1 and 0 - as
+// cast by NewMapXml() - are 'true' and 'false'.  See IntForPath().
+func (mv Map) BoolForPath(path string) (bool, error) {
+	v, err := mv.textValueForPath(path)
+	if err != nil {
+		return false, err
+	}
+	switch vv := v.(type) {
+	case bool:
+		return vv, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(vv))
+		if err != nil {
+			return false, &PathTypeError{path, "bool", v, err}
+		}
+		return b, nil
+	}
+	if f, err := toFloat64(v); err == nil && (f == 0 || f == 1) {
+		return f == 1, nil
+	}
+	return false, &PathTypeError{path, "bool", v, nil}
+}
+
+// TimeForPath returns the first value for the path as a time.Time.
+// A string value is parsed with each 'layout' in turn - see time.Parse - or,
+// if no layout is given, as time.RFC3339.  See IntForPath().
+func (mv Map) TimeForPath(path string, layout ...string) (time.Time, error) {
+	v, err := mv.textValueForPath(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch vv := v.(type) {
+	case time.Time:
+		return vv, nil
+	case string:
+		if len(layout) == 0 {
+			layout = []string{time.RFC3339}
+		}
+		var t time.Time
+		for _, l := range layout {
+			if t, err = time.Parse(l, strings.TrimSpace(vv)); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, &PathTypeError{path, "time.Time", v, err}
+	}
+	return time.Time{}, &PathTypeError{path, "time.Time", v, nil}
+}
+
+// DurationForPath returns the first value for the path as a time.Duration.
+// A string value is parsed with time.ParseDuration - e.g., "1m30s" - and a
+// number is a count of seconds.  See IntForPath().
+func (mv Map) DurationForPath(path string) (time.Duration, error) {
+	v, err := mv.textValueForPath(path)
+	if err != nil {
+		return 0, err
+	}
+	if s, ok := v.(string); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return 0, &PathTypeError{path, "time.Duration", v, err}
+		}
+		return d, nil
+	}
+	f, err := toFloat64(v)
+	if err != nil || math.Abs(f) > math.MaxInt64/float64(time.Second) {
+		return 0, &PathTypeError{path, "time.Duration", v, nil}
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
+
+// StringsForPath returns all the values for the path as strings - e.g., the
+// members of a list.  Numbers, bools, etc., are formatted with fmt.Sprint()
+// and time.Time values as time.RFC3339Nano.  The 'path' and 'subkeys' are as
+// for ValuesForPath() and, as there, it's not an error if there are no values.
+// A value that isn't a string or simple type - e.g., an element with
+// sub-elements - is a *PathTypeError.
+func (mv Map) StringsForPath(path string, subkeys ...string) ([]string, error) {
+	vals, err := mv.ValuesForPath(path, subkeys...)
+	if err != nil {
+		return nil, err
+	}
+	ss := make([]string, len(vals))
+	for i, v := range vals {
+		v = textValue(v)
+		s, ok := castString(v)
+		if !ok {
+			return nil, &PathTypeError{path, "string", v, nil}
+		}
+		ss[i] = s
+	}
+	return ss, nil
+}
+
+// textValueForPath returns the first value for the path with any "#text" value unwrapped.
+func (mv Map) textValueForPath(path string) (interface{}, error) {
+	v, err := mv.ValueForPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return textValue(v), nil
+}
+
+// textValue returns the "#text" value of an element with attributes - or of a
+// MapSeq value - or 'v'.
+func textValue(v interface{}) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		if t, ok := m[textK]; ok {
+			return t
+		}
+	}
+	return v
+}
+
+// errNotConvertible flags conversions that fail without a conversion error to report.
+var errNotConvertible = fmt.Errorf("not convertible")
+
+func errOrNil(err error) error {
+	if err == errNotConvertible {
+		return nil
+	}
+	return err
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch vv := v.(type) {
+	case int64:
+		return vv, nil
+	case int:
+		return int64(vv), nil
+	case int32:
+		return int64(vv), nil
+	case uint64:
+		if vv > math.MaxInt64 {
+			return 0, strconv.ErrRange
+		}
+		return int64(vv), nil
+	case float64:
+		return floatToInt64(vv)
+	case float32:
+		return floatToInt64(float64(vv))
+	case json.Number:
+		if n, err := vv.Int64(); err == nil {
+			return n, nil
+		}
+		f, err := vv.Float64()
+		if err != nil {
+			return 0, err
+		}
+		return floatToInt64(f)
+	case string:
+		s := strings.TrimSpace(vv)
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err == nil {
+			return n, nil
+		}
+		if f, ferr := strconv.ParseFloat(s, 64); ferr == nil {
+			return floatToInt64(f)
+		}
+		return 0, err
+	}
+	return 0, errNotConvertible
+}
+
+func floatToInt64(f float64) (int64, error) {
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("not a whole number")
+	}
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, strconv.ErrRange
+	}
+	return int64(f), nil
+}
+
+func toFloat64(v interface{}) (float64, error) {
+	switch vv := v.(type) {
+	case float64:
+		return vv, nil
+	case float32:
+		return float64(vv), nil
+	case int64:
+		return float64(vv), nil
+	case int:
+		return float64(vv), nil
+	case int32:
+		return float64(vv), nil
+	case uint64:
+		return float64(vv), nil
+	case json.Number:
+		return vv.Float64()
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(vv), 64)
+	}
+	return 0, errNotConvertible
+}
@@ -0,0 +1,157 @@
+package mxj
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var typedData = []byte(`<config version="3">
+	<name>server</name>
+	<port>8080</port>
+	<ratio unit="pct">0.75</ratio>
+	<debug>true</debug>
+	<verbose>1</verbose>
+	<started>2026-10-16T09:30:00Z</started>
+	<day>2026-10-16</day>
+	<timeout>1m30s</timeout>
+	<retry>2.5</retry>
+	<host>a.example.com</host>
+	<host>b.example.com</host>
+	<host>10</host>
+</config>`)
+
+func TestTypedForPath(t *testing.T) {
+	fmt.Println("\n================== TestTypedForPath")
+	for _, cast := range []bool{false, true} {
+		m, err := NewMapXml(typedData, cast)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := m.IntForPath("config.port"); err != nil || n != 8080 {
+			t.Errorf("cast %v: port: %d, %v", cast, n, err)
+		}
+		if n, err := m.IntForPath("config." + attrPrefix + "version"); err != nil || n != 3 {
+			t.Errorf("cast %v: version: %d, %v", cast, n, err)
+		}
+		// element with attributes - "#text" is unwrapped
+		if f, err := m.FloatForPath("config.ratio"); err != nil || f != 0.75 {
+			t.Errorf("cast %v: ratio: %v, %v", cast, f, err)
+		}
+		if f, err := m.FloatForPath("config.ratio." + textK); err != nil || f != 0.75 {
+			t.Errorf("cast %v: ratio.#text: %v, %v", cast, f, err)
+		}
+		if b, err := m.BoolForPath("config.debug"); err != nil || !b {
+			t.Errorf("cast %v: debug: %v, %v", cast, b, err)
+		}
+		if b, err := m.BoolForPath("config.verbose"); err != nil || !b {
+			t.Errorf("cast %v: verbose: %v, %v", cast, b, err)
+		}
+		want := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+		if tm, err := m.TimeForPath("config.started"); err != nil || !tm.Equal(want) {
+			t.Errorf("cast %v: started: %v, %v", cast, tm, err)
+		}
+		if tm, err := m.TimeForPath("config.day", time.RFC3339, "2006-01-02"); err != nil || tm.Day() != 16 {
+			t.Errorf("cast %v: day: %v, %v", cast, tm, err)
+		}
+		if d, err := m.DurationForPath("config.timeout"); err != nil || d != 90*time.Second {
+			t.Errorf("cast %v: timeout: %v, %v", cast, d, err)
+		}
+		if d, err := m.DurationForPath("config.retry"); cast && (err != nil || d != 2500*time.Millisecond) {
+			t.Errorf("cast %v: retry: %v, %v", cast, d, err)
+		}
+		ss, err := m.StringsForPath("config.host")
+		if err != nil || fmt.Sprint(ss) != "[a.example.com b.example.com 10]" {
+			t.Errorf("cast %v: host: %v, %v", cast, ss, err)
+		}
+	}
+}
+
+func TestTypedForPathJson(t *testing.T) {
+	fmt.Println("\n================== TestTypedForPathJson")
+	data := []byte(`{"a":{"n":42, "big":18446744073709551615, "f":1.5, "s":"7", "list":[1, "x", true]}}`)
+	m, err := NewMapJson(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := m.IntForPath("a.n"); err != nil || n != 42 {
+		t.Errorf("n: %d, %v", n, err)
+	}
+	if n, err := m.IntForPath("a.s"); err != nil || n != 7 {
+		t.Errorf("s: %d, %v", n, err)
+	}
+	if ss, err := m.StringsForPath("a.list"); err != nil || fmt.Sprint(ss) != "[1 x true]" {
+		t.Errorf("list: %v, %v", ss, err)
+	}
+
+	JsonUseNumber = true
+	m, err = NewMapJson(data)
+	JsonUseNumber = false
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["a"].(map[string]interface{})["n"].(json.Number); !ok {
+		t.Fatal("not a json.Number")
+	}
+	if n, err := m.IntForPath("a.n"); err != nil || n != 42 {
+		t.Errorf("json.Number n: %d, %v", n, err)
+	}
+	if f, err := m.FloatForPath("a.f"); err != nil || f != 1.5 {
+		t.Errorf("json.Number f: %v, %v", f, err)
+	}
+	// out of range for int64
+	_, err = m.IntForPath("a.big")
+	fmt.Println("err:", err)
+	var perr *PathTypeError
+	if !errors.As(err, &perr) || perr.Path != "a.big" || perr.Type != "int64" {
+		t.Errorf("big: %#v", err)
+	}
+}
+
+func TestTypedForPathErrors(t *testing.T) {
+	fmt.Println("\n================== TestTypedForPathErrors")
+	m, err := NewMapXml(typedData, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.IntForPath("config.nothere")
+	if err != PathNotExistError {
+		t.Errorf("nothere: %v", err)
+	}
+
+	_, err = m.IntForPath("config.name")
+	fmt.Println("err:", err)
+	var perr *PathTypeError
+	if !errors.As(err, &perr) {
+		t.Fatalf("name: %#v", err)
+	}
+	if perr.Path != "config.name" || perr.Type != "int64" || perr.Value != "server" {
+		t.Errorf("name: %#v", perr)
+	}
+	var nerr *strconv.NumError
+	if !errors.As(err, &nerr) {
+		t.Errorf("no *strconv.NumError: %#v", perr.Err)
+	}
+
+	// float64 that isn't a whole number
+	if _, err = m.IntForPath("config.ratio"); !errors.As(err, &perr) {
+		t.Errorf("ratio: %v", err)
+	}
+	// bool for a number other than 0 or 1
+	if _, err = m.BoolForPath("config.port"); !errors.As(err, &perr) || perr.Value != float64(8080) {
+		t.Errorf("port: %v", err)
+	}
+	// time for a number
+	if _, err = m.TimeForPath("config.port"); !errors.As(err, &perr) || perr.Type != "time.Time" {
+		t.Errorf("port: %v", err)
+	}
+	// element with sub-elements
+	if _, err = m.StringsForPath("config"); !errors.As(err, &perr) || perr.Type != "string" {
+		t.Errorf("config: %v", err)
+	}
+	fmt.Println("err:", err)
+}
@@ -3,7 +3,6 @@
 // license that can be found in the LICENSE file
 
 // updatevalues.go - modify a value based on path and possibly sub-keys
-// TODO(clb): handle simple elements with attributes and NewMapXmlSeq Map values.
 
 package mxj
 
@@ -19,7 +18,9 @@ import (
 //	'newVal' can be a Map or map[string]interface{} value with a single 'key' that is the key to be modified
 //	             or a string value "key:value[:type]" where type is "bool" or "num" to cast the value.
 //	'path' is dot-notation list of keys to traverse; last key in path can be newVal key
-//	       NOTE: 'path' spec does not currently support indexed array references.
+//	       - 'path' can contain wildcards, indexed array references, slices, predicates and
+//	         "..key" recursive descent as for ValuesForPath() - e.g., "doc.books.book[1].title",
+//	         "doc.books.book[-1]" or "doc.books.book[author='Gaddis'].price".
 //	'subkeys' are "key:value[:type]" entries that must match for path node
 //             - For attributes prefix the label with the attribute prefix character, by default a 
 //               hyphen, '-', e.g., "-seq:3". (See SetAttrPrefix function.)
@@ -28,8 +29,12 @@ import (
 //	              exclusion critera - e.g., "!author:William T. Gaddis".
 //
 //	NOTES:
-//		1. Simple elements with attributes need a path terminated as ".#text" to modify the actual value.
-//		2. Values in Maps created using NewMapXmlSeq are map[string]interface{} values with a "#text" key.
+//		1. For simple elements with attributes a simple 'newVal' value is the new "#text" value;
+//		   the attributes are kept.  A path terminated as ".#text" modifies the value, as well.
+//		2. Values in Maps created using NewMapXmlSeq are map[string]interface{} values with a "#text" key;
+//		   as for elements with attributes, a simple 'newVal' value is the new "#text" value and the
+//		   "#seq" value is kept - a map 'newVal' value gets the "#seq" value of the value it replaces.
+//		   (See msv.UpdateValuesForPath().)
 //		3. If values in 'newVal' or 'subkeys' args contain ":", use SetFieldSeparator to an unused symbol,
 //	      perhaps "|".
 func (mv Map) UpdateValuesForPath(newVal interface{}, path string, subkeys ...string) (int, error) {
@@ -90,6 +95,11 @@ func (mv Map) UpdateValuesForPath(newVal interface{}, path string, subkeys ...st
 		return 0, fmt.Errorf("invalid newVal type - %+v", newVal)
 	}
 
+	// indexed arrays, etc., in path - locate the values to update
+	if strings.Index(path, "[") >= 0 || strings.Index(path, "..") >= 0 {
+		return updateValuesForLocs(key, val, m, path, subKeyMap)
+	}
+
 	// parse path
 	keys := strings.Split(path, ".")
 
@@ -99,6 +109,94 @@ func (mv Map) UpdateValuesForPath(newVal interface{}, path string, subkeys ...st
 	return count, nil
 }
 
+// UpdateValuesForPath updates the values for a path in a MapSeq value; the
+// "#seq" values are kept.  See mv.UpdateValuesForPath().
+//
+//	A simple 'newVal' value replaces the "#text" value of the elements - or of
+//	the attributes, with paths like "doc.book.#attr.id":
+//	   msv.UpdateValuesForPath("author:William Gaddis", "doc.books.book[0].author")
+func (msv MapSeq) UpdateValuesForPath(newVal interface{}, path string, subkeys ...string) (int, error) {
+	return Map(msv).UpdateValuesForPath(newVal, path, subkeys...)
+}
+
+// updateValuesForLocs updates the values for a path with indexes, etc.
+func updateValuesForLocs(key string, value interface{}, m map[string]interface{}, path string, subkeys map[string]interface{}) (int, error) {
+	keys, err := parsePath(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	var count int
+	for _, l := range locsForPath(m, keys, true) {
+		if len(l.idx) == 0 {
+			// not a selected list member - as for the last key in a path w/o indexes
+			updateValue(key, value, l.m, l.key, subkeys, &count)
+			continue
+		}
+		// a list member: replace it or its 'key' value
+		v := l.value()
+		if key == l.key {
+			if hasSubKeys(v, subkeys) {
+				l.set(replaceValue(v, value))
+				count++
+			}
+			continue
+		}
+		if mm, ok := v.(map[string]interface{}); ok && hasSubKeys(mm, subkeys) {
+			if old, ok := mm[key]; ok {
+				mm[key] = replaceValue(old, value)
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// replaceValue returns the value that replaces 'old' - 'value' or, if 'old' is a
+// simple element with attributes or a MapSeq element and 'value' is a simple
+// value, 'old' with 'value' as its "#text" value.  A map 'value' that replaces
+// a MapSeq element is a copy with the "#seq" value of 'old'.
+func replaceValue(old, value interface{}) interface{} {
+	om, ok := old.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	switch nv := value.(type) {
+	case map[string]interface{}:
+		seq, ok := om[seqK]
+		if !ok {
+			return value
+		}
+		if _, ok := nv[seqK]; ok {
+			return value
+		}
+		nm := make(map[string]interface{}, len(nv)+1)
+		for k, v := range nv {
+			nm[k] = v
+		}
+		nm[seqK] = seq
+		return nm
+	case []interface{}, Map:
+		return value
+	}
+	if len(om) == 0 {
+		return value
+	}
+	for k := range om {
+		switch {
+		case k == textK, k == seqK, k == attrK:
+		case attrPrefix != "" && strings.HasPrefix(k, attrPrefix):
+		default:
+			return value // has sub-elements
+		}
+	}
+	om[textK] = value
+	return om
+}
+
 // navigate the path
 func updateValuesForKeyPath(key string, value interface{}, m interface{}, keys []string, subkeys map[string]interface{}, cnt *int) {
 	// ----- at end node: looking at possible node to get 'key' ----
@@ -170,7 +268,7 @@ func updateValue(key string, value interface{}, m interface{}, keys0 string, sub
 			switch endVal.(type) {
 			case map[string]interface{}:
 				if hasSubKeys(m, subkeys) {
-					(m.(map[string]interface{}))[keys0] = value
+					(m.(map[string]interface{}))[keys0] = replaceValue(endVal, value)
 					(*cnt)++
 				}
 			case []interface{}:
@@ -187,7 +285,7 @@ func updateValue(key string, value interface{}, m interface{}, keys0 string, sub
 					// check entry subkeys
 					if hasSubKeys(v, subkeys) {
 						// replace v with value
-						nv = append(nv, value)
+						nv = append(nv, replaceValue(v, value))
 						valmodified = true
 						(*cnt)++
 						continue
@@ -214,8 +312,8 @@ func updateValue(key string, value interface{}, m interface{}, keys0 string, sub
 			if !hasSubKeys(endVal, subkeys) {
 				return
 			}
-			if _, ok := (endVal.(map[string]interface{}))[key]; ok {
-				(endVal.(map[string]interface{}))[key] = value
+			if old, ok := (endVal.(map[string]interface{}))[key]; ok {
+				(endVal.(map[string]interface{}))[key] = replaceValue(old, value)
 				(*cnt)++
 			}
 		case []interface{}: // keys0 points to a list, check subkeys
@@ -225,13 +323,14 @@ func updateValue(key string, value interface{}, m interface{}, keys0 string, sub
 				if !vok {
 					continue
 				}
-				if _, ok := vv[key]; !ok {
+				old, ok := vv[key]
+				if !ok {
 					continue
 				}
 				if !hasSubKeys(vv, subkeys) {
 					continue
 				}
-				vv[key] = value
+				vv[key] = replaceValue(old, value)
 				(*cnt)++
 			}
 		}
@@ -243,13 +342,14 @@ func updateValue(key string, value interface{}, m interface{}, keys0 string, sub
 			if !ok {
 				continue
 			}
-			if _, ok := mm[key]; !ok {
+			old, ok := mm[key]
+			if !ok {
 				continue
 			}
 			if !hasSubKeys(mm, subkeys) {
 				continue
 			}
-			mm[key] = value
+			mm[key] = replaceValue(old, value)
 			(*cnt)++
 		}
 	}
@@ -0,0 +1,153 @@
+package mxj
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var updateData = []byte(`<doc>
+	<books>
+		<book id="b1"><title>The Recognitions</title><author>Gaddis</author><price currency="USD">12</price></book>
+		<book id="b2"><title>Les Misérables</title><author>Hugo</author><price currency="EUR">8</price></book>
+		<book id="b3"><title>JR</title><author>Gaddis</author><price currency="USD">15</price></book>
+	</books>
+	<note lang="en">old</note>
+</doc>`)
+
+func TestUpdateValuesForPathIndexed(t *testing.T) {
+	fmt.Println("\n================== TestUpdateValuesForPathIndexed")
+	m, err := NewMapXml(updateData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ap := attrPrefix
+
+	updates := []struct {
+		newVal interface{}
+		path   string
+		n      int
+	}{
+		{"title:Carpenter's Gothic", "doc.books.book[2].title", 1},
+		{"author:William Gaddis", "doc.books.book[author='Gaddis']", 2},
+		{"price:20", "doc.books.book[-1].price", 1}, // the "#text" value
+		{ap + "id:B2", "doc.books.book[1]", 1},
+		{"price:9", "doc.*.book[" + ap + "id='B2'].price", 1},
+		{"title:none", "doc.books.book[5].title", 0},
+		{"note:new", "doc..note", 1},
+	}
+	for _, u := range updates {
+		n, err := m.UpdateValuesForPath(u.newVal, u.path)
+		if err != nil {
+			t.Fatal(u.path, err)
+		}
+		if n != u.n {
+			t.Errorf("%v, %s: %d updates, want %d", u.newVal, u.path, n, u.n)
+		}
+	}
+	fmt.Println(m)
+
+	checks := map[string]interface{}{
+		"doc.books.book[2].title":                    "Carpenter's Gothic",
+		"doc.books.book[0].author":                   "William Gaddis",
+		"doc.books.book[1].author":                   "Hugo",
+		"doc.books.book[2].author":                   "William Gaddis",
+		"doc.books.book[2].price." + textK:           "20",
+		"doc.books.book[2].price." + ap + "currency": "USD",
+		"doc.books.book[1]." + ap + "id":             "B2",
+		"doc.books.book[1].price." + textK:           "9",
+		"doc.note." + textK:                          "new",
+		"doc.note." + ap + "lang":                    "en",
+	}
+	for path, want := range checks {
+		v, err := m.ValueForPath(path)
+		if err != nil || v != want {
+			t.Errorf("%s: got %v, %v, want %v", path, v, err, want)
+		}
+	}
+
+	// replace a list member
+	n, err := m.UpdateValuesForPath(map[string]interface{}{"book": map[string]interface{}{"title": "New"}}, "doc.books.book[0]")
+	if err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	if v, _ := m.ValuesForPath("doc.books.book.title"); !reflect.DeepEqual(v, []interface{}{"New", "Les Misérables", "Carpenter's Gothic"}) {
+		t.Errorf("titles: %v", v)
+	}
+
+	// subkeys, too
+	n, _ = m.UpdateValuesForPath("title:Newer", "doc.books.book[0:]", "title:New")
+	if n != 1 {
+		t.Errorf("subkeys: %d updates", n)
+	}
+
+	if _, err := m.UpdateValuesForPath("title:x", "doc.books.book[x].title"); err == nil || !strings.Contains(err.Error(), "cannot convert index") {
+		t.Errorf("bad path: %v", err)
+	}
+}
+
+func TestUpdateValuesForPathSeq(t *testing.T) {
+	fmt.Println("\n================== TestUpdateValuesForPathSeq")
+	msv, err := NewMapXmlSeq(updateData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := msv.UpdateValuesForPath("author:William Gaddis", "doc.books.book.author", "#text:Gaddis"); err != nil || n != 0 {
+		// subkeys are for the member, not its "#text" value
+		t.Errorf("subkeys: %d, %v", n, err)
+	}
+	for _, u := range []struct {
+		newVal interface{}
+		path   string
+		n      int
+	}{
+		{"author:William Gaddis", "doc.books.book[author='Gaddis'].author", 2},
+		{"title:JR (1975)", "doc.books.book[-1]", 1},
+		{"id:B2", "doc.books.book[1]." + attrK, 1},
+		{"note:new", "doc.note", 1},
+		{map[string]interface{}{"price": map[string]interface{}{textK: "9"}}, "doc.books.book[1]", 1},
+	} {
+		n, err := msv.UpdateValuesForPath(u.newVal, u.path)
+		if err != nil {
+			t.Fatal(u.path, err)
+		}
+		if n != u.n {
+			t.Errorf("%v, %s: %d updates, want %d", u.newVal, u.path, n, u.n)
+		}
+	}
+	fmt.Println(msv.StringIndent())
+
+	m := Map(msv)
+	checks := map[string]interface{}{
+		"doc.books.book[0].author." + textK:           "William Gaddis",
+		"doc.books.book[0].author." + seqK:            1,
+		"doc.books.book[2].title." + textK:            "JR (1975)",
+		"doc.books.book[2].title." + seqK:             0,
+		"doc.books.book[1]." + attrK + ".id." + textK: "B2",
+		"doc.books.book[1].price." + textK:            "9",
+		"doc.books.book[1].price." + seqK:             2,
+		"doc.note." + textK:                           "new",
+		"doc.note." + seqK:                            1,
+	}
+	for path, want := range checks {
+		v, err := m.ValueForPath(path)
+		if err != nil || v != want {
+			t.Errorf("%s: got %v, %v, want %v", path, v, err, want)
+		}
+	}
+
+	b, err := msv.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(b))
+	// a map value replaces the element, attributes and all
+	if !strings.Contains(string(b), `<book id="B2"><title>Les Misérables</title><author>Hugo</author><price>9</price></book>`) {
+		t.Errorf("xml: %s", b)
+	}
+	if !strings.HasSuffix(string(b), `<note lang="en">new</note></doc>`) {
+		t.Errorf("xml: %s", b)
+	}
+}
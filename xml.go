@@ -31,9 +31,10 @@ var (
 	procinstK  = "#procinst"
 	targetK    = "#target"
 	instK      = "#inst"
+	cdataK     = "#cdata"
 )
 
-// Support overriding default Map keys prefix
+// Support overriding default Map keys prefix - "#seq", "#attr", "#cdata", etc.
 
 func SetGlobalKeyMapPrefix(s string) {
 	textK = strings.ReplaceAll(textK, textK[0:1], s)
@@ -44,6 +45,7 @@ func SetGlobalKeyMapPrefix(s string) {
 	targetK = strings.ReplaceAll(targetK, targetK[0:1], s)
 	instK = strings.ReplaceAll(instK, instK[0:1], s)
 	attrK = strings.ReplaceAll(attrK, attrK[0:1], s)
+	cdataK = strings.ReplaceAll(cdataK, cdataK[0:1], s)
 }
 
 // ------------------- NewMapXml & NewMapXmlReader ... -------------------------
@@ -84,12 +86,11 @@ var XmlCharsetReader func(charset string, input io.Reader) (io.Reader, error)
 //	   3. If CoerceKeysToLower() has been called, then all key values will be lower case.
 //	   4. If CoerceKeysToSnakeCase() has been called, then all key values will be converted to snake case.
 //	   5. If DisableTrimWhiteSpace(b bool) has been called, then all values will be trimmed or not. 'true' by default.
+//	   6. The package-level settings are the defaults; use a DecoderOptions value for per-call settings.
+//	   7. Name space prefixes are dropped from keys; set DecoderOptions.Namespaces to keep them
+//	      as "prefix:local" or "{uri}local" keys.
 func NewMapXml(xmlVal []byte, cast ...bool) (Map, error) {
-	var r bool
-	if len(cast) == 1 {
-		r = cast[0]
-	}
-	return xmlToMap(xmlVal, r)
+	return decoderOptions(cast).NewMapXml(xmlVal)
 }
 
 // Get next XML doc from an io.Reader as a Map value.  Returns Map value.
@@ -101,20 +102,7 @@ func NewMapXml(xmlVal []byte, cast ...bool) (Map, error) {
 //	   3. If CoerceKeysToLower() has been called, then all key values will be lower case.
 //	   4. If CoerceKeysToSnakeCase() has been called, then all key values will be converted to snake case.
 func NewMapXmlReader(xmlReader io.Reader, cast ...bool) (Map, error) {
-	var r bool
-	if len(cast) == 1 {
-		r = cast[0]
-	}
-
-	// We need to put an *os.File reader in a ByteReader or the xml.NewDecoder
-	// will wrap it in a bufio.Reader and seek on the file beyond where the
-	// xml.Decoder parses!
-	if _, ok := xmlReader.(io.ByteReader); !ok {
-		xmlReader = myByteReader(xmlReader) // see code at EOF
-	}
-
-	// build the map
-	return xmlReaderToMap(xmlReader, r)
+	return decoderOptions(cast).NewMapXmlReader(xmlReader)
 }
 
 // Get next XML doc from an io.Reader as a Map value.  Returns Map value and slice with the raw XML.
@@ -132,49 +120,21 @@ func NewMapXmlReader(xmlReader io.Reader, cast ...bool) (Map, error) {
 //	   5. If CoerceKeysToLower() has been called, then all key values will be lower case.
 //	   6. If CoerceKeysToSnakeCase() has been called, then all key values will be converted to snake case.
 func NewMapXmlReaderRaw(xmlReader io.Reader, cast ...bool) (Map, []byte, error) {
-	var r bool
-	if len(cast) == 1 {
-		r = cast[0]
-	}
-	// create TeeReader so we can retrieve raw XML
-	buf := make([]byte, 0)
-	wb := bytes.NewBuffer(buf)
-	trdr := myTeeReader(xmlReader, wb) // see code at EOF
-
-	m, err := xmlReaderToMap(trdr, r)
-
-	// retrieve the raw XML that was decoded
-	b := wb.Bytes()
-
-	if err != nil {
-		return nil, b, err
-	}
-
-	return m, b, nil
+	return decoderOptions(cast).NewMapXmlReaderRaw(xmlReader)
 }
 
 // xmlReaderToMap() - parse a XML io.Reader to a map[string]interface{} value
-func xmlReaderToMap(rdr io.Reader, r bool) (map[string]interface{}, error) {
+func (o *DecoderOptions) xmlReaderToMap(rdr io.Reader) (map[string]interface{}, error) {
 	// parse the Reader
-	p := xml.NewDecoder(rdr)
-	if CustomDecoder != nil {
-		useCustomDecoder(p)
-	} else {
-		p.CharsetReader = XmlCharsetReader
-	}
-	return xmlToMapParser("", nil, p, r)
+	p := o.newDecoder(rdr)
+	return o.castResult(o.xmlToMapParser("", nil, p))
 }
 
 // xmlToMap - convert a XML doc into map[string]interface{} value
-func xmlToMap(doc []byte, r bool) (map[string]interface{}, error) {
+func (o *DecoderOptions) xmlToMap(doc []byte) (map[string]interface{}, error) {
 	b := bytes.NewReader(doc)
-	p := xml.NewDecoder(b)
-	if CustomDecoder != nil {
-		useCustomDecoder(p)
-	} else {
-		p.CharsetReader = XmlCharsetReader
-	}
-	return xmlToMapParser("", nil, p, r)
+	p := o.newDecoder(b)
+	return o.castResult(o.xmlToMapParser("", nil, p))
 }
 
 // ===================================== where the work happens =============================
@@ -268,7 +228,6 @@ func CoerceKeysToLower(b ...bool) {
 
 // disableTrimWhiteSpace sets if the white space should be removed or not
 var disableTrimWhiteSpace bool
-var trimRunes = "\t\r\b\n "
 
 // DisableTrimWhiteSpace set if the white space should be trimmed or not. By default white space is always trimmed. If
 // no argument is provided, trim white space will be disabled.
@@ -278,12 +237,6 @@ func DisableTrimWhiteSpace(b ...bool) {
 	} else {
 		disableTrimWhiteSpace = b[0]
 	}
-
-	if disableTrimWhiteSpace {
-		trimRunes = "\t\r\b\n"
-	} else {
-		trimRunes = "\t\r\b\n "
-	}
 }
 
 // 25jun16: Allow user to specify the "prefix" character for XML attribute key labels.
@@ -370,14 +323,32 @@ func DecodeSimpleValuesAsMap(b ...bool) {
 	}
 }
 
+// loadAttrs adds the xml.Attr values to 'na' using AttrPrefix keys.
+func (o *DecoderOptions) loadAttrs(na map[string]interface{}, a []xml.Attr) {
+	for _, v := range a {
+		if o.KeysToSnakeCase {
+			v.Name.Local = strings.Replace(v.Name.Local, "-", "_", -1)
+		}
+		var key string
+		key = o.AttrPrefix + o.nsKey(v.Name, true)
+		if o.KeysToLower {
+			key = strings.ToLower(key)
+		}
+		if o.EscapeChars { // per issue#84
+			v.Value = escapeChars(v.Value)
+		}
+		na[key] = o.castPath(v.Value, key, key)
+	}
+}
+
 // xmlToMapParser (2015.11.12) - load a 'clean' XML doc into a map[string]interface{} directly.
 // A refactoring of xmlToTreeParser(), markDuplicate() and treeToMap() - here, all-in-one.
 // We've removed the intermediate *node tree with the allocation and subsequent rescanning.
-func xmlToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[string]interface{}, error) {
-	if lowerCase {
+func (o *DecoderOptions) xmlToMapParser(skey string, a []xml.Attr, p *xml.Decoder) (map[string]interface{}, error) {
+	if o.KeysToLower {
 		skey = strings.ToLower(skey)
 	}
-	if snakeCaseKeys {
+	if o.KeysToSnakeCase {
 		skey = strings.Replace(skey, "-", "_", -1)
 	}
 
@@ -391,31 +362,19 @@ func xmlToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[stri
 	//       to get StartElement then recurse with skey==xml.StartElement.Name.Local
 	//       where we begin allocating map[string]interface{} values 'n' and 'na'.
 	if skey != "" {
+		o.pushPath(skey)
+		defer o.popPath()
 		n = make(map[string]interface{})  // old n
 		na = make(map[string]interface{}) // old n.nodes
-		if len(a) > 0 {
-			for _, v := range a {
-				if snakeCaseKeys {
-					v.Name.Local = strings.Replace(v.Name.Local, "-", "_", -1)
-				}
-				var key string
-				key = attrPrefix + v.Name.Local
-				if lowerCase {
-					key = strings.ToLower(key)
-				}
-				if xmlEscapeCharsDecoder { // per issue#84
-					v.Value = escapeChars(v.Value)
-				}
-				na[key] = cast(v.Value, r, key)
-			}
-		}
+		o.loadAttrs(na, a)
 	}
 	// Return XMPP <stream:stream> message.
-	if handleXMPPStreamTag && skey == "stream" {
+	if o.HandleXMPPStreamTag && skey == "stream" {
 		n[skey] = na
 		return n, nil
 	}
 
+	var text string // adjacent xml.CharData - e.g., "a<![CDATA[b]]>c" - is one value
 	for {
 		t, err := p.Token()
 		if err != nil {
@@ -424,6 +383,9 @@ func xmlToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[stri
 			}
 			return nil, err
 		}
+		if _, ok := t.(xml.CharData); !ok {
+			text = ""
+		}
 		switch t.(type) {
 		case xml.StartElement:
 			tt := t.(xml.StartElement)
@@ -435,13 +397,16 @@ func xmlToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[stri
 			// Subsequent calls to xmlToMapParser() will pass in tag+attributes for
 			// processing before getting the next token which is the element value,
 			// which is done above.
+			o.pushNamespaces(tt.Attr)
 			if skey == "" {
-				return xmlToMapParser(tt.Name.Local, tt.Attr, p, r)
+				defer o.popNamespaces()
+				return o.xmlToMapParser(o.nsKey(tt.Name, false), tt.Attr, p)
 			}
 
 			// If not initializing the map, parse the element.
 			// len(nn) == 1, necessarily - it is just an 'n'.
-			nn, err := xmlToMapParser(tt.Name.Local, tt.Attr, p, r)
+			nn, err := o.xmlToMapParser(o.nsKey(tt.Name, false), tt.Attr, p)
+			o.popNamespaces()
 			if err != nil {
 				return nil, err
 			}
@@ -464,7 +429,7 @@ func xmlToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[stri
 			// come next - we're only parsing forward.  So if you ask for 'includeTagSeqNum' you
 			// get it on every element. (Personally, I never liked this, but I added it on request
 			// and did get a $50 Amazon gift card in return - now we support it for backwards compatibility!)
-			if includeTagSeqNum {
+			if o.IncludeTagSeqNum {
 				switch val.(type) {
 				case []interface{}:
 					// noop - There's no clean way to handle this w/o changing message structure.
@@ -472,7 +437,7 @@ func xmlToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[stri
 					val.(map[string]interface{})["_seq"] = seq // will overwrite an "_seq" XML tag
 					seq++
 				case interface{}: // a non-nil simple element: string, float64, bool
-					v := map[string]interface{}{textK: val}
+					v := map[string]interface{}{o.keys.text: val}
 					v["_seq"] = seq
 					seq++
 					val = v
@@ -510,22 +475,24 @@ func xmlToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[stri
 			} else if len(n) == 1 && len(na) > 0 {
 				// it's a simple element w/ no attributes w/ subelements
 				for _, v := range n {
-					na[textK] = v
+					na[o.keys.text] = v
 				}
 				n[skey] = na
 			}
 			return n, nil
 		case xml.CharData:
 			// clean up possible noise
-			tt := strings.Trim(string(t.(xml.CharData)), trimRunes)
-			if xmlEscapeCharsDecoder { // issue#84
+			tt := strings.Trim(string(t.(xml.CharData)), o.trimRunes)
+			if o.EscapeChars { // issue#84
 				tt = escapeChars(tt)
 			}
 			if len(tt) > 0 {
-				if len(na) > 0 || decodeSimpleValuesAsMap {
-					na[textK] = cast(tt, r, textK)
+				tt = text + tt
+				text = tt
+				if len(na) > 0 || o.DecodeSimpleValuesAsMap {
+					na[o.keys.text] = o.castPath(tt, o.keys.text, "")
 				} else if skey != "" {
-					n[skey] = cast(tt, r, skey)
+					n[skey] = o.castPath(tt, skey, "")
 				} else {
 					// per Adrian (http://www.adrianlungu.com/) catch stray text
 					// in decoder stream -
@@ -555,16 +522,16 @@ func CastNanInf(b ...bool) {
 
 // cast - try to cast string values to bool or float64
 // 't' is the tag key that can be checked for 'not-casting'
-func cast(s string, r bool, t string) interface{} {
-	if checkTagToSkip != nil && t != "" && checkTagToSkip(t) {
+func (o *DecoderOptions) cast(s string, t string) interface{} {
+	if o.CheckTagToSkip != nil && t != "" && o.CheckTagToSkip(t) {
 		// call the check-function here with 't[0]'
 		// if 'true' return s
 		return s
 	}
 
-	if r {
+	if o.Cast {
 		// handle nan and inf
-		if !castNanInf {
+		if !o.CastNanInf {
 			switch strings.ToLower(s) {
 			case "nan", "inf", "-inf":
 				return s
@@ -572,7 +539,7 @@ func cast(s string, r bool, t string) interface{} {
 		}
 
 		// handle numeric strings ahead of boolean
-		if castToInt {
+		if o.CastToInt {
 			if f, err := strconv.ParseInt(s, 10, 64); err == nil {
 				return f
 			}
@@ -581,7 +548,7 @@ func cast(s string, r bool, t string) interface{} {
 			}
 		}
 
-		if castToFloat {
+		if o.CastToFloat {
 			if f, err := strconv.ParseFloat(s, 64); err == nil {
 				return f
 			}
@@ -590,7 +557,7 @@ func cast(s string, r bool, t string) interface{} {
 		// ParseBool treats "1"==true & "0"==false, we've already scanned those
 		// values as float64. See if value has 't' or 'f' as initial screen to
 		// minimize calls to ParseBool; also, see if len(s) < 6.
-		if castToBool {
+		if o.CastToBool {
 			if len(s) > 0 && len(s) < 6 {
 				switch s[:1] {
 				case "t", "T", "f", "F":
@@ -637,7 +604,7 @@ var checkTagToSkip func(string) bool
 
 // SetCheckTagToSkipFunc registers function to test whether the value
 // for a tag should be cast to bool or float64 when "cast" argument is 'true'.
-// (Dot tag path notation is not supported.)
+// (Dot tag path notation is not supported; see DecoderOptions.CastSchema for that.)
 // NOTE: key may be "#text" if it's a simple element with attributes
 //
 //	or "decodeSimpleValuesAsMap == true".
@@ -704,13 +671,21 @@ func XmlCheckIsValid(b ...bool) {
 //
 // The attributes tag=value pairs are alphabetized by "tag".  Also, when encoding map[string]interface{} values -
 // complex elements, etc. - the key:value pairs are alphabetized by key so the resulting tags will appear sorted.
+//
+// To use settings other than the package defaults for a single call, see EncoderOptions.
 func (mv Map) Xml(rootTag ...string) ([]byte, error) {
+	return encoderOptions(rootTag).Xml(mv)
+}
+
+// Xml encodes a Map as XML using the settings in 'o'.  See mv.Xml() for encoding rules.
+func (o *EncoderOptions) Xml(mv Map) ([]byte, error) {
+	o = o.call()
 	m := map[string]interface{}(mv)
 	var err error
 	b := new(bytes.Buffer)
 	p := new(pretty) // just a stub
 
-	if len(m) == 1 && len(rootTag) == 0 {
+	if len(m) == 1 && o.RootTag == "" {
 		for key, value := range m {
 			// if it an array, see if all values are map[string]interface{}
 			// we force a new root tag if we'll end up with no key:value in the list
@@ -721,29 +696,22 @@ func (mv Map) Xml(rootTag ...string) ([]byte, error) {
 					switch v.(type) {
 					case map[string]interface{}: // noop
 					default: // anything else
-						err = marshalMapToXmlIndent(false, b, DefaultRootTag, m, p)
+						err = o.marshalMapToXmlIndent(false, b, DefaultRootTag, m, p)
 						goto done
 					}
 				}
 			}
-			err = marshalMapToXmlIndent(false, b, key, value, p)
+			err = o.marshalMapToXmlIndent(false, b, key, value, p)
 		}
-	} else if len(rootTag) == 1 {
-		err = marshalMapToXmlIndent(false, b, rootTag[0], m, p)
+	} else if o.RootTag != "" {
+		err = o.marshalMapToXmlIndent(false, b, o.RootTag, m, p)
 	} else {
-		err = marshalMapToXmlIndent(false, b, DefaultRootTag, m, p)
+		err = o.marshalMapToXmlIndent(false, b, DefaultRootTag, m, p)
 	}
 done:
-	if xmlCheckIsValid {
-		d := xml.NewDecoder(bytes.NewReader(b.Bytes()))
-		for {
-			_, err = d.Token()
-			if err == io.EOF {
-				err = nil
-				break
-			} else if err != nil {
-				return nil, err
-			}
+	if err == nil && o.CheckIsValid {
+		if err = checkIsValidXml(b.Bytes()); err != nil {
+			return nil, err
 		}
 	}
 	return b.Bytes(), err
@@ -820,36 +788,9 @@ var xhandlerPollInterval = time.Millisecond
 //	Note: mapHandler() and errHandler() calls are blocking, so reading and processing of messages is serialized.
 //	      This means that you can stop reading the file on error or after processing a particular message.
 //	      To have reading and handling run concurrently, pass argument to a go routine in handler and return 'true'.
+//	      Or use HandleXmlReaderConcurrent() to decode and handle with a pool of workers.
 func HandleXmlReader(xmlReader io.Reader, mapHandler func(Map) bool, errHandler func(error) bool) error {
-	var n int
-	for {
-		m, merr := NewMapXmlReader(xmlReader)
-		n++
-
-		// handle error condition with errhandler
-		if merr != nil && merr != io.EOF {
-			merr = fmt.Errorf("[xmlReader: %d] %s", n, merr.Error())
-			if ok := errHandler(merr); !ok {
-				// caused reader termination
-				return merr
-			}
-			continue
-		}
-
-		// pass to maphandler
-		if len(m) != 0 {
-			if ok := mapHandler(m); !ok {
-				break
-			}
-		} else if merr != io.EOF {
-			time.Sleep(xhandlerPollInterval)
-		}
-
-		if merr == io.EOF {
-			break
-		}
-	}
-	return nil
+	return NewDecoderOptions().HandleXmlReader(xmlReader, mapHandler, errHandler)
 }
 
 // Bulk process XML using handlers that process a Map value and the raw XML.
@@ -862,35 +803,7 @@ func HandleXmlReader(xmlReader io.Reader, mapHandler func(Map) bool, errHandler
 //	      To have reading and handling run concurrently, pass argument(s) to a go routine in handler and return 'true'.
 //	See NewMapXmlReaderRaw for comment on performance associated with retrieving raw XML from a Reader.
 func HandleXmlReaderRaw(xmlReader io.Reader, mapHandler func(Map, []byte) bool, errHandler func(error, []byte) bool) error {
-	var n int
-	for {
-		m, raw, merr := NewMapXmlReaderRaw(xmlReader)
-		n++
-
-		// handle error condition with errhandler
-		if merr != nil && merr != io.EOF {
-			merr = fmt.Errorf("[xmlReader: %d] %s", n, merr.Error())
-			if ok := errHandler(merr, raw); !ok {
-				// caused reader termination
-				return merr
-			}
-			continue
-		}
-
-		// pass to maphandler
-		if len(m) != 0 {
-			if ok := mapHandler(m, raw); !ok {
-				break
-			}
-		} else if merr != io.EOF {
-			time.Sleep(xhandlerPollInterval)
-		}
-
-		if merr == io.EOF {
-			break
-		}
-	}
-	return nil
+	return NewDecoderOptions().HandleXmlReaderRaw(xmlReader, mapHandler, errHandler)
 }
 
 // ----------------- END: Handle XML stream by processing Map value --------------
@@ -964,6 +877,13 @@ func (b *byteReader) ReadByte() (byte, error) {
 // Encode a map[string]interface{} as a pretty XML string.
 // See Xml for encoding rules.
 func (mv Map) XmlIndent(prefix, indent string, rootTag ...string) ([]byte, error) {
+	return encoderOptions(rootTag).XmlIndent(mv, prefix, indent)
+}
+
+// XmlIndent encodes a Map as a pretty XML string using the settings in 'o'.
+// See mv.Xml() for encoding rules.
+func (o *EncoderOptions) XmlIndent(mv Map, prefix, indent string) ([]byte, error) {
+	o = o.call()
 	m := map[string]interface{}(mv)
 
 	var err error
@@ -972,31 +892,24 @@ func (mv Map) XmlIndent(prefix, indent string, rootTag ...string) ([]byte, error
 	p.indent = indent
 	p.padding = prefix
 
-	if len(m) == 1 && len(rootTag) == 0 {
+	if len(m) == 1 && o.RootTag == "" {
 		// this can extract the key for the single map element
 		// use it if it isn't a key for a list
 		for key, value := range m {
 			if _, ok := value.([]interface{}); ok {
-				err = marshalMapToXmlIndent(true, b, DefaultRootTag, m, p)
+				err = o.marshalMapToXmlIndent(true, b, DefaultRootTag, m, p)
 			} else {
-				err = marshalMapToXmlIndent(true, b, key, value, p)
+				err = o.marshalMapToXmlIndent(true, b, key, value, p)
 			}
 		}
-	} else if len(rootTag) == 1 {
-		err = marshalMapToXmlIndent(true, b, rootTag[0], m, p)
+	} else if o.RootTag != "" {
+		err = o.marshalMapToXmlIndent(true, b, o.RootTag, m, p)
 	} else {
-		err = marshalMapToXmlIndent(true, b, DefaultRootTag, m, p)
+		err = o.marshalMapToXmlIndent(true, b, DefaultRootTag, m, p)
 	}
-	if xmlCheckIsValid {
-		d := xml.NewDecoder(bytes.NewReader(b.Bytes()))
-		for {
-			_, err = d.Token()
-			if err == io.EOF {
-				err = nil
-				break
-			} else if err != nil {
-				return nil, err
-			}
+	if err == nil && o.CheckIsValid {
+		if err = checkIsValidXml(b.Bytes()); err != nil {
+			return nil, err
 		}
 	}
 	return b.Bytes(), err
@@ -1008,6 +921,7 @@ type pretty struct {
 	padding  string
 	mapDepth int
 	start    int
+	ns       *nsScope // name space declarations in scope - see namespace.go
 }
 
 func (p *pretty) Indent() {
@@ -1025,12 +939,12 @@ func (p *pretty) Outdent() {
 // where the work actually happens
 // returns an error if an attribute is not atomic
 // NOTE: 01may20 - replaces mapToXmlIndent(); uses bytes.Buffer instead for string appends.
-func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value interface{}, pp *pretty) error {
+func (o *EncoderOptions) marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value interface{}, pp *pretty) error {
 	var err error
 	var endTag bool
 	var isSimple bool
 	var elen int
-	p := &pretty{pp.indent, pp.cnt, pp.padding, pp.mapDepth, pp.start}
+	p := &pretty{pp.indent, pp.cnt, pp.padding, pp.mapDepth, pp.start, pp.ns}
 
 	// per issue #48, 18apr18 - try and coerce maps to map[string]interface{}
 	// Don't need for mapToXmlSeqIndent, since maps there are decoded by NewMapXmlSeq().
@@ -1058,6 +972,8 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 	case []interface{}:
 	case nil:
 		value = ""
+	case time.Time: // CastSchema type
+		value, _ = castString(value)
 	default:
 		// see if value is a struct, if so marshal using encoding/xml package
 		if reflect.ValueOf(value).Kind() == reflect.Struct {
@@ -1085,50 +1001,89 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 	}
 	switch value.(type) {
 	case []interface{}:
+	case map[string]interface{}:
+		// the tag is started after the attributes are scanned for name space declarations
 	default:
+		var decl [][2]string
+		if isClark(key) {
+			key = p.xmlName(key, false, &decl)
+		}
 		if _, err = b.WriteString(`<` + key); err != nil {
 			return err
 		}
+		for _, v := range decl {
+			if _, err = b.WriteString(` ` + v[0] + `="` + o.escape(v[1]) + `"`); err != nil {
+				return err
+			}
+		}
 	}
 
 	switch value.(type) {
 	case map[string]interface{}:
 		vv := value.(map[string]interface{})
 		lenvv := len(vv)
-		// scan out attributes - attribute keys have prepended attrPrefix
+		// scan out attributes - attribute keys have prepended o.AttrPrefix
 		attrlist := make([][2]string, len(vv))
 		var n int
 		var ss string
+		var hasNs bool
 		for k, v := range vv {
-			if lenAttrPrefix > 0 && lenAttrPrefix < len(k) && k[:lenAttrPrefix] == attrPrefix {
+			if o.isAttr(k) {
 				switch v.(type) {
 				case string:
-					if xmlEscapeChars {
+					if o.EscapeChars {
 						ss = escapeChars(v.(string))
 					} else {
 						ss = v.(string)
 					}
-					attrlist[n][0] = k[lenAttrPrefix:]
+					attrlist[n][0] = k[len(o.AttrPrefix):]
 					attrlist[n][1] = ss
 				case float64, bool, int, int32, int64, float32, json.Number:
-					attrlist[n][0] = k[lenAttrPrefix:]
+					attrlist[n][0] = k[len(o.AttrPrefix):]
 					attrlist[n][1] = fmt.Sprintf("%v", v)
+				case uint64, time.Time: // CastSchema types
+					attrlist[n][0] = k[len(o.AttrPrefix):]
+					attrlist[n][1], _ = castString(v)
 				case []byte:
-					if xmlEscapeChars {
+					if o.EscapeChars {
 						ss = escapeChars(string(v.([]byte)))
 					} else {
 						ss = string(v.([]byte))
 					}
-					attrlist[n][0] = k[lenAttrPrefix:]
+					attrlist[n][0] = k[len(o.AttrPrefix):]
 					attrlist[n][1] = ss
 				default:
 					return fmt.Errorf("invalid attribute value for: %s:<%T>", k, v)
 				}
+				if !hasNs {
+					hasNs = isNsDecl(attrlist[n][0]) || isClark(attrlist[n][0])
+				}
 				n++
 			}
 		}
+		attrlist = attrlist[:n]
+		// resolve Clark notation, "{uri}local", names - see namespace.go
+		if hasNs || isClark(key) {
+			p.pushNamespaces(attrlist)
+			var decl [][2]string
+			if isClark(key) {
+				key = p.xmlName(key, false, &decl)
+			}
+			for i := range attrlist {
+				if isClark(attrlist[i][0]) {
+					attrlist[i][0] = p.xmlName(attrlist[i][0], true, &decl)
+				}
+			}
+			for _, v := range decl {
+				attrlist = append(attrlist, [2]string{v[0], o.escape(v[1])})
+			}
+			n = len(attrlist)
+			lenvv += len(decl)
+		}
+		if _, err = b.WriteString(`<` + key); err != nil {
+			return err
+		}
 		if n > 0 {
-			attrlist = attrlist[:n]
 			sort.Sort(attrList(attrlist))
 			for _, v := range attrlist {
 				if _, err = b.WriteString(` ` + v[0] + `="` + v[1] + `"`); err != nil {
@@ -1138,7 +1093,7 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 		}
 		// only attributes?
 		if n == lenvv {
-			if useGoXmlEmptyElemSyntax {
+			if o.GoEmptyElemSyntax {
 				if _, err = b.WriteString(`</` + key + ">"); err != nil {
 					return err
 				}
@@ -1152,21 +1107,13 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 
 		// simple element? Note: '#text" is an invalid XML tag.
 		isComplex := false
-		if v, ok := vv[textK]; ok && n+1 == lenvv {
+		if v, ok := vv[o.keys.text]; ok && n+1 == lenvv {
 			// just the value and attributes
 			switch v.(type) {
 			case string:
-				if xmlEscapeChars {
-					v = escapeChars(v.(string))
-				} else {
-					v = v.(string)
-				}
+				v = o.text(v.(string))
 			case []byte:
-				if xmlEscapeChars {
-					v = escapeChars(string(v.([]byte)))
-				} else {
-					v = string(v.([]byte))
-				}
+				v = o.text(string(v.([]byte)))
 			}
 			if _, err = b.WriteString(">" + fmt.Sprintf("%v", v)); err != nil {
 				return err
@@ -1180,17 +1127,9 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 			// issue #90
 			switch v.(type) {
 			case string:
-				if xmlEscapeChars {
-					v = escapeChars(v.(string))
-				} else {
-					v = v.(string)
-				}
+				v = o.text(v.(string))
 			case []byte:
-				if xmlEscapeChars {
-					v = escapeChars(string(v.([]byte)))
-				} else {
-					v = string(v.([]byte))
-				}
+				v = o.text(string(v.([]byte)))
 			}
 			if _, err = b.WriteString(">" + fmt.Sprintf("%v", v)); err != nil {
 				return err
@@ -1216,11 +1155,11 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 		elemlist := make([][2]interface{}, len(vv))
 		n = 0
 		for k, v := range vv {
-			if k == textK {
+			if k == o.keys.text {
 				// simple element handled above
 				continue
 			}
-			if lenAttrPrefix > 0 && lenAttrPrefix < len(k) && k[:lenAttrPrefix] == attrPrefix {
+			if o.isAttr(k) {
 				continue
 			}
 			elemlist[n][0] = k
@@ -1239,7 +1178,7 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 				}
 			}
 			i++
-			if err := marshalMapToXmlIndent(doIndent, b, v[0].(string), v[1], p); err != nil {
+			if err := o.marshalMapToXmlIndent(doIndent, b, v[0].(string), v[1], p); err != nil {
 				return err
 			}
 			switch v[1].(type) {
@@ -1273,7 +1212,7 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 			if doIndent {
 				p.Indent()
 			}
-			if err := marshalMapToXmlIndent(doIndent, b, key, v, p); err != nil {
+			if err := o.marshalMapToXmlIndent(doIndent, b, key, v, p); err != nil {
 				return err
 			}
 			if doIndent {
@@ -1305,7 +1244,7 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 			if doIndent {
 				p.Indent()
 			}
-			if err := marshalMapToXmlIndent(doIndent, b, key, v, p); err != nil {
+			if err := o.marshalMapToXmlIndent(doIndent, b, key, v, p); err != nil {
 				return err
 			}
 			if doIndent {
@@ -1331,9 +1270,7 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 		switch value.(type) {
 		case string:
 			v := value.(string)
-			if xmlEscapeChars {
-				v = escapeChars(v)
-			}
+			v = o.text(v)
 			elen = len(v)
 			if elen > 0 {
 				// *s += ">" + v
@@ -1350,9 +1287,7 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 		case []byte: // NOTE: byte is just an alias for uint8
 			// similar to how xml.Marshal handles []byte structure members
 			v := string(value.([]byte))
-			if xmlEscapeChars {
-				v = escapeChars(v)
-			}
+			v = o.text(v)
 			elen = len(v)
 			if elen > 0 {
 				// *s += ">" + v
@@ -1395,7 +1330,7 @@ func marshalMapToXmlIndent(doIndent bool, b *bytes.Buffer, key string, value int
 				}
 			}
 		}
-		if elen > 0 || useGoXmlEmptyElemSyntax {
+		if elen > 0 || o.GoEmptyElemSyntax {
 			if elen == 0 {
 				if _, err = b.WriteString(">"); err != nil {
 					return err
@@ -0,0 +1,177 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// xmlpathscanner.go - stream the elements at a path, e.g., "feed.entry", from
+// a large XML doc without decoding the whole doc.
+
+package mxj
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// XmlPathScanner returns the elements at a path in an XML doc one at a time.
+// Only the current element is decoded, so memory use is independent of the size
+// of the doc.
+//
+//	s := mxj.NewXmlPathScanner(fh, "feed.entry")
+//	for {
+//		m, err := s.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		if err != nil {
+//			// handle error
+//		}
+//		// m is map["entry"]interface{}; the <feed> attributes are in s.Attrs()
+//	}
+type XmlPathScanner struct {
+	o     *DecoderOptions
+	p     *xml.Decoder
+	path  []string
+	stack []scanElem // the enclosing elements of the current position
+	err   error
+}
+
+// scanElem is an enclosing element and its attributes.
+type scanElem struct {
+	key   string
+	attrs map[string]interface{}
+}
+
+// NewXmlPathScanner returns an XmlPathScanner for the elements at 'path' in the
+// XML doc(s) read from 'xmlReader'.
+//
+//	The 'path' is a dot-separated list of element keys starting at the root, e.g.,
+//	"feed.entry"; a "*" matches any key at that level.  Keys are compared as they
+//	are in the Map values returned by NewMapXml() - e.g., after CoerceKeysToLower().
+//	If the reader holds a series of docs, elements are returned from each of them.
+//	The optional 'cast' argument is as for NewMapXml().
+func NewXmlPathScanner(xmlReader io.Reader, path string, cast ...bool) *XmlPathScanner {
+	return decoderOptions(cast).NewXmlPathScanner(xmlReader, path)
+}
+
+// NewXmlPathScanner returns an XmlPathScanner that decodes elements using the
+// settings in 'o'.  See the NewXmlPathScanner function.
+func (o *DecoderOptions) NewXmlPathScanner(xmlReader io.Reader, path string) *XmlPathScanner {
+	// We need to put an *os.File reader in a ByteReader or the xml.NewDecoder
+	// will wrap it in a bufio.Reader and seek on the file beyond where the
+	// xml.Decoder parses!
+	if _, ok := xmlReader.(io.ByteReader); !ok {
+		xmlReader = myByteReader(xmlReader) // see code at EOF in xml.go
+	}
+	s := &XmlPathScanner{o: o.call()}
+	s.p = s.o.newDecoder(xmlReader)
+	for _, k := range strings.Split(path, ".") {
+		if k != "" {
+			s.path = append(s.path, k)
+		}
+	}
+	if len(s.path) == 0 {
+		s.err = errors.New("no path")
+	}
+	return s
+}
+
+// Next returns the next element at the path as a Map - map[<key>]interface{} - or
+// io.EOF when the reader is exhausted.  After an error, all calls return that error.
+func (s *XmlPathScanner) Next() (Map, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	for {
+		t, err := s.p.Token()
+		if err != nil {
+			if err != io.EOF {
+				err = errors.New("xml.Decoder.Token() - " + err.Error())
+			}
+			s.err = err
+			return nil, err
+		}
+		switch tt := t.(type) {
+		case xml.StartElement:
+			s.o.pushNamespaces(tt.Attr)
+			key := s.o.elemKey(tt.Name)
+			if k := s.path[len(s.stack)]; k != "*" && k != key {
+				// not on the path - don't look inside
+				err = s.p.Skip()
+				s.o.popNamespaces()
+				if err != nil {
+					s.err = errors.New("xml.Decoder.Skip() - " + err.Error())
+					return nil, s.err
+				}
+				continue
+			}
+			s.o.setPath(s.stack)
+			if len(s.stack)+1 == len(s.path) {
+				m, err := s.o.xmlToMapParser(key, tt.Attr, s.p)
+				s.o.popNamespaces()
+				if err != nil {
+					if err == io.EOF {
+						err = io.ErrUnexpectedEOF
+					}
+					s.err = err
+					return nil, err
+				}
+				// a CastSchema error doesn't stop the scan
+				m, err = s.o.castResult(m, nil)
+				s.o.castErr = nil
+				return m, err
+			}
+			attrs := make(map[string]interface{})
+			s.o.pushPath(key)
+			s.o.loadAttrs(attrs, tt.Attr)
+			s.stack = append(s.stack, scanElem{key, attrs})
+		case xml.EndElement:
+			if len(s.stack) > 0 {
+				s.stack = s.stack[:len(s.stack)-1]
+				s.o.popNamespaces()
+			}
+		}
+	}
+}
+
+// setPath sets the CastSchema path to the keys of the enclosing elements.
+func (o *DecoderOptions) setPath(stack []scanElem) {
+	if len(o.castRules) == 0 {
+		return
+	}
+	o.path = o.path[:0]
+	for _, e := range stack {
+		o.path = append(o.path, e.key)
+	}
+}
+
+// Attrs returns the attributes of the elements enclosing the element last
+// returned by Next() as a Map keyed by the path - e.g., for "feed.entry",
+// map["feed"]map[<attr_keys>]interface{}.  So the <feed lang="en"> attribute
+// is available as s.Attrs().ValueForPath("feed.-lang").
+func (s *XmlPathScanner) Attrs() Map {
+	m := make(Map)
+	cur := map[string]interface{}(m)
+	for _, e := range s.stack {
+		v := make(map[string]interface{}, len(e.attrs)+1)
+		for k, a := range e.attrs {
+			v[k] = a
+		}
+		cur[e.key] = v
+		cur = v
+	}
+	return m
+}
+
+// elemKey returns the Map key for an element name as xmlToMapParser() sets it.
+func (o *DecoderOptions) elemKey(n xml.Name) string {
+	key := o.nsKey(n, false)
+	if o.KeysToLower {
+		key = strings.ToLower(key)
+	}
+	if o.KeysToSnakeCase {
+		key = strings.Replace(key, "-", "_", -1)
+	}
+	return key
+}
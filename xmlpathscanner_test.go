@@ -0,0 +1,107 @@
+package mxj
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const scanFeed = `<?xml version="1.0"?>
+<feed lang="en" xmlns="http://www.w3.org/2005/Atom">
+  <title>Example Feed</title>
+  <entry id="1"><title>one</title><skip><entry>nested</entry></skip></entry>
+  <other><entry id="x">not on the path</entry></other>
+  <entry id="2"><title>two</title></entry>
+  <entry id="3"><title>three</title></entry>
+</feed>`
+
+func TestXmlPathScanner(t *testing.T) {
+	fmt.Println("\n================== TestXmlPathScanner")
+	s := NewXmlPathScanner(strings.NewReader(scanFeed), "feed.entry")
+	var ids []string
+	for {
+		m, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Println(m)
+		id, err := m.ValueForPath("entry." + attrPrefix + "id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id.(string))
+		lang, err := s.Attrs().ValueForPath("feed." + attrPrefix + "lang")
+		if err != nil || lang != "en" {
+			t.Fatal("feed lang:", lang, err)
+		}
+	}
+	if strings.Join(ids, ",") != "1,2,3" {
+		t.Fatal("ids:", ids)
+	}
+	// exhausted
+	if _, err := s.Next(); err != io.EOF {
+		t.Fatal("expected io.EOF, got:", err)
+	}
+}
+
+func TestXmlPathScannerWildcard(t *testing.T) {
+	fmt.Println("\n================== TestXmlPathScannerWildcard")
+	s := NewXmlPathScanner(strings.NewReader(scanFeed), "feed.*.title")
+	var titles []string
+	for {
+		m, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles = append(titles, m["title"].(string))
+	}
+	if strings.Join(titles, ",") != "one,two,three" {
+		t.Fatal("titles:", titles)
+	}
+}
+
+func TestXmlPathScannerMultiDoc(t *testing.T) {
+	fmt.Println("\n================== TestXmlPathScannerMultiDoc")
+	data := `<doc><v>1</v><v>2</v></doc><doc><v>3</v></doc>`
+	opts := NewDecoderOptions()
+	opts.Cast = true
+	s := opts.NewXmlPathScanner(bytes.NewBufferString(data), "doc.v")
+	var sum float64
+	for {
+		m, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum += m["v"].(float64)
+	}
+	if sum != 6 {
+		t.Fatal("sum:", sum)
+	}
+}
+
+func TestXmlPathScannerError(t *testing.T) {
+	fmt.Println("\n================== TestXmlPathScannerError")
+	s := NewXmlPathScanner(strings.NewReader(`<doc><v>1</v><v>2</doc>`), "doc.v")
+	if _, err := s.Next(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := s.Next()
+	if err == nil || err == io.EOF {
+		t.Fatal("expected syntax error, got:", err)
+	}
+	fmt.Println("err:", err)
+	if _, err2 := s.Next(); err2 != err {
+		t.Fatal("error not sticky:", err2)
+	}
+}
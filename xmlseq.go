@@ -9,6 +9,7 @@ package mxj
 
 import (
 	"bytes"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
 // MapSeq is like Map but contains seqencing indices to allow recovering the original order of
@@ -72,8 +74,8 @@ var NO_ROOT = NoRoot // maintain backwards compatibility
 //   - comments, directives, and procinsts that are NOT part of a document with a root key will be returned as
 //     map[string]interface{} and the error value 'NoRoot'.
 //
-//   - note: "<![CDATA[" syntax is lost in xml.Decode parser - and is not handled here, either.
-//     and: "\r\n" is converted to "\n"
+//   - CDATA sections - "<![CDATA[text]]>" - are decoded as map["#text"]"text" with a "#cdata":true k:v pair,
+//     so msv.Xml() re-encodes them as CDATA. Note: "\r\n" is converted to "\n"
 //
 //     NOTES:
 //     1. The 'xmlVal' will be parsed looking for an xml.StartElement, xml.Comment, etc., so BOM and other
@@ -93,11 +95,7 @@ var NO_ROOT = NoRoot // maintain backwards compatibility
 //     2. Unmarshaling an XML doc that is formatted using the whitespace character, " ", will error, since
 //     Decoder.RawToken treats such occurances as significant. See NewMapFormattedXmlSeq().
 func NewMapXmlSeq(xmlVal []byte, cast ...bool) (MapSeq, error) {
-	var r bool
-	if len(cast) == 1 {
-		r = cast[0]
-	}
-	return xmlSeqToMap(xmlVal, r)
+	return decoderOptions(cast).NewMapXmlSeq(xmlVal)
 }
 
 // NewMapFormattedXmlSeq performs the same as NewMapXmlSeq but is useful for processing XML objects that
@@ -109,17 +107,16 @@ func NewMapXmlSeq(xmlVal []byte, cast ...bool) (MapSeq, error) {
 // into an empty string, "", prior to parsing the XML - irrespective of whether the occurrence is
 // formatting or is a actual element value.
 func NewMapFormattedXmlSeq(xmlVal []byte, cast ...bool) (MapSeq, error) {
-	var c bool
-	if len(cast) == 1 {
-		c = cast[0]
-	}
+	return decoderOptions(cast).NewMapFormattedXmlSeq(xmlVal)
+}
 
+// cleanFormattedXml removes formatting whitespace between tags for NewMapFormattedXmlSeq.
+func cleanFormattedXml(xmlVal []byte) []byte {
 	// Per PR #104 - clean out formatting characters so they don't show up in Decoder.RawToken() stream.
 	// NOTE: Also replaces element values that are solely comprised of formatting/whitespace characters
 	// with empty string, "".
 	r := regexp.MustCompile(`>[\n\t\r ]*<`)
-	xmlVal = r.ReplaceAll(xmlVal, []byte("><"))
-	return xmlSeqToMap(xmlVal, c)
+	return r.ReplaceAll(xmlVal, []byte("><"))
 }
 
 // NewMpaXmlSeqReader returns next XML doc from an io.Reader as a MapSeq value.
@@ -135,20 +132,7 @@ func NewMapFormattedXmlSeq(xmlVal []byte, cast ...bool) (MapSeq, error) {
 //	   1. If a NoRoot error, "no root key," is returned, check the initial map key for a "#comment",
 //	      "#directive" or #procinst" key.
 func NewMapXmlSeqReader(xmlReader io.Reader, cast ...bool) (MapSeq, error) {
-	var r bool
-	if len(cast) == 1 {
-		r = cast[0]
-	}
-
-	// We need to put an *os.File reader in a ByteReader or the xml.NewDecoder
-	// will wrap it in a bufio.Reader and seek on the file beyond where the
-	// xml.Decoder parses!
-	if _, ok := xmlReader.(io.ByteReader); !ok {
-		xmlReader = myByteReader(xmlReader) // see code at EOF
-	}
-
-	// build the map
-	return xmlSeqReaderToMap(xmlReader, r)
+	return decoderOptions(cast).NewMapXmlSeqReader(xmlReader)
 }
 
 // NewMapXmlSeqReaderRaw returns the  next XML doc from  an io.Reader as a MapSeq value.
@@ -171,54 +155,31 @@ func NewMapXmlSeqReader(xmlReader io.Reader, cast ...bool) (MapSeq, error) {
 //	    1. If a NoRoot error, "no root key," is returned, check if the initial map key is "#comment",
 //	       "#directive" or #procinst" key.
 func NewMapXmlSeqReaderRaw(xmlReader io.Reader, cast ...bool) (MapSeq, []byte, error) {
-	var r bool
-	if len(cast) == 1 {
-		r = cast[0]
-	}
-	// create TeeReader so we can retrieve raw XML
-	buf := make([]byte, 0)
-	wb := bytes.NewBuffer(buf)
-	trdr := myTeeReader(xmlReader, wb)
-
-	m, err := xmlSeqReaderToMap(trdr, r)
-
-	// retrieve the raw XML that was decoded
-	b := wb.Bytes()
-
-	// err may be NoRoot
-	return m, b, err
+	return decoderOptions(cast).NewMapXmlSeqReaderRaw(xmlReader)
 }
 
 // xmlSeqReaderToMap() - parse a XML io.Reader to a map[string]interface{} value
-func xmlSeqReaderToMap(rdr io.Reader, r bool) (map[string]interface{}, error) {
+func (o *DecoderOptions) xmlSeqReaderToMap(rdr io.Reader) (map[string]interface{}, error) {
 	// parse the Reader
-	p := xml.NewDecoder(rdr)
-	if CustomDecoder != nil {
-		useCustomDecoder(p)
-	} else {
-		p.CharsetReader = XmlCharsetReader
-	}
-	return xmlSeqToMapParser("", nil, p, r)
+	o.raw = newRawRecorder(rdr)
+	p := o.newDecoder(o.raw)
+	return o.castResult(o.xmlSeqToMapParser("", nil, p))
 }
 
 // xmlSeqToMap - convert a XML doc into map[string]interface{} value
-func xmlSeqToMap(doc []byte, r bool) (map[string]interface{}, error) {
+func (o *DecoderOptions) xmlSeqToMap(doc []byte) (map[string]interface{}, error) {
 	b := bytes.NewReader(doc)
-	p := xml.NewDecoder(b)
-	if CustomDecoder != nil {
-		useCustomDecoder(p)
-	} else {
-		p.CharsetReader = XmlCharsetReader
-	}
-	return xmlSeqToMapParser("", nil, p, r)
+	o.raw = newRawRecorder(b)
+	p := o.newDecoder(o.raw)
+	return o.castResult(o.xmlSeqToMapParser("", nil, p))
 }
 
 // ===================================== where the work happens =============================
 
 // xmlSeqToMapParser - load a 'clean' XML doc into a map[string]interface{} directly.
 // Add #seq tag value for each element decoded - to be used for Encoding later.
-func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[string]interface{}, error) {
-	if snakeCaseKeys {
+func (o *DecoderOptions) xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder) (map[string]interface{}, error) {
+	if o.KeysToSnakeCase {
 		skey = strings.Replace(skey, "-", "_", -1)
 	}
 
@@ -231,6 +192,8 @@ func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[s
 	//       to get StartElement then recurse with skey==xml.StartElement.Name.Local
 	//       where we begin allocating map[string]interface{} values 'n' and 'na'.
 	if skey != "" {
+		o.pushPath(skey)
+		defer o.popPath()
 		// 'n' only needs one slot - save call to runtime•hashGrow()
 		// 'na' we don't know
 		n = make(map[string]interface{}, 1)
@@ -240,29 +203,33 @@ func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[s
 			// where interface{} is map[string]interface{}{"#text":<attr_val>, "#seq":<attr_seq>}
 			aa := make(map[string]interface{}, len(a))
 			for i, v := range a {
-				if snakeCaseKeys {
+				if o.KeysToSnakeCase {
 					v.Name.Local = strings.Replace(v.Name.Local, "-", "_", -1)
 				}
-				if xmlEscapeCharsDecoder { // per issue#84
+				if o.EscapeChars { // per issue#84
 					v.Value = escapeChars(v.Value)
 				}
 				if len(v.Name.Space) > 0 {
-					aa[v.Name.Space+`:`+v.Name.Local] = map[string]interface{}{textK: cast(v.Value, r, ""), seqK: i}
+					aa[v.Name.Space+`:`+v.Name.Local] = map[string]interface{}{o.keys.text: o.castPath(v.Value, "", o.AttrPrefix+v.Name.Space+`:`+v.Name.Local), o.keys.seq: i}
 				} else {
-					aa[v.Name.Local] = map[string]interface{}{textK: cast(v.Value, r, ""), seqK: i}
+					aa[v.Name.Local] = map[string]interface{}{o.keys.text: o.castPath(v.Value, "", o.AttrPrefix+v.Name.Local), o.keys.seq: i}
 				}
 			}
-			na[attrK] = aa
+			na[o.keys.attr] = aa
 		}
 	}
 
 	// Return XMPP <stream:stream> message.
-	if handleXMPPStreamTag && skey == "stream:stream" {
+	if o.HandleXMPPStreamTag && skey == "stream:stream" {
 		n[skey] = na
 		return n, nil
 	}
 
+	var lastText bool // previous token was a #text value - adjacent CharData is concatenated
+	var text string   // the #text value, as read
+	var textCDATA bool
 	for {
+		start := p.InputOffset()
 		t, err := p.RawToken()
 		if err != nil {
 			if err != io.EOF {
@@ -270,6 +237,16 @@ func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[s
 			}
 			return nil, err
 		}
+		var isCDATA bool
+		if o.raw != nil {
+			if _, ok := t.(xml.CharData); ok {
+				isCDATA = o.raw.isCDATA(start, p.InputOffset())
+			}
+			o.raw.discard(p.InputOffset())
+		}
+		if _, ok := t.(xml.CharData); !ok {
+			lastText = false
+		}
 		switch t.(type) {
 		case xml.StartElement:
 			tt := t.(xml.StartElement)
@@ -283,9 +260,9 @@ func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[s
 			// which is done above.
 			if skey == "" {
 				if len(tt.Name.Space) > 0 {
-					return xmlSeqToMapParser(tt.Name.Space+`:`+tt.Name.Local, tt.Attr, p, r)
+					return o.xmlSeqToMapParser(tt.Name.Space+`:`+tt.Name.Local, tt.Attr, p)
 				} else {
-					return xmlSeqToMapParser(tt.Name.Local, tt.Attr, p, r)
+					return o.xmlSeqToMapParser(tt.Name.Local, tt.Attr, p)
 				}
 			}
 
@@ -293,9 +270,9 @@ func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[s
 			// len(nn) == 1, necessarily - it is just an 'n'.
 			var nn map[string]interface{}
 			if len(tt.Name.Space) > 0 {
-				nn, err = xmlSeqToMapParser(tt.Name.Space+`:`+tt.Name.Local, tt.Attr, p, r)
+				nn, err = o.xmlSeqToMapParser(tt.Name.Space+`:`+tt.Name.Local, tt.Attr, p)
 			} else {
-				nn, err = xmlSeqToMapParser(tt.Name.Local, tt.Attr, p, r)
+				nn, err = o.xmlSeqToMapParser(tt.Name.Local, tt.Attr, p)
 			}
 			if err != nil {
 				return nil, err
@@ -320,10 +297,10 @@ func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[s
 			// where all the "list" subelements are decoded into an array.
 			switch val.(type) {
 			case map[string]interface{}:
-				val.(map[string]interface{})[seqK] = seq
+				val.(map[string]interface{})[o.keys.seq] = seq
 				seq++
 			case interface{}: // a non-nil simple element: string, float64, bool
-				v := map[string]interface{}{textK: val, seqK: seq}
+				v := map[string]interface{}{o.keys.text: val, o.keys.seq: seq}
 				seq++
 				val = v
 			}
@@ -347,7 +324,7 @@ func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[s
 		case xml.EndElement:
 			if skey != "" {
 				tt := t.(xml.EndElement)
-				if snakeCaseKeys {
+				if o.KeysToSnakeCase {
 					tt.Name.Local = strings.Replace(tt.Name.Local, "-", "_", -1)
 				}
 				var name string
@@ -375,10 +352,13 @@ func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[s
 			}
 			return n, nil
 		case xml.CharData:
-			// clean up possible noise
-			tt := strings.Trim(string(t.(xml.CharData)), trimRunes)
-			if xmlEscapeCharsDecoder { // issue#84
-				tt = escapeChars(tt)
+			// clean up possible noise - CDATA sections are kept as written
+			tt := string(t.(xml.CharData))
+			if !isCDATA {
+				tt = strings.Trim(tt, o.trimRunes)
+				if o.EscapeChars { // issue#84
+					tt = escapeChars(tt)
+				}
 			}
 			if skey == "" {
 				// per Adrian (http://www.adrianlungu.com/) catch stray text
@@ -389,43 +369,62 @@ func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[s
 				continue
 			}
 			if len(tt) > 0 {
-				// every simple element is a #text and has #seq associated with it
-				na[textK] = cast(tt, r, "")
-				na[seqK] = seq
-				seq++
+				// "a<![CDATA[b]]>c" is decoded as three xml.CharData tokens;
+				// they're concatenated as read and the result is cast, if at all,
+				// as a whole
+				if lastText {
+					text += tt
+				} else {
+					// every simple element is a #text and has #seq associated with it
+					text, textCDATA = tt, false
+					delete(na, o.keys.cdata)
+					na[o.keys.seq] = seq
+					seq++
+					lastText = true
+				}
+				if isCDATA {
+					textCDATA = true
+				}
+				if textCDATA {
+					// don't cast - keep the section as written
+					na[o.keys.text] = text
+					na[o.keys.cdata] = true
+				} else {
+					na[o.keys.text] = o.castPath(text, "", "")
+				}
 			}
 		case xml.Comment:
 			if n == nil { // no root 'key'
-				n = map[string]interface{}{commentK: string(t.(xml.Comment))}
+				n = map[string]interface{}{o.keys.comment: string(t.(xml.Comment))}
 				return n, NoRoot
 			}
 			cm := make(map[string]interface{}, 2)
-			cm[textK] = string(t.(xml.Comment))
-			cm[seqK] = seq
+			cm[o.keys.text] = string(t.(xml.Comment))
+			cm[o.keys.seq] = seq
 			seq++
-			na[commentK] = cm
+			na[o.keys.comment] = cm
 		case xml.Directive:
 			if n == nil { // no root 'key'
-				n = map[string]interface{}{directiveK: string(t.(xml.Directive))}
+				n = map[string]interface{}{o.keys.directive: string(t.(xml.Directive))}
 				return n, NoRoot
 			}
 			dm := make(map[string]interface{}, 2)
-			dm[textK] = string(t.(xml.Directive))
-			dm[seqK] = seq
+			dm[o.keys.text] = string(t.(xml.Directive))
+			dm[o.keys.seq] = seq
 			seq++
-			na[directiveK] = dm
+			na[o.keys.directive] = dm
 		case xml.ProcInst:
 			if n == nil {
-				na = map[string]interface{}{targetK: t.(xml.ProcInst).Target, instK: string(t.(xml.ProcInst).Inst)}
-				n = map[string]interface{}{procinstK: na}
+				na = map[string]interface{}{o.keys.target: t.(xml.ProcInst).Target, o.keys.inst: string(t.(xml.ProcInst).Inst)}
+				n = map[string]interface{}{o.keys.procinst: na}
 				return n, NoRoot
 			}
 			pm := make(map[string]interface{}, 3)
-			pm[targetK] = t.(xml.ProcInst).Target
-			pm[instK] = string(t.(xml.ProcInst).Inst)
-			pm[seqK] = seq
+			pm[o.keys.target] = t.(xml.ProcInst).Target
+			pm[o.keys.inst] = string(t.(xml.ProcInst).Inst)
+			pm[o.keys.seq] = seq
 			seq++
-			na[procinstK] = pm
+			na[o.keys.procinst] = pm
 		default:
 			// noop - shouldn't ever get here, now, since we handle all token types
 		}
@@ -452,14 +451,21 @@ func xmlSeqToMapParser(skey string, a []xml.Attr, p *xml.Decoder, r bool) (map[s
 //   - Elements with only attribute values or are null are terminated using "/>" unless XmlGoEmptyElemSystax() called.
 //   - If len(mv) == 1 and no rootTag is provided, then the map key is used as the root tag, possible.
 //     Thus, `{ "key":"value" }` encodes as "<key>value</key>".
+//
+// To use settings other than the package defaults for a single call, see EncoderOptions.
 func (mv MapSeq) Xml(rootTag ...string) ([]byte, error) {
+	return encoderOptions(rootTag).XmlSeq(mv)
+}
+
+// XmlSeq encodes a MapSeq as XML using the settings in 'o'.  See msv.Xml() for encoding rules.
+func (o *EncoderOptions) XmlSeq(mv MapSeq) ([]byte, error) {
+	o = o.call()
 	m := map[string]interface{}(mv)
 	var err error
-	s := new(string)
 	p := new(pretty) // just a stub
 
 	var sb strings.Builder
-	if len(m) == 1 && len(rootTag) == 0 {
+	if len(m) == 1 && o.RootTag == "" {
 		for key, value := range m {
 			// if it's an array, see if all values are map[string]interface{}
 			// we force a new root tag if we'll end up with no key:value in the list
@@ -470,29 +476,22 @@ func (mv MapSeq) Xml(rootTag ...string) ([]byte, error) {
 					switch v.(type) {
 					case map[string]interface{}: // noop
 					default: // anything else
-						err = mapToXmlSeqIndent(false, &sb, DefaultRootTag, m, p)
+						err = o.mapToXmlSeqIndent(false, &sb, DefaultRootTag, m, p)
 						goto done
 					}
 				}
 			}
-			err = mapToXmlSeqIndent(false, &sb, key, value, p)
+			err = o.mapToXmlSeqIndent(false, &sb, key, value, p)
 		}
-	} else if len(rootTag) == 1 {
-		err = mapToXmlSeqIndent(false, &sb, rootTag[0], m, p)
+	} else if o.RootTag != "" {
+		err = o.mapToXmlSeqIndent(false, &sb, o.RootTag, m, p)
 	} else {
-		err = mapToXmlSeqIndent(false, &sb, DefaultRootTag, m, p)
+		err = o.mapToXmlSeqIndent(false, &sb, DefaultRootTag, m, p)
 	}
 done:
-	if xmlCheckIsValid {
-		d := xml.NewDecoder(bytes.NewReader([]byte(*s)))
-		for {
-			_, err = d.Token()
-			if err == io.EOF {
-				err = nil
-				break
-			} else if err != nil {
-				return nil, err
-			}
+	if err == nil && o.CheckIsValid {
+		if err = checkIsValidXml([]byte(sb.String())); err != nil {
+			return nil, err
 		}
 	}
 	return []byte(sb.String()), err
@@ -560,6 +559,13 @@ func (mv MapSeq) XmlIndentWriterRaw(xmlWriter io.Writer, prefix, indent string,
 // XmlIndent encodes a map[string]interface{} as a pretty XML string.
 // See MapSeq.XmlSeq() for encoding rules.
 func (mv MapSeq) XmlIndent(prefix, indent string, rootTag ...string) ([]byte, error) {
+	return encoderOptions(rootTag).XmlSeqIndent(mv, prefix, indent)
+}
+
+// XmlSeqIndent encodes a MapSeq as a pretty XML string using the settings in 'o'.
+// See msv.Xml() for encoding rules.
+func (o *EncoderOptions) XmlSeqIndent(mv MapSeq, prefix, indent string) ([]byte, error) {
+	o = o.call()
 	m := map[string]interface{}(mv)
 
 	var err error
@@ -568,55 +574,45 @@ func (mv MapSeq) XmlIndent(prefix, indent string, rootTag ...string) ([]byte, er
 	p.padding = prefix
 
 	var sb strings.Builder
-	if len(m) == 1 && len(rootTag) == 0 {
+	if len(m) == 1 && o.RootTag == "" {
 		// this can extract the key for the single map element
 		// use it if it isn't a key for a list
 		for key, value := range m {
 			if _, ok := value.([]interface{}); ok {
-				err = mapToXmlSeqIndent(true, &sb, DefaultRootTag, m, p)
+				err = o.mapToXmlSeqIndent(true, &sb, DefaultRootTag, m, p)
 			} else {
-				err = mapToXmlSeqIndent(true, &sb, key, value, p)
+				err = o.mapToXmlSeqIndent(true, &sb, key, value, p)
 			}
 		}
-	} else if len(rootTag) == 1 {
-		err = mapToXmlSeqIndent(true, &sb, rootTag[0], m, p)
+	} else if o.RootTag != "" {
+		err = o.mapToXmlSeqIndent(true, &sb, o.RootTag, m, p)
 	} else {
-		err = mapToXmlSeqIndent(true, &sb, DefaultRootTag, m, p)
+		err = o.mapToXmlSeqIndent(true, &sb, DefaultRootTag, m, p)
 	}
-	if xmlCheckIsValid {
-		if _, err = NewMapXml([]byte(sb.String())); err != nil {
+	if err == nil && o.CheckIsValid {
+		if err = checkIsValidXml([]byte(sb.String())); err != nil {
 			return nil, err
 		}
-		d := xml.NewDecoder(bytes.NewReader([]byte(sb.String())))
-		for {
-			_, err = d.Token()
-			if err == io.EOF {
-				err = nil
-				break
-			} else if err != nil {
-				return nil, err
-			}
-		}
 	}
 	return []byte(sb.String()), err
 }
 
 // where the work actually happens
 // returns an error if an attribute is not atomic
-func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value interface{}, pp *pretty) error {
+func (o *EncoderOptions) mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value interface{}, pp *pretty) error {
 	var endTag bool
 	var isSimple bool
 	var noEndTag bool
 	var elen int
 	var ss string
-	p := &pretty{pp.indent, pp.cnt, pp.padding, pp.mapDepth, pp.start}
+	p := &pretty{pp.indent, pp.cnt, pp.padding, pp.mapDepth, pp.start, pp.ns}
 
 	switch value.(type) {
 	case map[string]interface{}, []byte, string, float64, bool, int, int32, int64, float32:
 		if doIndent {
 			sb.WriteString(p.padding)
 		}
-		if key != commentK && key != directiveK && key != procinstK {
+		if key != o.keys.comment && key != o.keys.directive && key != o.keys.procinst {
 			sb.WriteString("<")
 			sb.WriteString(key)
 		}
@@ -625,27 +621,27 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 	case map[string]interface{}:
 		val := value.(map[string]interface{})
 
-		if key == commentK {
+		if key == o.keys.comment {
 			sb.WriteString("<!--")
-			sb.WriteString(val[textK].(string))
+			sb.WriteString(val[o.keys.text].(string))
 			sb.WriteString("-->")
 			noEndTag = true
 			break
 		}
 
-		if key == directiveK {
+		if key == o.keys.directive {
 			sb.WriteString("<!")
-			sb.WriteString(val[textK].(string))
+			sb.WriteString(val[o.keys.text].(string))
 			sb.WriteString(">")
 			noEndTag = true
 			break
 		}
 
-		if key == procinstK {
+		if key == o.keys.procinst {
 			sb.WriteString("<?")
-			sb.WriteString(val[targetK].(string))
+			sb.WriteString(val[o.keys.target].(string))
 			sb.WriteString(" ")
-			sb.WriteString(val[instK].(string))
+			sb.WriteString(val[o.keys.inst].(string))
 			sb.WriteString("?>")
 			noEndTag = true
 			break
@@ -653,7 +649,7 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 
 		haveAttrs := false
 		// process attributes first
-		if v, ok := val[attrK].(map[string]interface{}); ok {
+		if v, ok := val[o.keys.attr].(map[string]interface{}); ok {
 			// First, unroll the map[string]interface{} into a []keyval array.
 			// Then sequence it.
 			kv := make([]keyval, len(v))
@@ -662,16 +658,16 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 				kv[n] = keyval{ak, av}
 				n++
 			}
-			sort.Sort(elemListSeq(kv))
+			sort.Sort(elemListSeq{kv, o.keys.seq})
 			// Now encode the attributes in original decoding sequence, using keyval array.
 			for _, a := range kv {
 				vv := a.v.(map[string]interface{})
-				switch vv[textK].(type) {
+				switch vv[o.keys.text].(type) {
 				case string:
-					if xmlEscapeChars {
-						ss = escapeChars(vv[textK].(string))
+					if o.EscapeChars {
+						ss = escapeChars(vv[o.keys.text].(string))
 					} else {
-						ss = vv[textK].(string)
+						ss = vv[o.keys.text].(string)
 					}
 					sb.WriteString(" ")
 					sb.WriteString(a.k)
@@ -682,13 +678,20 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 					sb.WriteString(" ")
 					sb.WriteString(a.k)
 					sb.WriteString(`="`)
-					sb.WriteString(fmt.Sprintf("%v", vv[textK]))
+					sb.WriteString(fmt.Sprintf("%v", vv[o.keys.text]))
+					sb.WriteString(`"`)
+				case uint64, json.Number, time.Time: // CastSchema types
+					ss, _ = castString(vv[o.keys.text])
+					sb.WriteString(" ")
+					sb.WriteString(a.k)
+					sb.WriteString(`="`)
+					sb.WriteString(ss)
 					sb.WriteString(`"`)
 				case []byte:
-					if xmlEscapeChars {
-						ss = escapeChars(string(vv[textK].([]byte)))
+					if o.EscapeChars {
+						ss = escapeChars(string(vv[o.keys.text].([]byte)))
 					} else {
-						ss = string(vv[textK].([]byte))
+						ss = string(vv[o.keys.text].([]byte))
 					}
 					sb.WriteString(" ")
 					sb.WriteString(a.k)
@@ -704,11 +707,18 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 
 		// simple element?
 		// every map value has, at least, "#seq" and, perhaps, "#text" and/or "#attr"
-		_, seqOK := val[seqK] // have key
-		if v, ok := val[textK]; ok && ((len(val) == 3 && haveAttrs) || (len(val) == 2 && !haveAttrs)) && seqOK {
-			if stmp, ok := v.(string); ok && stmp != "" {
-				if xmlEscapeChars {
-					stmp = escapeChars(stmp)
+		_, seqOK := val[o.keys.seq] // have key
+		lenval := len(val)
+		isCDATA, cdataOK := val[o.keys.cdata].(bool)
+		if cdataOK {
+			lenval--
+		}
+		if v, ok := val[o.keys.text]; ok && ((lenval == 3 && haveAttrs) || (lenval == 2 && !haveAttrs)) && seqOK {
+			if stmp, ok := castString(v); ok && stmp != "" {
+				if isCDATA {
+					stmp = cdata(stmp)
+				} else {
+					stmp = o.text(stmp)
 				}
 				sb.WriteString(">")
 				sb.WriteString(stmp)
@@ -717,7 +727,7 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 			}
 			isSimple = true
 			break
-		} else if !ok && ((len(val) == 2 && haveAttrs) || (len(val) == 1 && !haveAttrs)) && seqOK {
+		} else if !ok && ((lenval == 2 && haveAttrs) || (lenval == 1 && !haveAttrs)) && seqOK {
 			// here no #text but have #seq or #seq+#attr
 			endTag = false
 			break
@@ -727,10 +737,10 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 		// 'kv' will hold everything that needs to be written
 		kv := make([]keyval, 0)
 		for k, v := range val {
-			if k == attrK { // already processed
+			if k == o.keys.attr { // already processed
 				continue
 			}
-			if k == seqK { // ignore - just for sorting
+			if k == o.keys.seq || k == o.keys.cdata { // ignore - just for sorting
 				continue
 			}
 			switch v.(type) {
@@ -751,7 +761,7 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 		}
 		// something more complex
 		p.mapDepth++
-		sort.Sort(elemListSeq(kv))
+		sort.Sort(elemListSeq{kv, o.keys.seq})
 		i := 0
 		for _, v := range kv {
 			switch v.v.(type) {
@@ -762,7 +772,7 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 				}
 			}
 			i++
-			if err := mapToXmlSeqIndent(doIndent, sb, v.k, v.v, p); err != nil {
+			if err := o.mapToXmlSeqIndent(doIndent, sb, v.k, v.v, p); err != nil {
 				return err
 			}
 			switch v.v.(type) {
@@ -782,7 +792,7 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 			if doIndent {
 				p.Indent()
 			}
-			if err := mapToXmlSeqIndent(doIndent, sb, key, v, p); err != nil {
+			if err := o.mapToXmlSeqIndent(doIndent, sb, key, v, p); err != nil {
 				return err
 			}
 			if doIndent {
@@ -803,11 +813,7 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 		elen = 0
 		switch value.(type) {
 		case string:
-			if xmlEscapeChars {
-				ss = escapeChars(value.(string))
-			} else {
-				ss = value.(string)
-			}
+			ss = o.text(value.(string))
 			elen = len(ss)
 			if elen > 0 {
 				sb.WriteString(">")
@@ -822,7 +828,7 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 			}
 		case []byte: // NOTE: byte is just an alias for uint8
 			// similar to how xml.Marshal handles []byte structure members
-			if xmlEscapeChars {
+			if o.EscapeChars {
 				ss = escapeChars(string(value.([]byte)))
 			} else {
 				ss = string(value.([]byte))
@@ -860,7 +866,7 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 		}
 		switch value.(type) {
 		case map[string]interface{}, []byte, string, float64, bool, int, int32, int64, float32:
-			if elen > 0 || useGoXmlEmptyElemSyntax {
+			if elen > 0 || o.GoEmptyElemSyntax {
 				if elen == 0 {
 					sb.WriteString(">")
 				}
@@ -872,8 +878,8 @@ func mapToXmlSeqIndent(doIndent bool, sb *strings.Builder, key string, value int
 			}
 		}
 	} else if !noEndTag {
-		if useGoXmlEmptyElemSyntax {
-			sb.WriteString("</")
+		if o.GoEmptyElemSyntax {
+			sb.WriteString("></")
 			sb.WriteString(key)
 			sb.WriteString(">")
 			// *s += "></" + key + ">"
@@ -897,30 +903,34 @@ type keyval struct {
 	k string
 	v interface{}
 }
-type elemListSeq []keyval
+// elemListSeq sorts on the 'seq' key - "#seq" - of the keyval values.
+type elemListSeq struct {
+	kv  []keyval
+	seq string
+}
 
 func (e elemListSeq) Len() int {
-	return len(e)
+	return len(e.kv)
 }
 
 func (e elemListSeq) Swap(i, j int) {
-	e[i], e[j] = e[j], e[i]
+	e.kv[i], e.kv[j] = e.kv[j], e.kv[i]
 }
 
 func (e elemListSeq) Less(i, j int) bool {
 	var iseq, jseq int
 	var fiseq, fjseq float64
 	var ok bool
-	if iseq, ok = e[i].v.(map[string]interface{})[seqK].(int); !ok {
-		if fiseq, ok = e[i].v.(map[string]interface{})[seqK].(float64); ok {
+	if iseq, ok = e.kv[i].v.(map[string]interface{})[e.seq].(int); !ok {
+		if fiseq, ok = e.kv[i].v.(map[string]interface{})[e.seq].(float64); ok {
 			iseq = int(fiseq)
 		} else {
 			iseq = 9999999
 		}
 	}
 
-	if jseq, ok = e[j].v.(map[string]interface{})[seqK].(int); !ok {
-		if fjseq, ok = e[j].v.(map[string]interface{})[seqK].(float64); ok {
+	if jseq, ok = e.kv[j].v.(map[string]interface{})[e.seq].(int); !ok {
+		if fjseq, ok = e.kv[j].v.(map[string]interface{})[e.seq].(float64); ok {
 			jseq = int(fjseq)
 		} else {
 			jseq = 9999999
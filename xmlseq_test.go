@@ -98,3 +98,28 @@ func BenchmarkMapToXml(b *testing.B) {
 		}
 	}
 }
+
+// regression: with XmlGoEmptyElemSyntax() an empty element was encoded as "<b</b>"
+func TestXmlSeqGoEmptyElemSyntax(t *testing.T) {
+	fmt.Println("\n================== TestXmlSeqGoEmptyElemSyntax")
+	msv, err := NewMapXmlSeq([]byte(`<a><b/><c x="1"/><d>2</d></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	XmlGoEmptyElemSyntax()
+	defer XmlDefaultEmptyElemSyntax()
+	x, err := msv.Xml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<a><b></b><c x="1"></c><d>2</d></a>`; string(x) != want {
+		t.Fatalf("got: %s\nwant: %s", x, want)
+	}
+	x, err = msv.XmlIndent("", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<a>\n  <b></b>\n  <c x=\"1\"></c>\n  <d>2</d>\n</a>"; string(x) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", x, want)
+	}
+}
@@ -0,0 +1,1302 @@
+// Copyright 2012-2016, 2018-2019 Charles Banning. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file
+
+// xpath.go - XPath 1.0 expressions on Map and MapSeq values decoded from XML.
+// The Map or MapSeq value is viewed as an XML node tree - elements, attributes
+// and text - and the expression is evaluated on that tree.
+
+package mxj
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// XPath returns the result of an XPath 1.0 expression evaluated on the Map as
+// an XML doc.
+//
+//	Element steps match Map keys, attributes are the keys with the attribute prefix -
+//	"-" by default, see SetAttrPrefix() - and the "#text" value is the element's text:
+//	   //book[@lang='en']/title       the <title> values of the English books
+//	   /catalog/book[2]/@id           the 'id' attribute of the second <book>
+//	   //book[price > 10 and contains(author, 'Gaddis')]
+//	   count(//book), sum(//book/price) div count(//book)
+//	For a node-set the values are returned as they are in the Map - an element's
+//	map[string]interface{} or simple value, an attribute or text value.  A string,
+//	number - float64 - or boolean result is returned as a single value.
+//
+//	Supported: the child, descendant, descendant-or-self, parent, ancestor, ancestor-or-self,
+//	following-sibling, preceding-sibling, attribute and self axes and their abbreviations -
+//	//, ., .., @; the node tests name, prefix:*, *, text() and node(); positional and
+//	value predicates; the operators or, and, =, !=, <, <=, >, >=, +, -, *, div, mod and |;
+//	and the core functions:
+//	   last, position, count, name, local-name, string, concat, starts-with, contains,
+//	   substring-before, substring-after, substring, string-length, normalize-space,
+//	   translate, boolean, not, true, false, number, sum, floor, ceiling, round.
+//	A Map has no document order, so sibling elements are in key order with the members
+//	of a list in list order; use MapSeq values if position() must follow the XML doc.
+func (mv Map) XPath(expr string) ([]interface{}, error) {
+	return xpathEval(expr, xpMapTree(map[string]interface{}(mv)))
+}
+
+// XPath returns the result of an XPath 1.0 expression evaluated on the MapSeq
+// value as an XML doc.  Sibling elements are in document order, as recorded in
+// the "#seq" values, and attributes are the "#attr" entries.  Element values are
+// returned with their "#seq" keys.  See mv.XPath() for Map values.
+func (msv MapSeq) XPath(expr string) ([]interface{}, error) {
+	return xpathEval(expr, xpSeqTree(map[string]interface{}(msv)))
+}
+
+func xpathEval(expr string, root *xpNode) ([]interface{}, error) {
+	e, err := parseXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	v, err := e.eval(&xpContext{node: root, pos: 1, size: 1, root: root})
+	if err != nil {
+		return nil, err
+	}
+	if ns, ok := v.([]*xpNode); ok {
+		vals := make([]interface{}, len(ns))
+		for i, n := range ns {
+			vals[i] = n.value
+		}
+		return vals, nil
+	}
+	return []interface{}{v}, nil
+}
+
+// ------------------------------ the node tree ------------------------------
+
+const (
+	xpRoot = iota
+	xpElement
+	xpAttribute
+	xpText
+)
+
+type xpNode struct {
+	kind     int
+	name     string
+	value    interface{} // as returned by XPath()
+	text     string      // attribute and text nodes
+	parent   *xpNode
+	attrs    []*xpNode
+	children []*xpNode // elements and text, in document order
+	order    int       // document order
+}
+
+// xpBuilder numbers the nodes in document order as the tree is built.
+type xpBuilder struct {
+	n int
+}
+
+func (b *xpBuilder) node(kind int, name string, parent *xpNode, v interface{}) *xpNode {
+	b.n++
+	n := &xpNode{kind: kind, name: name, value: v, parent: parent, order: b.n}
+	if kind == xpAttribute || kind == xpText {
+		n.text, _ = castString(v)
+	}
+	if parent != nil {
+		if kind == xpAttribute {
+			parent.attrs = append(parent.attrs, n)
+		} else {
+			parent.children = append(parent.children, n)
+		}
+	}
+	return n
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// xpMapTree builds the node tree for a Map value.
+func xpMapTree(m map[string]interface{}) *xpNode {
+	b := &xpBuilder{}
+	root := b.node(xpRoot, "", nil, m)
+	for _, k := range sortedKeys(m) {
+		b.mapElements(root, k, m[k])
+	}
+	return root
+}
+
+// mapElements adds the element(s) for Map key 'key' - one for each member of a list.
+func (b *xpBuilder) mapElements(parent *xpNode, key string, v interface{}) {
+	if a, ok := v.([]interface{}); ok {
+		for _, vv := range a {
+			b.mapElement(parent, key, vv)
+		}
+		return
+	}
+	b.mapElement(parent, key, v)
+}
+
+func (b *xpBuilder) mapElement(parent *xpNode, key string, v interface{}) {
+	e := b.node(xpElement, key, parent, v)
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		if s, ok := castString(v); ok && s != "" {
+			b.node(xpText, "", e, v)
+		}
+		return
+	}
+	keys := sortedKeys(m)
+	for _, k := range keys {
+		if attrPrefix != "" && strings.HasPrefix(k, attrPrefix) {
+			b.node(xpAttribute, k[len(attrPrefix):], e, m[k])
+		}
+	}
+	if t, ok := m[textK]; ok {
+		if s, ok := castString(t); ok && s != "" {
+			b.node(xpText, "", e, t)
+		}
+	}
+	for _, k := range keys {
+		if k == textK || (attrPrefix != "" && strings.HasPrefix(k, attrPrefix)) {
+			continue
+		}
+		b.mapElements(e, k, m[k])
+	}
+}
+
+// xpSeqTree builds the node tree for a MapSeq value.
+func xpSeqTree(m map[string]interface{}) *xpNode {
+	b := &xpBuilder{}
+	root := b.node(xpRoot, "", nil, m)
+	b.seqChildren(root, m)
+	return root
+}
+
+// seqChildren adds the text and sub-elements of a MapSeq element in "#seq" order.
+func (b *xpBuilder) seqChildren(e *xpNode, m map[string]interface{}) {
+	if t, ok := m[textK]; ok {
+		if s, ok := castString(t); ok && s != "" {
+			b.node(xpText, "", e, t)
+		}
+	}
+	type elem struct {
+		key string
+		seq int
+		v   interface{}
+	}
+	var elems []elem
+	for _, k := range sortedKeys(m) {
+		switch k {
+		case textK, seqK, attrK, cdataK, commentK, directiveK, procinstK:
+			continue
+		}
+		vals, ok := m[k].([]interface{})
+		if !ok {
+			vals = []interface{}{m[k]}
+		}
+		for _, v := range vals {
+			elems = append(elems, elem{k, jsonSeqOrder(v), v})
+		}
+	}
+	sort.SliceStable(elems, func(i, j int) bool {
+		return elems[i].seq < elems[j].seq
+	})
+	for _, el := range elems {
+		b.seqElement(e, el.key, el.v)
+	}
+}
+
+func (b *xpBuilder) seqElement(parent *xpNode, key string, v interface{}) {
+	e := b.node(xpElement, key, parent, v)
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		if s, ok := castString(v); ok && s != "" {
+			b.node(xpText, "", e, v)
+		}
+		return
+	}
+	if attrs, ok := m[attrK].(map[string]interface{}); ok {
+		type attr struct {
+			key string
+			seq int
+			v   interface{}
+		}
+		list := make([]attr, 0, len(attrs))
+		for _, k := range sortedKeys(attrs) {
+			v := attrs[k]
+			if am, ok := v.(map[string]interface{}); ok {
+				v = am[textK]
+			}
+			list = append(list, attr{k, jsonSeqOrder(attrs[k]), v})
+		}
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].seq < list[j].seq
+		})
+		for _, a := range list {
+			b.node(xpAttribute, a.key, e, a.v)
+		}
+	}
+	b.seqChildren(e, m)
+}
+
+// stringValue is the XPath string-value of a node.
+func (n *xpNode) stringValue() string {
+	switch n.kind {
+	case xpAttribute, xpText:
+		return n.text
+	}
+	var b strings.Builder
+	var walk func(*xpNode)
+	walk = func(n *xpNode) {
+		for _, c := range n.children {
+			if c.kind == xpText {
+				b.WriteString(c.text)
+			} else {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// ------------------------------ evaluation ------------------------------
+
+type xpContext struct {
+	node      *xpNode
+	pos, size int
+	root      *xpNode
+}
+
+// xpExpr is an expression; its value is a node-set - []*xpNode - string, float64 or bool.
+type xpExpr interface {
+	eval(ctx *xpContext) (interface{}, error)
+}
+
+type xpLiteral string
+
+func (e xpLiteral) eval(ctx *xpContext) (interface{}, error) { return string(e), nil }
+
+type xpNumber float64
+
+func (e xpNumber) eval(ctx *xpContext) (interface{}, error) { return float64(e), nil }
+
+type xpNeg struct{ e xpExpr }
+
+func (e xpNeg) eval(ctx *xpContext) (interface{}, error) {
+	v, err := e.e.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return -xpToNumber(v), nil
+}
+
+type xpBinary struct {
+	op   string
+	l, r xpExpr
+}
+
+func (e xpBinary) eval(ctx *xpContext) (interface{}, error) {
+	l, err := e.l.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 'and' and 'or' don't evaluate the right operand if the result is known
+	switch e.op {
+	case "or":
+		if xpToBool(l) {
+			return true, nil
+		}
+	case "and":
+		if !xpToBool(l) {
+			return false, nil
+		}
+	}
+	r, err := e.r.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "or", "and":
+		return xpToBool(r), nil
+	case "|":
+		ln, lok := l.([]*xpNode)
+		rn, rok := r.([]*xpNode)
+		if !lok || !rok {
+			return nil, fmt.Errorf("xpath: | operands must be node-sets")
+		}
+		return xpDocOrder(append(append([]*xpNode{}, ln...), rn...)), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return xpCompare(e.op, l, r), nil
+	}
+	a, b := xpToNumber(l), xpToNumber(r)
+	switch e.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "div":
+		return a / b, nil
+	case "mod":
+		return math.Mod(a, b), nil
+	}
+	return nil, fmt.Errorf("xpath: unknown operator %s", e.op)
+}
+
+// xpCompare compares values as XPath 1.0, section 3.4, specifies.
+func xpCompare(op string, l, r interface{}) bool {
+	ln, lok := l.([]*xpNode)
+	rn, rok := r.([]*xpNode)
+	switch {
+	case lok && rok:
+		for _, a := range ln {
+			for _, b := range rn {
+				if xpCompare(op, a.stringValue(), b.stringValue()) {
+					return true
+				}
+			}
+		}
+		return false
+	case lok || rok:
+		ns, other, swap := ln, r, false
+		if rok {
+			ns, other, swap = rn, l, true
+		}
+		if b, ok := other.(bool); ok {
+			if swap {
+				return xpCompare(op, b, len(ns) > 0)
+			}
+			return xpCompare(op, len(ns) > 0, b)
+		}
+		for _, n := range ns {
+			var v interface{} = n.stringValue()
+			if _, ok := other.(float64); ok {
+				v = xpToNumber(v)
+			}
+			if swap && xpCompare(op, other, v) || !swap && xpCompare(op, v, other) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if op == "=" || op == "!=" {
+		var eq bool
+		_, lb := l.(bool)
+		_, rb := r.(bool)
+		_, lf := l.(float64)
+		_, rf := r.(float64)
+		switch {
+		case lb || rb:
+			eq = xpToBool(l) == xpToBool(r)
+		case lf || rf:
+			eq = xpToNumber(l) == xpToNumber(r)
+		default:
+			eq = xpToString(l) == xpToString(r)
+		}
+		return eq == (op == "=")
+	}
+	a, b := xpToNumber(l), xpToNumber(r)
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+func xpToBool(v interface{}) bool {
+	switch vv := v.(type) {
+	case bool:
+		return vv
+	case float64:
+		return vv != 0 && !math.IsNaN(vv)
+	case string:
+		return vv != ""
+	case []*xpNode:
+		return len(vv) > 0
+	}
+	return false
+}
+
+func xpToNumber(v interface{}) float64 {
+	switch vv := v.(type) {
+	case float64:
+		return vv
+	case bool:
+		if vv {
+			return 1
+		}
+		return 0
+	case string:
+		s := strings.TrimSpace(vv)
+		// XPath numbers are digits with an optional '.' and leading '-'
+		if s == "" || strings.IndexFunc(s, func(r rune) bool {
+			return !(r >= '0' && r <= '9' || r == '.' || r == '-')
+		}) >= 0 {
+			return math.NaN()
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return f
+	case []*xpNode:
+		return xpToNumber(xpToString(vv))
+	}
+	return math.NaN()
+}
+
+func xpToString(v interface{}) string {
+	switch vv := v.(type) {
+	case string:
+		return vv
+	case bool:
+		if vv {
+			return "true"
+		}
+		return "false"
+	case float64:
+		switch {
+		case math.IsNaN(vv):
+			return "NaN"
+		case math.IsInf(vv, 1):
+			return "Infinity"
+		case math.IsInf(vv, -1):
+			return "-Infinity"
+		case vv == 0:
+			return "0"
+		}
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case []*xpNode:
+		if len(vv) == 0 {
+			return ""
+		}
+		return vv[0].stringValue()
+	}
+	return ""
+}
+
+// xpDocOrder sorts the node-set in document order and removes duplicates.
+func xpDocOrder(ns []*xpNode) []*xpNode {
+	sort.Slice(ns, func(i, j int) bool { return ns[i].order < ns[j].order })
+	out := ns[:0]
+	for i, n := range ns {
+		if i == 0 || n != ns[i-1] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// ------------------------------ location paths ------------------------------
+
+const (
+	xpChild = iota
+	xpDescendant
+	xpDescendantOrSelf
+	xpParent
+	xpAncestor
+	xpAncestorOrSelf
+	xpFollowingSibling
+	xpPrecedingSibling
+	xpAttr
+	xpSelf
+)
+
+var xpAxes = map[string]int{
+	"child":              xpChild,
+	"descendant":         xpDescendant,
+	"descendant-or-self": xpDescendantOrSelf,
+	"parent":             xpParent,
+	"ancestor":           xpAncestor,
+	"ancestor-or-self":   xpAncestorOrSelf,
+	"following-sibling":  xpFollowingSibling,
+	"preceding-sibling":  xpPrecedingSibling,
+	"attribute":          xpAttr,
+	"self":               xpSelf,
+}
+
+// node tests
+const (
+	xpTestName = iota // name, prefix:* or *
+	xpTestNode        // node()
+	xpTestText        // text()
+)
+
+type xpStep struct {
+	axis  int
+	test  int
+	name  string // "*" or "prefix:*" for wildcards
+	preds []xpExpr
+}
+
+// xpPath is a location path or a filter expression followed by a location path.
+type xpPath struct {
+	abs    bool
+	filter xpExpr
+	steps  []*xpStep
+}
+
+func (e *xpPath) eval(ctx *xpContext) (interface{}, error) {
+	var ns []*xpNode
+	switch {
+	case e.filter != nil:
+		v, err := e.filter.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if ns, ok = v.([]*xpNode); !ok {
+			return nil, fmt.Errorf("xpath: a location step must follow a node-set")
+		}
+	case e.abs:
+		ns = []*xpNode{ctx.root}
+	default:
+		ns = []*xpNode{ctx.node}
+	}
+	for _, s := range e.steps {
+		var next []*xpNode
+		for _, n := range ns {
+			sel, err := s.apply(ctx, n)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, sel...)
+		}
+		ns = xpDocOrder(next)
+	}
+	return ns, nil
+}
+
+// axis returns the nodes on the axis from 'n' in axis order - reverse document
+// order for the reverse axes.
+func (s *xpStep) axisNodes(n *xpNode) []*xpNode {
+	var out []*xpNode
+	var desc func(*xpNode)
+	desc = func(n *xpNode) {
+		for _, c := range n.children {
+			out = append(out, c)
+			desc(c)
+		}
+	}
+	switch s.axis {
+	case xpChild:
+		out = n.children
+	case xpDescendant:
+		desc(n)
+	case xpDescendantOrSelf:
+		out = append(out, n)
+		desc(n)
+	case xpParent:
+		if n.parent != nil {
+			out = append(out, n.parent)
+		}
+	case xpAncestor, xpAncestorOrSelf:
+		if s.axis == xpAncestorOrSelf {
+			out = append(out, n)
+		}
+		for p := n.parent; p != nil; p = p.parent {
+			out = append(out, p)
+		}
+	case xpFollowingSibling, xpPrecedingSibling:
+		if n.parent == nil || n.kind == xpAttribute {
+			break
+		}
+		sibs := n.parent.children
+		for i, c := range sibs {
+			if c != n {
+				continue
+			}
+			if s.axis == xpFollowingSibling {
+				out = append(out, sibs[i+1:]...)
+			} else {
+				for j := i - 1; j >= 0; j-- {
+					out = append(out, sibs[j])
+				}
+			}
+			break
+		}
+	case xpAttr:
+		out = n.attrs
+	case xpSelf:
+		out = append(out, n)
+	}
+	return out
+}
+
+func (s *xpStep) match(n *xpNode) bool {
+	switch s.test {
+	case xpTestNode:
+		return true
+	case xpTestText:
+		return n.kind == xpText
+	}
+	// the principal node type of the axis
+	if s.axis == xpAttr {
+		if n.kind != xpAttribute {
+			return false
+		}
+	} else if n.kind != xpElement {
+		return false
+	}
+	if s.name == "*" {
+		return true
+	}
+	if strings.HasSuffix(s.name, ":*") {
+		return strings.HasPrefix(n.name, s.name[:len(s.name)-1])
+	}
+	return n.name == s.name
+}
+
+func (s *xpStep) apply(ctx *xpContext, n *xpNode) ([]*xpNode, error) {
+	var ns []*xpNode
+	for _, c := range s.axisNodes(n) {
+		if s.match(c) {
+			ns = append(ns, c)
+		}
+	}
+	return xpPredicates(ctx, ns, s.preds)
+}
+
+// xpPredicates filters the node-set - in axis order - with each predicate in turn.
+func xpPredicates(ctx *xpContext, ns []*xpNode, preds []xpExpr) ([]*xpNode, error) {
+	for _, p := range preds {
+		var out []*xpNode
+		for i, n := range ns {
+			c := &xpContext{node: n, pos: i + 1, size: len(ns), root: ctx.root}
+			v, err := p.eval(c)
+			if err != nil {
+				return nil, err
+			}
+			if f, ok := v.(float64); ok {
+				if f == float64(c.pos) {
+					out = append(out, n)
+				}
+			} else if xpToBool(v) {
+				out = append(out, n)
+			}
+		}
+		ns = out
+	}
+	return ns, nil
+}
+
+// xpFilter is a primary expression with predicates - e.g., (//book)[1].
+type xpFilter struct {
+	e     xpExpr
+	preds []xpExpr
+}
+
+func (e *xpFilter) eval(ctx *xpContext) (interface{}, error) {
+	v, err := e.e.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ns, ok := v.([]*xpNode)
+	if !ok {
+		return nil, fmt.Errorf("xpath: predicates must follow a node-set")
+	}
+	return xpPredicates(ctx, ns, e.preds)
+}
+
+// ------------------------------ functions ------------------------------
+
+type xpCall struct {
+	name string
+	args []xpExpr
+}
+
+// xpFuncArgs are the minimum and maximum - -1 for any - number of arguments.
+var xpFuncArgs = map[string][2]int{
+	"last": {0, 0}, "position": {0, 0}, "count": {1, 1}, "name": {0, 1}, "local-name": {0, 1},
+	"string": {0, 1}, "concat": {2, -1}, "starts-with": {2, 2}, "contains": {2, 2},
+	"substring-before": {2, 2}, "substring-after": {2, 2}, "substring": {2, 3},
+	"string-length": {0, 1}, "normalize-space": {0, 1}, "translate": {3, 3},
+	"boolean": {1, 1}, "not": {1, 1}, "true": {0, 0}, "false": {0, 0},
+	"number": {0, 1}, "sum": {1, 1}, "floor": {1, 1}, "ceiling": {1, 1}, "round": {1, 1},
+}
+
+func (e *xpCall) eval(ctx *xpContext) (interface{}, error) {
+	args := make([]interface{}, len(e.args))
+	for i, a := range e.args {
+		v, err := a.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	// the context node is the default argument of string(), etc.
+	arg := func() interface{} {
+		if len(args) > 0 {
+			return args[0]
+		}
+		return []*xpNode{ctx.node}
+	}
+	nodes := func(v interface{}) ([]*xpNode, error) {
+		ns, ok := v.([]*xpNode)
+		if !ok {
+			return nil, fmt.Errorf("xpath: %s() argument must be a node-set", e.name)
+		}
+		return ns, nil
+	}
+	str := func(i int) string { return xpToString(args[i]) }
+
+	switch e.name {
+	case "last":
+		return float64(ctx.size), nil
+	case "position":
+		return float64(ctx.pos), nil
+	case "count":
+		ns, err := nodes(args[0])
+		return float64(len(ns)), err
+	case "name", "local-name":
+		ns, err := nodes(arg())
+		if err != nil || len(ns) == 0 {
+			return "", err
+		}
+		name := ns[0].name
+		if e.name == "local-name" {
+			if i := strings.LastIndex(name, ":"); i >= 0 {
+				name = name[i+1:]
+			}
+		}
+		return name, nil
+	case "string":
+		return xpToString(arg()), nil
+	case "concat":
+		var b strings.Builder
+		for i := range args {
+			b.WriteString(str(i))
+		}
+		return b.String(), nil
+	case "starts-with":
+		return strings.HasPrefix(str(0), str(1)), nil
+	case "contains":
+		return strings.Contains(str(0), str(1)), nil
+	case "substring-before":
+		if i := strings.Index(str(0), str(1)); i >= 0 {
+			return str(0)[:i], nil
+		}
+		return "", nil
+	case "substring-after":
+		if i := strings.Index(str(0), str(1)); i >= 0 {
+			return str(0)[i+len(str(1)):], nil
+		}
+		return "", nil
+	case "substring":
+		// positions are in characters, starting at 1, and rounded
+		r := []rune(str(0))
+		start := xpRound(xpToNumber(args[1]))
+		end := math.Inf(1)
+		if len(args) == 3 {
+			end = start + xpRound(xpToNumber(args[2]))
+		}
+		var b strings.Builder
+		for i, c := range r {
+			if p := float64(i + 1); p >= start && p < end {
+				b.WriteRune(c)
+			}
+		}
+		return b.String(), nil
+	case "string-length":
+		return float64(utf8.RuneCountInString(xpToString(arg()))), nil
+	case "normalize-space":
+		return strings.Join(strings.Fields(xpToString(arg())), " "), nil
+	case "translate":
+		from, to := []rune(str(1)), []rune(str(2))
+		return strings.Map(func(r rune) rune {
+			for i, f := range from {
+				if f == r {
+					if i < len(to) {
+						return to[i]
+					}
+					return -1
+				}
+			}
+			return r
+		}, str(0)), nil
+	case "boolean":
+		return xpToBool(args[0]), nil
+	case "not":
+		return !xpToBool(args[0]), nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "number":
+		return xpToNumber(arg()), nil
+	case "sum":
+		ns, err := nodes(args[0])
+		var sum float64
+		for _, n := range ns {
+			sum += xpToNumber(n.stringValue())
+		}
+		return sum, err
+	case "floor":
+		return math.Floor(xpToNumber(args[0])), nil
+	case "ceiling":
+		return math.Ceil(xpToNumber(args[0])), nil
+	case "round":
+		return xpRound(xpToNumber(args[0])), nil
+	}
+	return nil, fmt.Errorf("xpath: unknown function %s()", e.name)
+}
+
+// xpRound rounds half up - round(-2.5) is -2 - as XPath specifies.
+func xpRound(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return f
+	}
+	return math.Floor(f + 0.5)
+}
+
+// ------------------------------ parse an expression ------------------------------
+
+type xpToken struct {
+	kind string // "name", "op", "literal", "number", "func", "axis", "nodetype"
+	val  string
+	pos  int
+}
+
+type xpParser struct {
+	expr string
+	toks []xpToken
+	i    int
+}
+
+func parseXPath(s string) (xpExpr, error) {
+	toks, err := xpTokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &xpParser{expr: s, toks: toks}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("xpath: empty expression")
+	}
+	e, err := p.orExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.toks) {
+		return nil, p.errorf("unexpected %q", p.toks[p.i].val)
+	}
+	return e, nil
+}
+
+func (p *xpParser) errorf(format string, args ...interface{}) error {
+	pos := len(p.expr)
+	if p.i < len(p.toks) {
+		pos = p.toks[p.i].pos
+	}
+	return fmt.Errorf("xpath %q: offset %d: %s", p.expr, pos, fmt.Sprintf(format, args...))
+}
+
+func isXPNameStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func isXPNameChar(c byte) bool {
+	return isXPNameStart(c) || c >= '0' && c <= '9' || c == '-' || c == '.'
+}
+
+// xpTokenize splits the expression into tokens, resolving '*' and the operator
+// names - XPath 1.0, section 3.7.
+func xpTokenize(s string) ([]xpToken, error) {
+	var toks []xpToken
+	// an operator is expected if the preceding token isn't an operator, '@', '::', '(', '[' or ','
+	opExpected := func() bool {
+		if len(toks) == 0 {
+			return false
+		}
+		t := toks[len(toks)-1]
+		switch t.kind {
+		case "axis", "func", "nodetype":
+			return false
+		case "op":
+			return t.val == ")" || t.val == "]" || t.val == "." || t.val == ".."
+		}
+		return true
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '\'' || c == '"':
+			j := strings.IndexByte(s[i+1:], c)
+			if j < 0 {
+				return nil, fmt.Errorf("xpath %q: offset %d: unterminated literal", s, i)
+			}
+			toks = append(toks, xpToken{"literal", s[i+1 : i+1+j], start})
+			i += j + 2
+			continue
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+				i++
+			}
+			toks = append(toks, xpToken{"number", s[start:i], start})
+			continue
+		case c == '*' && opExpected():
+			toks = append(toks, xpToken{"op", "*", start})
+			i++
+			continue
+		case c == '*':
+			toks = append(toks, xpToken{"name", "*", start})
+			i++
+			continue
+		case isXPNameStart(c):
+			for i < len(s) && isXPNameChar(s[i]) {
+				i++
+			}
+			// a QName - prefix:local or prefix:*
+			if i+1 < len(s) && s[i] == ':' && s[i+1] != ':' {
+				i++
+				if s[i] == '*' {
+					i++
+				} else {
+					for i < len(s) && isXPNameChar(s[i]) {
+						i++
+					}
+				}
+			}
+			name := s[start:i]
+			if opExpected() {
+				switch name {
+				case "and", "or", "div", "mod":
+					toks = append(toks, xpToken{"op", name, start})
+					continue
+				}
+				return nil, fmt.Errorf("xpath %q: offset %d: unexpected %q", s, start, name)
+			}
+			// look ahead for '(' or '::'
+			j := i
+			for j < len(s) && (s[j] == ' ' || s[j] == '\t' || s[j] == '\n' || s[j] == '\r') {
+				j++
+			}
+			switch {
+			case strings.HasPrefix(s[j:], "::"):
+				toks = append(toks, xpToken{"axis", name, start})
+				i = j + 2
+			case j < len(s) && s[j] == '(':
+				switch name {
+				case "node", "text", "comment", "processing-instruction":
+					toks = append(toks, xpToken{"nodetype", name, start})
+				default:
+					toks = append(toks, xpToken{"func", name, start})
+				}
+			default:
+				toks = append(toks, xpToken{"name", name, start})
+			}
+			continue
+		}
+		// operators and punctuation
+		for _, op := range []string{"//", "..", "!=", "<=", ">=", "/", ".", "@", "(", ")", "[", "]", ",", "|", "=", "<", ">", "+", "-"} {
+			if strings.HasPrefix(s[i:], op) {
+				toks = append(toks, xpToken{"op", op, start})
+				i += len(op)
+				break
+			}
+		}
+		if i == start {
+			return nil, fmt.Errorf("xpath %q: offset %d: unexpected %q", s, i, s[i:i+1])
+		}
+	}
+	return toks, nil
+}
+
+func (p *xpParser) peek() xpToken {
+	if p.i < len(p.toks) {
+		return p.toks[p.i]
+	}
+	return xpToken{}
+}
+
+func (p *xpParser) isOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != "op" {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.val == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *xpParser) expect(op string) error {
+	if _, ok := p.isOp(op); !ok {
+		return p.errorf("expected %s", op)
+	}
+	p.i++
+	return nil
+}
+
+// binary parses a left-associative sequence of 'next' operands separated by 'ops'.
+func (p *xpParser) binary(next func() (xpExpr, error), ops ...string) (xpExpr, error) {
+	l, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.isOp(ops...)
+		if !ok {
+			return l, nil
+		}
+		p.i++
+		r, err := next()
+		if err != nil {
+			return nil, err
+		}
+		l = xpBinary{op, l, r}
+	}
+}
+
+func (p *xpParser) orExpr() (xpExpr, error) { return p.binary(p.andExpr, "or") }
+
+func (p *xpParser) andExpr() (xpExpr, error) { return p.binary(p.equalityExpr, "and") }
+
+func (p *xpParser) equalityExpr() (xpExpr, error) {
+	return p.binary(p.relationalExpr, "=", "!=")
+}
+
+func (p *xpParser) relationalExpr() (xpExpr, error) {
+	return p.binary(p.additiveExpr, "<", "<=", ">", ">=")
+}
+
+func (p *xpParser) additiveExpr() (xpExpr, error) {
+	return p.binary(p.multiplicativeExpr, "+", "-")
+}
+
+func (p *xpParser) multiplicativeExpr() (xpExpr, error) {
+	return p.binary(p.unaryExpr, "*", "div", "mod")
+}
+
+func (p *xpParser) unaryExpr() (xpExpr, error) {
+	if _, ok := p.isOp("-"); ok {
+		p.i++
+		e, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return xpNeg{e}, nil
+	}
+	return p.binary(p.pathExpr, "|")
+}
+
+// pathExpr parses a location path or a filter expression with an optional path.
+func (p *xpParser) pathExpr() (xpExpr, error) {
+	t := p.peek()
+	isPrimary := t.kind == "literal" || t.kind == "number" || t.kind == "func" ||
+		t.kind == "op" && t.val == "("
+	if !isPrimary {
+		return p.locationPath()
+	}
+	e, err := p.primaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	preds, err := p.predicates()
+	if err != nil {
+		return nil, err
+	}
+	if len(preds) > 0 {
+		e = &xpFilter{e, preds}
+	}
+	if op, ok := p.isOp("/", "//"); ok {
+		p.i++
+		path := &xpPath{filter: e}
+		if op == "//" {
+			path.steps = append(path.steps, &xpStep{axis: xpDescendantOrSelf, test: xpTestNode})
+		}
+		if err := p.relativePath(path); err != nil {
+			return nil, err
+		}
+		return path, nil
+	}
+	return e, nil
+}
+
+func (p *xpParser) primaryExpr() (xpExpr, error) {
+	t := p.peek()
+	p.i++
+	switch t.kind {
+	case "literal":
+		return xpLiteral(t.val), nil
+	case "number":
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			p.i--
+			return nil, p.errorf("invalid number %q", t.val)
+		}
+		return xpNumber(f), nil
+	case "func":
+		n, ok := xpFuncArgs[t.val]
+		if !ok {
+			p.i--
+			return nil, p.errorf("unknown function %s()", t.val)
+		}
+		p.i++ // '('
+		call := &xpCall{name: t.val}
+		if _, ok := p.isOp(")"); !ok {
+			for {
+				a, err := p.orExpr()
+				if err != nil {
+					return nil, err
+				}
+				call.args = append(call.args, a)
+				if _, ok := p.isOp(","); !ok {
+					break
+				}
+				p.i++
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if len(call.args) < n[0] || n[1] >= 0 && len(call.args) > n[1] {
+			p.i--
+			return nil, p.errorf("wrong number of arguments for %s()", t.val)
+		}
+		return call, nil
+	}
+	// '('
+	e, err := p.orExpr()
+	if err != nil {
+		return nil, err
+	}
+	return e, p.expect(")")
+}
+
+func (p *xpParser) predicates() ([]xpExpr, error) {
+	var preds []xpExpr
+	for {
+		if _, ok := p.isOp("["); !ok {
+			return preds, nil
+		}
+		p.i++
+		e, err := p.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		preds = append(preds, e)
+	}
+}
+
+func (p *xpParser) locationPath() (xpExpr, error) {
+	path := &xpPath{}
+	if op, ok := p.isOp("/", "//"); ok {
+		p.i++
+		path.abs = true
+		if op == "/" && !p.stepStart() {
+			return path, nil // just the root
+		}
+		if op == "//" {
+			path.steps = append(path.steps, &xpStep{axis: xpDescendantOrSelf, test: xpTestNode})
+		}
+	}
+	if err := p.relativePath(path); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// stepStart reports whether the next token can start a location step.
+func (p *xpParser) stepStart() bool {
+	t := p.peek()
+	switch t.kind {
+	case "name", "axis", "nodetype":
+		return true
+	case "op":
+		return t.val == "@" || t.val == "." || t.val == ".."
+	}
+	return false
+}
+
+// relativePath parses steps separated by '/' or '//'.
+func (p *xpParser) relativePath(path *xpPath) error {
+	for {
+		s, err := p.step()
+		if err != nil {
+			return err
+		}
+		path.steps = append(path.steps, s)
+		op, ok := p.isOp("/", "//")
+		if !ok {
+			return nil
+		}
+		p.i++
+		if op == "//" {
+			path.steps = append(path.steps, &xpStep{axis: xpDescendantOrSelf, test: xpTestNode})
+		}
+	}
+}
+
+func (p *xpParser) step() (*xpStep, error) {
+	if op, ok := p.isOp(".", ".."); ok {
+		p.i++
+		if op == "." {
+			return &xpStep{axis: xpSelf, test: xpTestNode}, nil
+		}
+		return &xpStep{axis: xpParent, test: xpTestNode}, nil
+	}
+	s := &xpStep{axis: xpChild}
+	if _, ok := p.isOp("@"); ok {
+		p.i++
+		s.axis = xpAttr
+	} else if t := p.peek(); t.kind == "axis" {
+		axis, ok := xpAxes[t.val]
+		if !ok {
+			return nil, p.errorf("unsupported axis %s", t.val)
+		}
+		s.axis = axis
+		p.i++
+	}
+	t := p.peek()
+	switch t.kind {
+	case "name":
+		s.test = xpTestName
+		s.name = t.val
+		p.i++
+	case "nodetype":
+		switch t.val {
+		case "node":
+			s.test = xpTestNode
+		case "text":
+			s.test = xpTestText
+		default:
+			return nil, p.errorf("unsupported node test %s()", t.val)
+		}
+		p.i += 2 // name and '('
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.errorf("expected a location step")
+	}
+	preds, err := p.predicates()
+	if err != nil {
+		return nil, err
+	}
+	s.preds = preds
+	return s, nil
+}
@@ -0,0 +1,180 @@
+package mxj
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var xpathData = []byte(`<catalog>
+	<book id="b1" lang="en">
+		<title>The Recognitions</title>
+		<author>William Gaddis</author>
+		<price>12.50</price>
+	</book>
+	<magazine id="m1"><title>Harper's</title><price>5</price></magazine>
+	<book id="b2" lang="fr">
+		<title>Les Misérables</title>
+		<author>Victor Hugo</author>
+		<price>8</price>
+	</book>
+	<book id="b3" lang="en">
+		<title>JR</title>
+		<author>William Gaddis</author>
+		<price>15</price>
+	</book>
+</catalog>`)
+
+func TestXPath(t *testing.T) {
+	fmt.Println("\n================== TestXPath")
+	m, err := NewMapXml(xpathData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want []interface{}
+	}{
+		{"/catalog/book/title", []interface{}{"The Recognitions", "Les Misérables", "JR"}},
+		{"//book[@lang='en']/title", []interface{}{"The Recognitions", "JR"}},
+		{"/catalog/book[2]/@id", []interface{}{"b2"}},
+		{"//book[last()]/title", []interface{}{"JR"}},
+		{"//book[position() < 3]/@id", []interface{}{"b1", "b2"}},
+		{"//book[price > 10 and contains(author, 'Gaddis')]/@id", []interface{}{"b1", "b3"}},
+		{"//title[. = 'JR']/../@id", []interface{}{"b3"}},
+		{"//book[title='JR']/parent::catalog/magazine/title", []interface{}{"Harper's"}},
+		{"//price[../@id='m1']", []interface{}{"5"}},
+		{"//magazine/@* | //book[1]/@id", []interface{}{"b1", "m1"}},
+		{"count(//book)", []interface{}{float64(3)}},
+		{"sum(//book/price)", []interface{}{float64(35.5)}},
+		{"sum(//price) div count(//price)", []interface{}{float64(10.125)}},
+		{"round(2.5) + floor(-1.5) + ceiling(0.2)", []interface{}{float64(2)}},
+		{"7 mod 3 * 2", []interface{}{float64(2)}},
+		{"string(//book[2]/price)", []interface{}{"8"}},
+		{"concat(//book[1]/@id, '-', substring-after(//book[1]/author, ' '))", []interface{}{"b1-Gaddis"}},
+		{"substring('12345', 1.5, 2.6)", []interface{}{"234"}},
+		{"substring-before('1999/04/01', '/')", []interface{}{"1999"}},
+		{"string-length(//book[3]/title)", []interface{}{float64(2)}},
+		{"normalize-space('  a   b ')", []interface{}{"a b"}},
+		{"translate('bar', 'abc', 'ABC')", []interface{}{"BAr"}},
+		{"starts-with(name(/*), 'cat')", []interface{}{true}},
+		{"not(//book[@lang='de'])", []interface{}{true}},
+		{"//book[@lang != 'en']/author/text()", []interface{}{"Victor Hugo"}},
+		{"//book[3]/preceding-sibling::book/@id", []interface{}{"b1", "b2"}},
+		{"//book[1]/following-sibling::*[1]/@id", []interface{}{"b2"}},
+		{"count(//book[1]/ancestor::*)", []interface{}{float64(1)}},
+		{"count(/catalog/descendant::title)", []interface{}{float64(4)}},
+		{"//book[self::book/@id='b2']/title", []interface{}{"Les Misérables"}},
+		{"(//book)[last()]/attribute::id", []interface{}{"b3"}},
+		{"//nothing", []interface{}{}},
+	}
+	for _, tt := range tests {
+		got, err := m.XPath(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+
+	// element nodes are returned as their Map values
+	vals, err := m.XPath("//book[@id='b3']")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(vals)
+	if b, ok := vals[0].(map[string]interface{}); !ok || b["title"] != "JR" || b[attrPrefix+"id"] != "b3" {
+		t.Errorf("book: %#v", vals)
+	}
+}
+
+func TestXPathText(t *testing.T) {
+	fmt.Println("\n================== TestXPathText")
+	m, err := NewMapXml([]byte(`<doc><n unit="kg">3</n><n>4</n></doc>`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err := m.XPath("//n/text()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vals, []interface{}{float64(3), float64(4)}) {
+		t.Errorf("got %#v", vals)
+	}
+	vals, _ = m.XPath("sum(//n) * 2")
+	if vals[0] != float64(14) {
+		t.Errorf("sum: %#v", vals)
+	}
+	vals, _ = m.XPath("//n[@unit]")
+	if n, ok := vals[0].(map[string]interface{}); !ok || n[textK] != float64(3) {
+		t.Errorf("n: %#v", vals)
+	}
+}
+
+func TestXPathSeq(t *testing.T) {
+	fmt.Println("\n================== TestXPathSeq")
+	msv, err := NewMapXmlSeq(xpathData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want []interface{}
+	}{
+		// document order, not key order
+		{"/catalog/*/@id", []interface{}{"b1", "m1", "b2", "b3"}},
+		{"/catalog/*[2]/title/text()", []interface{}{"Harper's"}},
+		{"/catalog/*[position() = 3]/@lang", []interface{}{"fr"}},
+		{"//book[2]/@id", []interface{}{"b2"}},
+		{"//magazine/following-sibling::book[1]/@id", []interface{}{"b2"}},
+		{"//magazine/preceding-sibling::*/@id", []interface{}{"b1"}},
+		{"//title[contains(., 'Mis')]/../@id", []interface{}{"b2"}},
+		{"sum(//price)", []interface{}{float64(40.5)}},
+		{"name(/catalog/*[2])", []interface{}{"magazine"}},
+	}
+	for _, tt := range tests {
+		got, err := msv.XPath(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+
+	vals, err := msv.XPath("//magazine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mg, ok := vals[0].(map[string]interface{}); !ok || mg[seqK] != 1 {
+		t.Errorf("magazine: %#v", vals)
+	}
+}
+
+func TestXPathErrors(t *testing.T) {
+	fmt.Println("\n================== TestXPathErrors")
+	m := Map{"a": "1"}
+	for _, expr := range []string{
+		"",
+		"/a[",
+		"/a/following::b",
+		"foo(1)",
+		"count()",
+		"'abc",
+		"/a/comment()",
+		"1 | 2",
+		"a b",
+	} {
+		_, err := m.XPath(expr)
+		fmt.Println(expr, "=>", err)
+		if err == nil || !strings.HasPrefix(err.Error(), "xpath") {
+			t.Errorf("%q: %v", expr, err)
+		}
+	}
+}